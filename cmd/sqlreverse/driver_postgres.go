@@ -0,0 +1,7 @@
+// +build postgres
+
+package main
+
+import (
+    _ "github.com/lib/pq"
+)
@@ -0,0 +1,7 @@
+// +build sqlite3
+
+package main
+
+import (
+    _ "github.com/mattn/go-sqlite3"
+)
@@ -0,0 +1,313 @@
+package main
+
+import (
+    "fmt"
+    "bytes"
+    "go/format"
+    "reflect"
+    "sort"
+    "strings"
+    "time"
+    "database/sql"
+    "github.com/princeofdatamining/golib/sqlutil/dialect"
+)
+
+var (
+    typeBool        = reflect.TypeOf(false)
+    typeInt8        = reflect.TypeOf(int8(0))
+    typeInt16       = reflect.TypeOf(int16(0))
+    typeInt32       = reflect.TypeOf(int32(0))
+    typeInt64       = reflect.TypeOf(int64(0))
+    typeUint8       = reflect.TypeOf(uint8(0))
+    typeUint16      = reflect.TypeOf(uint16(0))
+    typeUint32      = reflect.TypeOf(uint32(0))
+    typeUint64      = reflect.TypeOf(uint64(0))
+    typeFloat32     = reflect.TypeOf(float32(0))
+    typeFloat64     = reflect.TypeOf(float64(0))
+    typeString      = reflect.TypeOf("")
+    typeBytes       = reflect.TypeOf([]byte(nil))
+    typeTime        = reflect.TypeOf(time.Time{})
+    typeNullBool    = reflect.TypeOf(sql.NullBool{})
+    typeNullInt64   = reflect.TypeOf(sql.NullInt64{})
+    typeNullFloat64 = reflect.TypeOf(sql.NullFloat64{})
+    typeNullString  = reflect.TypeOf(sql.NullString{})
+)
+
+// goTypeOf maps an inspected SQL type name back to the Go type whose
+// default mapping in the dialects is the closest match.
+func goTypeOf(col *dialect.ColumnInfo, nullable bool) (reflect.Type) {
+    t := typeString
+    name := col.Type
+    unsigned := strings.HasSuffix(name, " unsigned")
+    name = strings.TrimSuffix(name, " unsigned")
+    switch name {
+    case "boolean", "bool":
+        t = typeBool
+    case "tinyint":
+        t = typeInt8
+        if unsigned { t = typeUint8 }
+    case "smallint", "int2", "smallserial":
+        t = typeInt16
+        if unsigned { t = typeUint16 }
+    case "int", "integer", "int4", "mediumint", "serial":
+        t = typeInt32
+        if unsigned { t = typeUint32 }
+        // sqlite has a single integer storage class
+        if name == "integer" && col.Size < 0 { t = typeInt64 }
+    case "bigint", "int8", "bigserial":
+        t = typeInt64
+        if unsigned { t = typeUint64 }
+    case "float", "real", "float4":
+        t = typeFloat32
+    case "double", "double precision", "float8", "decimal", "numeric":
+        t = typeFloat64
+    case "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary", "bytea":
+        t = typeBytes
+    case "date", "datetime", "timestamp", "timestamp with time zone", "time":
+        t = typeTime
+    }
+    if !nullable || col.NotNull || col.AutoIncr {
+        return t
+    }
+    switch t {
+    case typeBool:
+        return typeNullBool
+    case typeInt8, typeInt16, typeInt32, typeInt64, typeUint8, typeUint16, typeUint32:
+        return typeNullInt64
+    case typeFloat32, typeFloat64:
+        return typeNullFloat64
+    case typeString:
+        return typeNullString
+    }
+    return t
+}
+
+// goName turns snake_case identifiers into exported CamelCase.
+func goName(s string) (string) {
+    var b bytes.Buffer
+    upper := true
+    for _, c := range s {
+        switch {
+        case c == '_' || c == '-' || c == ' ' || c == '.':
+            upper = true
+            continue
+        case upper && c >= 'a' && c <= 'z':
+            c -= 'a' - 'A'
+        }
+        upper = false
+        b.WriteRune(c)
+    }
+    name := b.String()
+    if name == "" || name[0] >= '0' && name[0] <= '9' {
+        name = "T" + name
+    }
+    return name
+}
+
+// columnMeta lets the generator render a column through the dialect
+// exactly like sqlutil would for the generated struct field.
+type columnMeta struct {
+    name        string
+    gotype      reflect.Type
+    autoincr    bool
+    notnull     bool
+    newtype     string
+    size        int
+    precision   int
+    hasDefault  bool
+    defaults    string
+    comment     string
+}
+func (this *columnMeta) GetColumnName() (string) { return this.name }
+func (this *columnMeta) GetFieldName() (string) { return goName(this.name) }
+func (this *columnMeta) GetAutoIncr() (bool) { return this.autoincr }
+func (this *columnMeta) GetNotNull() (bool) { return this.notnull }
+func (this *columnMeta) GetGoType() (reflect.Type) { return this.gotype }
+func (this *columnMeta) GetForceType() (string) { return this.newtype }
+func (this *columnMeta) GetSize() (int, int) { return this.size, this.precision }
+func (this *columnMeta) GetDefault() (bool, string) { return this.hasDefault, this.defaults }
+func (this *columnMeta) GetComment() (string) { return this.comment }
+
+type Generator struct {
+    Dialect     dialect.Dialect
+    Package     string
+    // use sql.Null* types for nullable columns
+    Nullable    bool
+
+    imports     map[string]bool
+    body        bytes.Buffer
+    tables      []*dialect.TableInfo
+}
+
+func NewGenerator(d dialect.Dialect, pkg string) (*Generator) {
+    return &Generator{
+        Dialect: d,
+        Package: pkg,
+        imports: map[string]bool{},
+    }
+}
+
+// Add emits the struct definition of one inspected table.
+func (this *Generator) Add(info *dialect.TableInfo) () {
+    this.tables = append(this.tables, info)
+    keys := columnKeys(info)
+
+    if info.Comment != "" {
+        fmt.Fprintf(&this.body, "// %s\n", oneLine(info.Comment))
+    }
+    fmt.Fprintf(&this.body, "type %s struct {\n", goName(info.Name))
+    for _, col := range info.Columns {
+        meta := this.columnMeta(col)
+        tag, notes := this.columnTag(col, meta, keys[col.Name])
+        for _, note := range notes {
+            fmt.Fprintf(&this.body, "\t// %s\n", note)
+        }
+        if tag == "" {
+            fmt.Fprintf(&this.body, "\t%s %s\n", goName(col.Name), this.typeName(meta.gotype))
+        } else {
+            fmt.Fprintf(&this.body, "\t%s %s `db:%q`\n", goName(col.Name), this.typeName(meta.gotype), tag)
+        }
+    }
+    fmt.Fprintf(&this.body, "}\n\n")
+}
+func (this *Generator) columnMeta(col *dialect.ColumnInfo) (meta *columnMeta) {
+    meta = &columnMeta{
+        name: col.Name,
+        gotype: goTypeOf(col, this.Nullable),
+        autoincr: col.AutoIncr,
+        notnull: col.NotNull,
+        size: -1,
+        precision: -1,
+        hasDefault: col.HasDefault,
+        defaults: col.Default,
+        comment: col.Comment,
+    }
+    switch col.Type {
+    case "varchar", "char", "decimal", "numeric":
+        meta.size, meta.precision = col.Size, col.Precision
+    }
+    // force the inspected type when the default Go mapping renders other DDL
+    rendered := this.Dialect.CreateColumnStr(meta)
+    meta.newtype = col.Type
+    if forced := this.Dialect.CreateColumnStr(meta); forced == rendered {
+        meta.newtype = ""
+    } else if col.Size > 0 && meta.size < 0 {
+        meta.size, meta.precision = col.Size, col.Precision
+    }
+    return
+}
+func (this *Generator) columnTag(col *dialect.ColumnInfo, meta *columnMeta, keys []string) (tag string, notes []string) {
+    var fields []string
+    if strings.ToLower(goName(col.Name)) != col.Name {
+        fields = append(fields, "name=" + col.Name)
+    }
+    fields = append(fields, keys...)
+    if col.AutoIncr {
+        fields = append(fields, "autoincr")
+    }
+    if col.NotNull && !col.AutoIncr {
+        fields = append(fields, "notnull")
+    }
+    if meta.newtype != "" {
+        fields = append(fields, "type=" + meta.newtype)
+    }
+    if meta.size > 0 {
+        fields = append(fields, fmt.Sprintf("size=%d", meta.size))
+    }
+    if meta.precision >= 0 {
+        fields = append(fields, fmt.Sprintf("precision=%d", meta.precision))
+    }
+    // the tag syntax has no escaping, values holding a comma are dropped
+    if col.HasDefault {
+        if !tagSafe(col.Default) {
+            notes = append(notes, fmt.Sprintf("default %s not representable in tag", oneLine(col.Default)))
+        } else {
+            fields = append(fields, "default=" + col.Default)
+        }
+    }
+    if col.Comment != "" {
+        if !tagSafe(col.Comment) {
+            notes = append(notes, oneLine(col.Comment))
+        } else {
+            fields = append(fields, "comment=" + oneLine(col.Comment))
+        }
+    }
+    return strings.Join(fields, ","), notes
+}
+
+// columnKeys converts index metadata into the primary/unique/index
+// tag fields of each column. Single column keys named after their
+// column use the short flag form.
+func columnKeys(info *dialect.TableInfo) (keys map[string][]string) {
+    keys = map[string][]string{}
+    for _, index := range info.Indexes {
+        flag := "index"
+        switch {
+        case index.Primary:
+            flag = "primary"
+        case index.Unique:
+            flag = "unique"
+        }
+        for _, name := range index.Columns {
+            col := info.Column(name)
+            switch {
+            case index.Primary && len(index.Columns) == 1:
+                // autoincr implies the primary key
+                if col == nil || !col.AutoIncr {
+                    keys[name] = append(keys[name], flag)
+                }
+            case index.Primary:
+                keys[name] = append(keys[name], flag + "=" + info.Name + "_pkey")
+            case len(index.Columns) == 1 && index.Name == name:
+                keys[name] = append(keys[name], flag)
+            default:
+                keys[name] = append(keys[name], flag + "=" + index.Name)
+            }
+        }
+    }
+    return
+}
+func (this *Generator) typeName(t reflect.Type) (string) {
+    switch t.PkgPath() {
+    case "time":
+        this.imports["time"] = true
+    case "database/sql":
+        this.imports["database/sql"] = true
+    }
+    if t == typeBytes {
+        return "[]byte"
+    }
+    return t.String()
+}
+func tagSafe(s string) (bool) {
+    return !strings.ContainsAny(s, ",`")
+}
+func oneLine(s string) (string) {
+    return strings.Join(strings.Fields(s), " ")
+}
+
+// Source returns the gofmt-ed file with all added tables and a
+// RegisterTables helper mapping them on a DbMap.
+func (this *Generator) Source() ([]byte, error) {
+    var out bytes.Buffer
+    fmt.Fprintf(&out, "// Code generated by sqlreverse. DO NOT EDIT.\n\npackage %s\n\n", this.Package)
+    this.imports["github.com/princeofdatamining/golib/sqlutil"] = true
+    var imports []string
+    for imp := range this.imports {
+        imports = append(imports, imp)
+    }
+    sort.Strings(imports)
+    fmt.Fprintf(&out, "import (\n")
+    for _, imp := range imports {
+        fmt.Fprintf(&out, "\t%q\n", imp)
+    }
+    fmt.Fprintf(&out, ")\n\n")
+    out.Write(this.body.Bytes())
+
+    fmt.Fprintf(&out, "func RegisterTables(dbmap sqlutil.DbMap) (err error) {\n")
+    for _, info := range this.tables {
+        fmt.Fprintf(&out, "\tif _, err = dbmap.AddTable3(%s{}, %q, %q, %q); err != nil {\n\t\treturn\n\t}\n", goName(info.Name), info.Schema, info.Name, oneLine(info.Comment))
+    }
+    fmt.Fprintf(&out, "\treturn\n}\n")
+    return format.Source(out.Bytes())
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+    "io/ioutil"
+    "path/filepath"
+    "strings"
+    "testing"
+    "database/sql"
+    _ "github.com/mattn/go-sqlite3"
+    "github.com/princeofdatamining/golib/sqlutil/dialect"
+)
+
+var testReverseColumns = []*columnMeta{
+    { name: "id"       , gotype: typeInt64  , notnull: true, size: -1, precision: -1 },
+    { name: "user_name", gotype: typeString , notnull: true, size: 64, precision: -1 },
+    { name: "score"    , gotype: typeFloat32, size: -1, precision: -1 },
+    { name: "avatar"   , gotype: typeBytes  , size: -1, precision: -1 },
+    { name: "created"  , gotype: typeTime   , size: -1, precision: -1 },
+}
+
+// createReverseTable creates the table the way sqlutil renders it.
+func createReverseTable(t *testing.T, d dialect.Dialect, db *sql.DB) () {
+    var lines []string
+    cols := make([]dialect.ColumnMeta, len(testReverseColumns))
+    for i, col := range testReverseColumns {
+        lines = append(lines, d.CreateColumnStr(col))
+        cols[i] = col
+    }
+    lines = append(lines, d.CreatePrimaryKey("id", cols[0]))
+    query := strings.Replace(d.CreateTableSQL("", "users", false, nil), "%s", strings.Join(lines, ",\n"), 1)
+    if _, err := db.Exec(query); err != nil {
+        t.Fatalf("create table: %v\n%s", err, query)
+    }
+    if _, err := db.Exec("CREATE UNIQUE INDEX `user_name` ON `users` (`user_name`)"); err != nil {
+        t.Fatalf("create index: %v", err)
+    }
+}
+
+func TestReverse(t *testing.T) () {
+    d, err := dialect.Open("sqlite", map[string]string{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    path := filepath.Join(t.TempDir(), "reverse.db")
+    db, err := sql.Open("sqlite3", path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+    createReverseTable(t, d, db)
+
+    info, err := d.(dialect.Inspector).InspectTable(db, "", "users")
+    if err != nil {
+        t.Fatal(err)
+    }
+    gen := NewGenerator(d, "models")
+    // every inspected column renders the DDL it was created with
+    for i, col := range info.Columns {
+        want := d.CreateColumnStr(testReverseColumns[i])
+        if got := d.CreateColumnStr(gen.columnMeta(col)); got != want {
+            t.Errorf("column %s renders %q, want %q", col.Name, got, want)
+        }
+    }
+
+    gen.Add(info)
+    src, err := gen.Source()
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, field := range []string{
+        "package models",
+        "type Users struct {",
+        "Id       int64  `db:\"primary,notnull\"`",
+        "UserName string `db:\"name=user_name,unique,notnull,size=64\"`",
+        "Score    float32",
+        "Avatar   []byte",
+        "Created  time.Time",
+        "dbmap.AddTable3(Users{}, \"\", \"users\", \"\")",
+    } {
+        if !strings.Contains(string(src), field) {
+            t.Errorf("generated source misses %q:\n%s", field, src)
+        }
+    }
+
+    // the command writes the same file
+    output := filepath.Join(t.TempDir(), "tables.go")
+    if err = run("sqlite3", path, "sqlite", "", "", "models", false, output); err != nil {
+        t.Fatal(err)
+    }
+    if written, _ := ioutil.ReadFile(output); string(written) != string(src) {
+        t.Errorf("run wrote:\n%s\nwant:\n%s", written, src)
+    }
+}
@@ -0,0 +1,98 @@
+// sqlreverse connects to an existing database and prints Go struct
+// definitions whose `db` tags map back onto the same tables through
+// sqlutil.DbMap.
+//
+//  sqlreverse -driver mysql -dsn 'user:pass@/db' -package models -o models/tables.go
+//
+// Database drivers are linked in with build tags (mysql, postgres, sqlite3):
+//
+//  go build -tags mysql github.com/princeofdatamining/golib/cmd/sqlreverse
+package main
+
+import (
+    "flag"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "strings"
+    "database/sql"
+    "github.com/princeofdatamining/golib/sqlutil/dialect"
+)
+
+// database/sql driver name => sqlutil dialect name
+var driverDialects = map[string]string{
+    "mysql": "mysql",
+    "postgres": "postgres",
+    "sqlite3": "sqlite",
+}
+
+func main() {
+    var (
+        driverName  = flag.String("driver", "", "database/sql driver name")
+        dsn         = flag.String("dsn", "", "data source name")
+        dialectName = flag.String("dialect", "", "sqlutil dialect, derived from -driver by default")
+        schemaName  = flag.String("schema", "", "schema/database to inspect, current one by default")
+        tableNames  = flag.String("tables", "", "comma separated tables, all tables by default")
+        pkg         = flag.String("package", "models", "package name of generated file")
+        nullable    = flag.Bool("null", false, "use sql.Null* types for nullable columns")
+        output      = flag.String("o", "", "output file, stdout by default")
+    )
+    flag.Parse()
+    if *driverName == "" || *dsn == "" {
+        flag.Usage()
+        os.Exit(2)
+    }
+    if *dialectName == "" {
+        *dialectName = driverDialects[*driverName]
+    }
+    if err := run(*driverName, *dsn, *dialectName, *schemaName, *tableNames, *pkg, *nullable, *output); err != nil {
+        fmt.Fprintln(os.Stderr, "sqlreverse:", err)
+        os.Exit(1)
+    }
+}
+
+func run(driverName, dsn, dialectName, schemaName, tableNames, pkg string, nullable bool, output string) (err error) {
+    d, err := dialect.Open(dialectName, map[string]string{})
+    if err != nil {
+        return
+    }
+    inspector, ok := d.(dialect.Inspector)
+    if !ok {
+        return fmt.Errorf("dialect %q can not inspect tables", dialectName)
+    }
+    db, err := sql.Open(driverName, dsn)
+    if err != nil {
+        return
+    }
+    defer db.Close()
+
+    var tables []string
+    if tableNames != "" {
+        for _, t := range strings.Split(tableNames, ",") {
+            if t = strings.TrimSpace(t); t != "" {
+                tables = append(tables, t)
+            }
+        }
+    } else if tables, err = inspector.ListTables(db, schemaName); err != nil {
+        return
+    }
+
+    gen := NewGenerator(d, pkg)
+    gen.Nullable = nullable
+    for _, t := range tables {
+        var info *dialect.TableInfo
+        if info, err = inspector.InspectTable(db, schemaName, t); err != nil {
+            return
+        }
+        gen.Add(info)
+    }
+    src, err := gen.Source()
+    if err != nil {
+        return
+    }
+    if output == "" {
+        _, err = os.Stdout.Write(src)
+        return
+    }
+    return ioutil.WriteFile(output, src, 0644)
+}
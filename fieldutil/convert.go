@@ -0,0 +1,88 @@
+package fieldutil
+
+import (
+    "fmt"
+    "reflect"
+    "strconv"
+    "time"
+)
+
+var (
+    typeTime = reflect.TypeOf(time.Time{})
+    typeBytes = reflect.TypeOf([]byte(nil))
+
+    // layouts accepted when text is converted into time.Time fields
+    TimeLayouts = []string{
+        time.RFC3339Nano,
+        "2006-01-02 15:04:05",
+        "2006-01-02",
+    }
+)
+
+// scanner is sql.Scanner, without importing database/sql.
+type scanner interface {
+    Scan(src interface{}) (error)
+}
+
+func parseTime(s string) (t time.Time, err error) {
+    for _, layout := range TimeLayouts {
+        if t, err = time.Parse(layout, s); err == nil {
+            return
+        }
+    }
+    return
+}
+
+// SetString converts text into the field according to its Go type:
+// numbers, bools, time.Time by TimeLayouts, sql.Scanner types by Scan,
+// pointers to any of them.
+func SetString(f reflect.Value, s string) (err error) {
+    if f.Kind() == reflect.Ptr {
+        ptr := reflect.New(f.Type().Elem())
+        if err = SetString(ptr.Elem(), s); err == nil {
+            f.Set(ptr)
+        }
+        return
+    }
+    switch {
+    case f.Type() == typeTime:
+        var t time.Time
+        if t, err = parseTime(s); err == nil {
+            f.Set(reflect.ValueOf(t))
+        }
+        return
+    case f.Type() == typeBytes:
+        f.SetBytes([]byte(s))
+        return
+    }
+    if sc, ok := f.Addr().Interface().(scanner); ok {
+        return sc.Scan(s)
+    }
+    switch f.Kind() {
+    case reflect.String:
+        f.SetString(s)
+    case reflect.Bool:
+        var b bool
+        if b, err = strconv.ParseBool(s); err == nil {
+            f.SetBool(b)
+        }
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        var i int64
+        if i, err = strconv.ParseInt(s, 10, f.Type().Bits()); err == nil {
+            f.SetInt(i)
+        }
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        var u uint64
+        if u, err = strconv.ParseUint(s, 10, f.Type().Bits()); err == nil {
+            f.SetUint(u)
+        }
+    case reflect.Float32, reflect.Float64:
+        var x float64
+        if x, err = strconv.ParseFloat(s, f.Type().Bits()); err == nil {
+            f.SetFloat(x)
+        }
+    default:
+        err = fmt.Errorf("can not convert text into %v", f.Type())
+    }
+    return
+}
@@ -0,0 +1,48 @@
+package fieldutil
+
+import (
+    "reflect"
+    "testing"
+    "time"
+    "database/sql"
+)
+
+func TestParseRules(t *testing.T) () {
+    rules, required, err := ParseRules("Code", "required,len=4,regexp=^[0-9a-f,]+$")
+    if err != nil || !required || len(rules) != 2 {
+        t.Fatalf("rules must parse, but got %v %v %v\n", rules, required, err)
+    }
+    if rules[1].Name != "regexp" || rules[1].Param != "^[0-9a-f,]+$" {
+        t.Fatalf("regexp must consume the rest of the tag, but got %q\n", rules[1].Param)
+    }
+    for _, tag := range []string{ "min=x", "len=", "regexp=[", "nosuch" } {
+        if _, _, err := ParseRules("Code", tag); err == nil {
+            t.Fatalf("tag %q must fail\n", tag)
+        }
+    }
+}
+
+func TestSetString(t *testing.T) () {
+    var dst struct {
+        I   int
+        U   *uint8
+        B   bool
+        F   float64
+        T   time.Time
+        N   sql.NullInt64
+        Raw []byte
+    }
+    v := reflect.ValueOf(&dst).Elem()
+    for i, s := range []string{ "-3", "7", "true", "1.5", "2024-01-02", "9", "raw" } {
+        if err := SetString(v.Field(i), s); err != nil {
+            t.Fatalf("field %d from %q: %v\n", i, s, err)
+        }
+    }
+    if dst.I != -3 || dst.U == nil || *dst.U != 7 || !dst.B || dst.F != 1.5 ||
+        !dst.T.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) || dst.N.Int64 != 9 || string(dst.Raw) != "raw" {
+        t.Fatalf("fields must be set, but got %+v\n", dst)
+    }
+    if err := SetString(v.Field(1), "300"); err == nil {
+        t.Fatalf("out of range numbers must fail\n")
+    }
+}
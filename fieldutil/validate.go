@@ -0,0 +1,190 @@
+// Package fieldutil checks and sets struct fields by reflection, shared
+// by sqlutil and httputil: the rules of `validate` tags and the
+// conversion of text into fields.
+package fieldutil
+
+import (
+    "fmt"
+    "reflect"
+    "regexp"
+    "strconv"
+    "strings"
+    "sync"
+    "unicode/utf8"
+)
+
+/*
+    Name    string  `validate:"required,min=2"`
+    State   string  `validate:"enum=new|done"`
+    Code    string  `validate:"len=6,regexp=^[0-9a-f]+$"`
+
+    Built in rules are required, min, max, len, enum, email and regexp,
+    which consumes the rest of the tag so its pattern may hold commas.
+    min, max and len compare numbers by value, strings by rune count
+    and slices or maps by length.
+//*/
+
+// ValidatorFunc checks one (dereferenced) field value, param is the
+// text after "=" in the tag. Return nil when the value is valid.
+type ValidatorFunc func (v reflect.Value, param string) (error)
+
+var (
+    validatorsLock sync.RWMutex
+    validators = map[string]ValidatorFunc{
+        "min": validateMin,
+        "max": validateMax,
+        "len": validateLen,
+        "enum": validateEnum,
+        "email": validateEmail,
+    }
+
+    reEmail = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+)
+
+// RegisterValidator adds a named rule usable in `validate` tags. Rules
+// are resolved when tags are parsed, e.g. by sqlutil.AddTable or the
+// first httputil.Bind of a type, register them before.
+func RegisterValidator(name string, f ValidatorFunc) () {
+    validatorsLock.Lock()
+    defer validatorsLock.Unlock()
+    validators[name] = f
+}
+func getValidator(name string) (f ValidatorFunc, ok bool) {
+    validatorsLock.RLock()
+    defer validatorsLock.RUnlock()
+    f, ok = validators[name]
+    return
+}
+
+// Rule is one compiled rule of a `validate` tag.
+type Rule struct {
+    Name    string
+    Param   string
+    Check   ValidatorFunc
+}
+
+func splitRules(tag string) (rules [][2]string) {
+    for tag != "" {
+        var field string
+        if strings.HasPrefix(tag, "regexp=") {
+            field, tag = tag, ""
+        } else if i := strings.Index(tag, ","); i >= 0 {
+            field, tag = tag[:i], tag[i+1:]
+        } else {
+            field, tag = tag, ""
+        }
+        if field = strings.TrimSpace(field); field == "" {
+            continue
+        }
+        parts := strings.SplitN(field, "=", 2)
+        if len(parts) < 2 {
+            parts = append(parts, "")
+        }
+        rules = append(rules, [2]string{parts[0], parts[1]})
+    }
+    return
+}
+
+// ParseRules compiles the `validate` tag of field with the registered
+// validators. "required" is returned apart, callers check it first and
+// skip the rules of missing values.
+func ParseRules(field, tag string) (rules []*Rule, required bool, err error) {
+    for _, rule := range splitRules(tag) {
+        name, param := rule[0], rule[1]
+        switch name {
+        case "required":
+            required = true
+            continue
+        case "regexp":
+            re, err := regexp.Compile(param)
+            if err != nil {
+                return nil, false, fmt.Errorf("validate: field %q rule %q: bad parameter %q", field, name, param)
+            }
+            rules = append(rules, &Rule{name, param, func (v reflect.Value, param string) (error) {
+                if v.Kind() == reflect.String && !re.MatchString(v.String()) {
+                    return fmt.Errorf("must match %s", param)
+                }
+                return nil
+            }})
+            continue
+        case "min", "max", "len":
+            if _, err := strconv.ParseFloat(param, 64); err != nil {
+                return nil, false, fmt.Errorf("validate: field %q rule %q: bad parameter %q", field, name, param)
+            }
+        }
+        check, ok := getValidator(name)
+        if !ok {
+            return nil, false, fmt.Errorf("validate: field %q uses unknown rule %q", field, name)
+        }
+        rules = append(rules, &Rule{name, param, check})
+    }
+    return
+}
+
+func sizeOf(v reflect.Value) (n float64, ok bool) {
+    switch v.Kind() {
+    case reflect.String:
+        return float64(utf8.RuneCountInString(v.String())), true
+    case reflect.Slice, reflect.Map, reflect.Array:
+        return float64(v.Len()), true
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return float64(v.Int()), true
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return float64(v.Uint()), true
+    case reflect.Float32, reflect.Float64:
+        return v.Float(), true
+    }
+    return 0, false
+}
+func isNumber(v reflect.Value) (bool) {
+    switch v.Kind() {
+    case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+        return false
+    }
+    return true
+}
+func validateMin(v reflect.Value, param string) (error) {
+    n, ok := sizeOf(v)
+    limit, _ := strconv.ParseFloat(param, 64)
+    if !ok || n >= limit {
+        return nil
+    }
+    if isNumber(v) {
+        return fmt.Errorf("must be at least %s", param)
+    }
+    return fmt.Errorf("length must be at least %s", param)
+}
+func validateMax(v reflect.Value, param string) (error) {
+    n, ok := sizeOf(v)
+    limit, _ := strconv.ParseFloat(param, 64)
+    if !ok || n <= limit {
+        return nil
+    }
+    if isNumber(v) {
+        return fmt.Errorf("must be at most %s", param)
+    }
+    return fmt.Errorf("length must be at most %s", param)
+}
+func validateLen(v reflect.Value, param string) (error) {
+    n, ok := sizeOf(v)
+    limit, _ := strconv.ParseFloat(param, 64)
+    if !ok || isNumber(v) || n == limit {
+        return nil
+    }
+    return fmt.Errorf("length must be %s", param)
+}
+func validateEnum(v reflect.Value, param string) (error) {
+    s := fmt.Sprint(v.Interface())
+    for _, option := range strings.Split(param, "|") {
+        if s == option {
+            return nil
+        }
+    }
+    return fmt.Errorf("must be one of %s", strings.Replace(param, "|", ", ", -1))
+}
+func validateEmail(v reflect.Value, param string) (error) {
+    if v.Kind() == reflect.String && v.Len() > 0 && !reEmail.MatchString(v.String()) {
+        return fmt.Errorf("must be an email address")
+    }
+    return nil
+}
@@ -0,0 +1,339 @@
+package httputil
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "math/rand"
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+/*
+    proxies, _ := ParseTrustedProxies("10.0.0.0/8")
+    file, _ := OpenRotatingFile("/var/log/app/access.log", 100 << 20, 5)
+    chain := NewChainHandler(NewAccessLog(&AccessLogOptions{
+        Format: FormatLogfmt,
+        Sink: file,
+        SampleRate: 0.1,
+        TrustedProxies: proxies,
+    }), ...)
+
+    Every entry is written with a single Write call ending with a newline.
+//*/
+
+// AccessEntry is one served request.
+type AccessEntry struct {
+    Time        time.Time
+    Method      string
+    Host        string
+    Path        string
+    Proto       string
+    // rule the router matched, empty when none did
+    Rule        string
+    Status      int
+    Size        int
+    Latency     time.Duration
+    RemoteIP    string
+    UserAgent   string
+    Referer     string
+    RequestID   string
+    TraceID     string
+}
+
+// AccessFormatter appends one line, without newline.
+type AccessFormatter func (buf *bytes.Buffer, e *AccessEntry) ()
+
+type AccessLogOptions struct {
+    // FormatJSON by default
+    Format          AccessFormatter
+    // os.Stderr by default, writes are serialized
+    Sink            io.Writer
+    // fraction of requests logged, 0 logs them all, 5xx are always logged
+    SampleRate      float64
+    // overrides SampleRate when set
+    Sample          func (e *AccessEntry) (bool)
+    // proxies allowed to tell the client address
+    TrustedProxies  TrustedProxies
+}
+
+func NewAccessLog(opts *AccessLogOptions) (Handler) {
+    this := &AccessLog{}
+    if opts != nil {
+        this.AccessLogOptions = *opts
+    }
+    if this.Format == nil {
+        this.Format = FormatJSON
+    }
+    if this.Sink == nil {
+        this.Sink = os.Stderr
+    }
+    return this
+}
+
+type AccessLog struct {
+    AccessLogOptions
+    sinkLock    sync.Mutex
+}
+
+var accessBuffers = sync.Pool{
+    New: func () (interface{}) { return new(bytes.Buffer) },
+}
+
+func (this *AccessLog) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+    start := time.Now()
+    rw, ok := w.(ResponseWriter)
+    if !ok {
+        rw = NewResponseWriter(w)
+    }
+    matched := &matchedRoute{}
+    next(rw, r.WithContext(context.WithValue(r.Context(), matchedKey{}, matched)))
+
+    status := rw.Status()
+    if status == 0 {
+        // nothing written, net/http replies 200
+        status = http.StatusOK
+    }
+    e := &AccessEntry{
+        Time: start,
+        Method: r.Method,
+        Host: r.Host,
+        Path: r.URL.RequestURI(),
+        Proto: r.Proto,
+        Rule: matched.rule,
+        Status: status,
+        Size: rw.Size(),
+        Latency: time.Since(start),
+        RemoteIP: this.TrustedProxies.ClientIP(r),
+        UserAgent: r.UserAgent(),
+        Referer: r.Referer(),
+        RequestID: requestID(rw, r),
+    }
+    if trace, ok := TraceFromContext(r.Context()); ok {
+        e.TraceID = trace.TraceID
+    } else if trace, ok := ParseTraceparent(rw.Header().Get(XHEADER_TRACEPARENT)); ok {
+        e.TraceID = trace.TraceID
+    }
+    if !this.sampled(e) {
+        return
+    }
+    this.Log(e)
+}
+
+func (this *AccessLog) sampled(e *AccessEntry) (bool) {
+    switch {
+    case this.Sample != nil:
+        return this.Sample(e)
+    case e.Status >= 500 || this.SampleRate <= 0 || this.SampleRate >= 1:
+        return true
+    }
+    return rand.Float64() < this.SampleRate
+}
+
+// Log formats e and writes it to the sink.
+func (this *AccessLog) Log(e *AccessEntry) () {
+    buf := accessBuffers.Get().(*bytes.Buffer)
+    buf.Reset()
+    this.Format(buf, e)
+    buf.WriteByte('\n')
+    this.sinkLock.Lock()
+    this.Sink.Write(buf.Bytes())
+    this.sinkLock.Unlock()
+    accessBuffers.Put(buf)
+}
+
+//
+
+// FormatJSON writes one JSON object, latency in milliseconds.
+func FormatJSON(buf *bytes.Buffer, e *AccessEntry) () {
+    data, _ := json.Marshal(struct {
+        Time        string  `json:"time"`
+        Method      string  `json:"method"`
+        Host        string  `json:"host"`
+        Path        string  `json:"path"`
+        Proto       string  `json:"proto"`
+        Rule        string  `json:"rule,omitempty"`
+        Status      int     `json:"status"`
+        Size        int     `json:"size"`
+        Latency     float64 `json:"latency_ms"`
+        RemoteIP    string  `json:"remote_ip"`
+        UserAgent   string  `json:"user_agent,omitempty"`
+        Referer     string  `json:"referer,omitempty"`
+        RequestID   string  `json:"request_id,omitempty"`
+        TraceID     string  `json:"trace_id,omitempty"`
+    }{
+        e.Time.Format(time.RFC3339Nano), e.Method, e.Host, e.Path, e.Proto, e.Rule,
+        e.Status, e.Size, latencyMillis(e.Latency), e.RemoteIP, e.UserAgent, e.Referer, e.RequestID, e.TraceID,
+    })
+    buf.Write(data)
+}
+
+// FormatLogfmt writes key=value pairs, quoting values when needed.
+func FormatLogfmt(buf *bytes.Buffer, e *AccessEntry) () {
+    first := true
+    pair := func (key, value string) () {
+        if value == "" {
+            return
+        }
+        if !first {
+            buf.WriteByte(' ')
+        }
+        first = false
+        buf.WriteString(key)
+        buf.WriteByte('=')
+        if strings.ContainsAny(value, " =\"\\") || strings.IndexFunc(value, isControl) >= 0 {
+            value = strconv.Quote(value)
+        }
+        buf.WriteString(value)
+    }
+    pair("time", e.Time.Format(time.RFC3339Nano))
+    pair("method", e.Method)
+    pair("host", e.Host)
+    pair("path", e.Path)
+    pair("proto", e.Proto)
+    pair("rule", e.Rule)
+    pair("status", strconv.Itoa(e.Status))
+    pair("size", strconv.Itoa(e.Size))
+    pair("latency_ms", strconv.FormatFloat(latencyMillis(e.Latency), 'f', -1, 64))
+    pair("remote_ip", e.RemoteIP)
+    pair("user_agent", e.UserAgent)
+    pair("referer", e.Referer)
+    pair("request_id", e.RequestID)
+    pair("trace_id", e.TraceID)
+}
+
+// FormatCombined writes the Apache combined log format.
+func FormatCombined(buf *bytes.Buffer, e *AccessEntry) () {
+    size := "-"
+    if e.Size > 0 {
+        size = strconv.Itoa(e.Size)
+    }
+    fmt.Fprintf(buf, "%s - - [%s] %s %d %s %s %s",
+        e.RemoteIP,
+        e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+        combinedQuote(e.Method + " " + e.Path + " " + e.Proto),
+        e.Status,
+        size,
+        combinedQuote(e.Referer),
+        combinedQuote(e.UserAgent),
+    )
+}
+
+func combinedQuote(s string) (string) {
+    if s == "" {
+        return `"-"`
+    }
+    return strconv.Quote(s)
+}
+
+func isControl(r rune) (bool) { return r < ' ' || r == 0x7f }
+
+func latencyMillis(d time.Duration) (float64) {
+    return float64(d.Microseconds()) / 1000
+}
+
+//
+
+// RotatingFile is a sink renaming path to path.1, path.1 to path.2 and
+// so on once it grows past MaxSize bytes, keeping Backups old files.
+type RotatingFile struct {
+    sync.Mutex
+    Path        string
+    // 0 never rotates by size, Rotate can still be called e.g. on SIGHUP
+    MaxSize     int64
+    Backups     int
+    file        *os.File
+    size        int64
+}
+
+func OpenRotatingFile(path string, maxSize int64, backups int) (*RotatingFile, error) {
+    this := &RotatingFile{
+        Path: path,
+        MaxSize: maxSize,
+        Backups: backups,
+    }
+    if err := this.open(); err != nil {
+        return nil, err
+    }
+    return this, nil
+}
+
+func (this *RotatingFile) open() (error) {
+    file, err := os.OpenFile(this.Path, os.O_CREATE | os.O_WRONLY | os.O_APPEND, 0644)
+    if err != nil {
+        return err
+    }
+    info, err := file.Stat()
+    if err != nil {
+        file.Close()
+        return err
+    }
+    this.file, this.size = file, info.Size()
+    return nil
+}
+
+func (this *RotatingFile) Write(p []byte) (int, error) {
+    this.Lock()
+    defer this.Unlock()
+    if this.file == nil {
+        return 0, os.ErrClosed
+    }
+    if this.MaxSize > 0 && this.size > 0 && this.size + int64(len(p)) > this.MaxSize {
+        if err := this.rotate(); err != nil {
+            return 0, err
+        }
+    }
+    n, err := this.file.Write(p)
+    this.size += int64(n)
+    return n, err
+}
+
+// Rotate starts a new file.
+func (this *RotatingFile) Rotate() (error) {
+    this.Lock()
+    defer this.Unlock()
+    return this.rotate()
+}
+
+func (this *RotatingFile) rotate() (error) {
+    // the old file stays open until its successor is, writes go on to it
+    // when renaming fails
+    if this.Backups <= 0 {
+        if err := os.Remove(this.Path); err != nil && !os.IsNotExist(err) {
+            return err
+        }
+    } else {
+        os.Remove(fmt.Sprintf("%s.%d", this.Path, this.Backups))
+        for i := this.Backups-1; i >= 1; i-- {
+            os.Rename(fmt.Sprintf("%s.%d", this.Path, i), fmt.Sprintf("%s.%d", this.Path, i+1))
+        }
+        if err := os.Rename(this.Path, this.Path + ".1"); err != nil && !os.IsNotExist(err) {
+            return err
+        }
+    }
+    old := this.file
+    if err := this.open(); err != nil {
+        return err
+    }
+    if old != nil {
+        return old.Close()
+    }
+    return nil
+}
+
+func (this *RotatingFile) Close() (error) {
+    this.Lock()
+    defer this.Unlock()
+    if this.file == nil {
+        return nil
+    }
+    err := this.file.Close()
+    this.file = nil
+    return err
+}
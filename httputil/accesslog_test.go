@@ -0,0 +1,110 @@
+package httputil_test
+
+import (
+    "bytes"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "time"
+    "encoding/json"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+func TestAccessLog(t *testing.T) () {
+    proxies, err := httputil.ParseTrustedProxies("10.0.0.0/8", "192.0.2.1")
+    if err != nil {
+        t.Fatal(err)
+    }
+    var sink bytes.Buffer
+    router := httputil.NewRouter()
+    router.Handle("/users/<id:int>", httputil.METHOD_GET, http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        fmt.Fprint(w, "hello")
+    }))
+    chain := httputil.NewChainHandler(httputil.NewAccessLog(&httputil.AccessLogOptions{
+        Sink: &sink,
+        TrustedProxies: proxies,
+    }), httputil.HandlerFunc(func (w http.ResponseWriter, r *http.Request, next http.HandlerFunc) () {
+        router.ServeHTTP(w, r)
+    }))
+
+    req := httptest.NewRequest(httputil.METHOD_GET, "http://example.com/users/7?x=1", nil)
+    req.RemoteAddr = "10.1.2.3:4567"
+    req.Header.Set(httputil.XHEADER_FORWARDED_FOR, "203.0.113.9, 192.0.2.1")
+    req.Header.Set(httputil.XHEADER_REQUEST_ID, "abc")
+    chain.ServeHTTP(httptest.NewRecorder(), req)
+
+    var entry map[string]interface{}
+    if err := json.Unmarshal(sink.Bytes(), &entry); err != nil {
+        t.Fatalf("invalid entry %q: %v\n", sink.String(), err)
+    }
+    for key, value := range map[string]interface{}{
+        "method": "GET",
+        "path": "/users/7?x=1",
+        "rule": "/users/<id:int>",
+        "status": float64(200),
+        "size": float64(5),
+        "remote_ip": "203.0.113.9",
+        "request_id": "abc",
+    } {
+        if entry[key] != value {
+            t.Fatalf("%s must be %v, but got %v\n", key, value, entry[key])
+        }
+    }
+
+    // untrusted peers can't forge their address
+    req.RemoteAddr = "198.51.100.1:80"
+    if ip := proxies.ClientIP(req); ip != "198.51.100.1" {
+        t.Fatalf("client ip must be the peer, but got %q\n", ip)
+    }
+
+    var buf bytes.Buffer
+    httputil.FormatCombined(&buf, &httputil.AccessEntry{
+        Time: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+        Method: "GET", Path: "/", Proto: "HTTP/1.1", Status: 404, RemoteIP: "::1", UserAgent: "curl",
+    })
+    if line := `::1 - - [02/Jan/2020:03:04:05 +0000] "GET / HTTP/1.1" 404 - "-" "curl"`; buf.String() != line {
+        t.Fatalf("combined must be %q, but got %q\n", line, buf.String())
+    }
+    buf.Reset()
+    httputil.FormatLogfmt(&buf, &httputil.AccessEntry{ Method: "GET", Path: "/a b", Status: 200 })
+    if !strings.Contains(buf.String(), `method=GET path="/a b" status=200`) {
+        t.Fatalf("unexpected logfmt %q\n", buf.String())
+    }
+}
+
+func TestRotatingFile(t *testing.T) () {
+    path := filepath.Join(t.TempDir(), "access.log")
+    file, err := httputil.OpenRotatingFile(path, 8, 1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer file.Close()
+    for _, line := range []string{ "first\n", "second\n", "third\n" } {
+        if _, err := file.Write([]byte(line)); err != nil {
+            t.Fatal(err)
+        }
+    }
+    for name, want := range map[string]string{ path: "third\n", path + ".1": "second\n" } {
+        if data, _ := ioutil.ReadFile(name); string(data) != want {
+            t.Fatalf("%s must hold %q, but got %q\n", name, want, data)
+        }
+    }
+
+    // a failed rename keeps writing to the current file
+    os.Remove(path + ".1")
+    os.MkdirAll(filepath.Join(path + ".1", "busy"), 0755)
+    if err := file.Rotate(); err == nil {
+        t.Fatalf("rotate onto a directory must fail\n")
+    }
+    if _, err := file.Write([]byte("x\n")); err != nil {
+        t.Fatalf("write after a failed rotate: %v\n", err)
+    }
+    if data, _ := ioutil.ReadFile(path); string(data) != "third\nx\n" {
+        t.Fatalf("%s must hold %q, but got %q\n", path, "third\nx\n", data)
+    }
+}
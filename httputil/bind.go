@@ -0,0 +1,462 @@
+package httputil
+
+import (
+    "encoding"
+    "encoding/json"
+    "encoding/xml"
+    "errors"
+    "fmt"
+    "io"
+    "mime/multipart"
+    "net/http"
+    "reflect"
+    "strings"
+    "sync"
+    "time"
+    "github.com/princeofdatamining/golib/fieldutil"
+)
+
+/*
+    type NewPost struct {
+        UserID  int                     `param:"id"`
+        Draft   bool                    `query:"draft"`
+        Title   string                  `json:"title" validate:"required,max=200"`
+        Tags    []string                `json:"tags" form:"tag" validate:"max=5"`
+        Cover   *multipart.FileHeader   `form:"cover"`
+    }
+
+    var post NewPost
+    if err := Bind(r, &post); err != nil {
+        err.(*BindError).ServeHTTP(w, r)
+        return
+    }
+
+    The body is decoded by its Content-Type: JSON and XML by the json and
+    xml tags, urlencoded and multipart forms by the form tags (the json
+    name when missing), file parts go to *multipart.FileHeader fields.
+    Query values and route params follow, params win. Requests without a
+    Content-Type bind no body.
+
+    Fields are checked with the `validate` rules of fieldutil: required,
+    min, max, len, enum, email, regexp, which consumes the rest of the
+    tag, and the ones added by fieldutil.RegisterValidator. Malformed
+    bodies fail with 400, too large ones 413, unknown types 415 and
+    invalid fields 422, as a *BindError that writes itself with
+    WriteError, see problem.go.
+//*/
+
+// FieldError is one invalid field, named as in its source.
+type FieldError struct {
+    Field   string  `json:"field"`
+    // "body", "query" or "param"
+    Source  string  `json:"source"`
+    Rule    string  `json:"rule"`
+    Param   string  `json:"param,omitempty"`
+    Message string  `json:"message"`
+}
+func (this *FieldError) Error() (string) {
+    return fmt.Sprintf("%s: %s", this.Field, this.Message)
+}
+
+type BindError struct {
+    Status  int
+    Detail  string
+    Errors  []*FieldError
+}
+func (this *BindError) Error() (string) {
+    msgs := []string{ this.Detail }
+    for _, e := range this.Errors {
+        msgs = append(msgs, e.Error())
+    }
+    return "httputil: bind: " + strings.Join(msgs, "; ")
+}
+
+// ServeHTTP writes the error with WriteError, as a problem with the
+// field errors in "errors".
+func (this *BindError) ServeHTTP(w http.ResponseWriter, r *http.Request) () { WriteError(w, r, this) }
+
+//
+
+type BindOptions struct {
+    // longer bodies fail with 413, 10 MB by default
+    MaxBodySize         int64
+    // multipart data beyond is stored in temporary files, 32 MB by default
+    MaxMemory           int64
+    // JSON fields dst does not have fail with 400
+    DisallowUnknownFields bool
+}
+
+func NewBinder(opts *BindOptions) (*Binder) {
+    this := &Binder{
+        BindOptions: BindOptions{
+            MaxBodySize: 10 << 20,
+            MaxMemory: 32 << 20,
+        },
+    }
+    if opts != nil {
+        if opts.MaxBodySize > 0 {
+            this.MaxBodySize = opts.MaxBodySize
+        }
+        if opts.MaxMemory > 0 {
+            this.MaxMemory = opts.MaxMemory
+        }
+        this.DisallowUnknownFields = opts.DisallowUnknownFields
+    }
+    return this
+}
+
+type Binder struct {
+    BindOptions
+}
+
+var DefaultBinder = NewBinder(nil)
+
+// Bind fills the struct dst points to with DefaultBinder.
+func Bind(r *http.Request, dst interface{}) (error) { return DefaultBinder.Bind(r, dst) }
+
+// Bind fills the struct dst points to, errors are *BindError. It panics
+// when dst is no struct pointer or its tags are invalid.
+func (this *Binder) Bind(r *http.Request, dst interface{}) (error) {
+    v := reflect.ValueOf(dst)
+    if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+        panic(fmt.Sprintf("httputil: Bind needs a pointer to a struct, got %T", dst))
+    }
+    obj := v.Elem()
+    fields := getBindFields(obj.Type())
+
+    var errs []*FieldError
+    failed := make(map[*bindField]bool)
+    fail := func (f *bindField, source, name string, err error) () {
+        failed[f] = true
+        errs = append(errs, &FieldError{ Field: name, Source: source, Rule: "type", Message: err.Error() })
+    }
+
+    form, files, err := this.bindBody(r, obj)
+    if err != nil {
+        return err
+    }
+    query := r.URL.Query()
+    params := RequestParams(r)
+    for _, f := range fields {
+        field := obj.FieldByIndex(f.index)
+        if f.form != "" {
+            if fh, ok := files[f.form]; ok && f.file {
+                setFiles(field, fh)
+            } else if values, ok := form[f.form]; ok && !f.file {
+                if err := setValues(field, values); err != nil {
+                    fail(f, "body", f.form, err)
+                }
+            }
+        }
+        if values, ok := query[f.query]; ok && f.query != "" {
+            if err := setValues(field, values); err != nil {
+                fail(f, "query", f.query, err)
+            }
+        }
+        if param, ok := params.Get(f.param); ok {
+            if err := setValues(field, []string{ param.Value }); err != nil {
+                fail(f, "param", f.param, err)
+            }
+        }
+    }
+
+    for _, f := range fields {
+        if !failed[f] {
+            errs = append(errs, f.validate(obj.FieldByIndex(f.index))...)
+        }
+    }
+    if len(errs) > 0 {
+        return &BindError{ Status: http.StatusUnprocessableEntity, Detail: "invalid fields", Errors: errs }
+    }
+    return nil
+}
+
+// bindBody decodes JSON and XML into obj and returns form values.
+func (this *Binder) bindBody(r *http.Request, obj reflect.Value) (form map[string][]string, files map[string][]*multipart.FileHeader, err error) {
+    if r.Body == nil || r.Body == http.NoBody || r.Header.Get(HEADER_CONTENT_TYPE) == "" {
+        return nil, nil, nil
+    }
+    if r.ContentLength > this.MaxBodySize {
+        return nil, nil, errBodyTooLarge(this.MaxBodySize)
+    }
+    r.Body = http.MaxBytesReader(nil, r.Body, this.MaxBodySize)
+
+    ct := ResolveContentType(r)
+    switch {
+    case ct == "application/json" || strings.HasSuffix(ct, "+json"):
+        dec := json.NewDecoder(r.Body)
+        if this.DisallowUnknownFields {
+            dec.DisallowUnknownFields()
+        }
+        err = dec.Decode(obj.Addr().Interface())
+    case ct == "application/xml" || ct == "text/xml" || strings.HasSuffix(ct, "+xml"):
+        err = xml.NewDecoder(r.Body).Decode(obj.Addr().Interface())
+    case ct == "application/x-www-form-urlencoded":
+        if err = r.ParseForm(); err == nil {
+            form = r.PostForm
+        }
+    case ct == "multipart/form-data":
+        if err = r.ParseMultipartForm(this.MaxMemory); err == nil {
+            form, files = r.MultipartForm.Value, r.MultipartForm.File
+        }
+    default:
+        return nil, nil, &BindError{ Status: http.StatusUnsupportedMediaType, Detail: fmt.Sprintf("unsupported content type %q", ct) }
+    }
+
+    var (
+        tooLarge    *http.MaxBytesError
+        typeErr     *json.UnmarshalTypeError
+        syntaxErr   *json.SyntaxError
+    )
+    switch {
+    case err == nil || err == io.EOF:
+        return form, files, nil
+    case errors.As(err, &tooLarge):
+        return nil, nil, errBodyTooLarge(this.MaxBodySize)
+    case errors.As(err, &typeErr):
+        return nil, nil, &BindError{
+            Status: http.StatusUnprocessableEntity,
+            Detail: "invalid fields",
+            Errors: []*FieldError{{
+                Field: typeErr.Field,
+                Source: "body",
+                Rule: "type",
+                Message: fmt.Sprintf("cannot be a JSON %s", typeErr.Value),
+            }},
+        }
+    case errors.As(err, &syntaxErr):
+        return nil, nil, &BindError{ Status: http.StatusBadRequest, Detail: fmt.Sprintf("malformed body at offset %d: %v", syntaxErr.Offset, err) }
+    }
+    return nil, nil, &BindError{ Status: http.StatusBadRequest, Detail: "malformed body: " + err.Error() }
+}
+
+func errBodyTooLarge(limit int64) (error) {
+    return &BindError{ Status: http.StatusRequestEntityTooLarge, Detail: fmt.Sprintf("body exceeds %d bytes", limit) }
+}
+
+//
+
+type bindField struct {
+    index   []int
+    // error name and source of validation failures
+    name    string
+    source  string
+    form    string
+    query   string
+    param   string
+    // *multipart.FileHeader or a slice of them
+    file    bool
+    required bool
+    rules   []*fieldutil.Rule
+}
+
+var (
+    bindFieldsCache sync.Map
+
+    fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+    durationType = reflect.TypeOf(time.Duration(0))
+    textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func getBindFields(t reflect.Type) ([]*bindField) {
+    if fields, ok := bindFieldsCache.Load(t); ok {
+        return fields.([]*bindField)
+    }
+    fields := buildBindFields(t, nil)
+    bindFieldsCache.Store(t, fields)
+    return fields
+}
+
+// buildBindFields lists the exported fields of t, embedded structs are
+// flattened.
+func buildBindFields(t reflect.Type, index []int) (fields []*bindField) {
+    for i := 0; i < t.NumField(); i++ {
+        sf := t.Field(i)
+        idx := append(append([]int(nil), index...), i)
+        if sf.Anonymous && sf.Type.Kind() == reflect.Struct && sf.Tag.Get("json") == "" {
+            fields = append(fields, buildBindFields(sf.Type, idx)...)
+            continue
+        }
+        if sf.PkgPath != "" {
+            continue
+        }
+        f := &bindField{
+            index: idx,
+            form: tagName(sf.Tag.Get("form")),
+            query: tagName(sf.Tag.Get("query")),
+            param: tagName(sf.Tag.Get("param")),
+            file: sf.Type == fileHeaderType || sf.Type == reflect.SliceOf(fileHeaderType),
+        }
+        jsonName := tagName(sf.Tag.Get("json"))
+        if f.form == "" && sf.Tag.Get("form") != "-" {
+            f.form = jsonName
+        }
+        for _, name := range []string{ f.param, f.query } {
+            if name != "" && !canBind(sf.Type) {
+                panic(fmt.Sprintf("httputil: cannot bind %s field %s", sf.Type, sf.Name))
+            }
+        }
+        if f.form != "" && !f.file && !canBind(sf.Type) {
+            // e.g. nested JSON objects
+            f.form = ""
+        }
+        switch {
+        case f.param != "":
+            f.name, f.source = f.param, "param"
+        case f.query != "":
+            f.name, f.source = f.query, "query"
+        case f.form != "":
+            f.name, f.source = f.form, "body"
+        case jsonName != "":
+            f.name, f.source = jsonName, "body"
+        default:
+            f.name, f.source = sf.Name, "body"
+        }
+        if err := f.buildRules(sf.Tag.Get("validate")); err != nil {
+            panic(err.Error())
+        }
+        fields = append(fields, f)
+    }
+    return
+}
+
+// tagName is the name part of a tag, "" for "-".
+func tagName(tag string) (string) {
+    name := strings.Split(tag, ",")[0]
+    if name == "-" {
+        return ""
+    }
+    return name
+}
+
+func canBind(t reflect.Type) (bool) {
+    if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+        t = t.Elem()
+    }
+    if t.Kind() == reflect.Ptr {
+        t = t.Elem()
+    }
+    if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+        return true
+    }
+    switch t.Kind() {
+    case reflect.String, reflect.Bool,
+        reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+        reflect.Float32, reflect.Float64:
+        return true
+    }
+    return false
+}
+
+func setFiles(field reflect.Value, files []*multipart.FileHeader) () {
+    if field.Kind() == reflect.Slice {
+        field.Set(reflect.ValueOf(files))
+    } else if len(files) > 0 {
+        field.Set(reflect.ValueOf(files[0]))
+    }
+}
+
+// setValues sets slices to all values, other fields to the first.
+func setValues(field reflect.Value, values []string) (error) {
+    if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+        slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+        for i, s := range values {
+            if err := setValue(slice.Index(i), s); err != nil {
+                return err
+            }
+        }
+        field.Set(slice)
+        return nil
+    }
+    if len(values) <= 0 {
+        return nil
+    }
+    return setValue(field, values[0])
+}
+
+func setValue(v reflect.Value, s string) (error) {
+    if v.Kind() == reflect.Ptr {
+        elem := reflect.New(v.Type().Elem())
+        if err := setValue(elem.Elem(), s); err != nil {
+            return err
+        }
+        v.Set(elem)
+        return nil
+    }
+    if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+        if err := u.UnmarshalText([]byte(s)); err != nil {
+            return fmt.Errorf("must be a valid %s", v.Type())
+        }
+        return nil
+    }
+    if s == "" && v.Kind() != reflect.String {
+        // e.g. an empty input
+        v.Set(reflect.Zero(v.Type()))
+        return nil
+    }
+    switch {
+    case v.Type() == durationType:
+        d, err := time.ParseDuration(s)
+        if err != nil {
+            return fmt.Errorf("must be a duration")
+        }
+        v.SetInt(int64(d))
+        return nil
+    case v.Kind() == reflect.Bool && s == "on":
+        s = "true"
+    }
+    if err := fieldutil.SetString(v, s); err != nil {
+        switch v.Kind() {
+        case reflect.Bool:
+            return fmt.Errorf("must be a boolean")
+        case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+            return fmt.Errorf("must be an integer")
+        case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+            return fmt.Errorf("must be a positive integer")
+        case reflect.Float32, reflect.Float64:
+            return fmt.Errorf("must be a number")
+        }
+        return fmt.Errorf("cannot bind %s", v.Type())
+    }
+    return nil
+}
+
+//
+
+func (this *bindField) buildRules(tag string) (err error) {
+    this.rules, this.required, err = fieldutil.ParseRules(this.name, tag)
+    return
+}
+
+func (this *bindField) validate(f reflect.Value) (errs []*FieldError) {
+    fail := func (rule, param, msg string) () {
+        errs = append(errs, &FieldError{
+            Field: this.name,
+            Source: this.source,
+            Rule: rule,
+            Param: param,
+            Message: msg,
+        })
+    }
+    v := f
+    for v.Kind() == reflect.Ptr {
+        if v.IsNil() {
+            if this.required {
+                fail("required", "", "is required")
+            }
+            return
+        }
+        v = v.Elem()
+    }
+    if this.required && v.IsZero() || this.required && (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0 {
+        fail("required", "", "is required")
+        return
+    }
+    for _, rule := range this.rules {
+        if err := rule.Check(v, rule.Param); err != nil {
+            fail(rule.Name, rule.Param, err.Error())
+        }
+    }
+    return
+}
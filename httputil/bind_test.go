@@ -0,0 +1,131 @@
+package httputil_test
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "mime/multipart"
+    "strings"
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "time"
+    "encoding/json"
+    "github.com/princeofdatamining/golib/httputil"
+    "github.com/princeofdatamining/golib/fieldutil"
+)
+
+type testBindPage struct {
+    Page    int     `query:"page" validate:"min=1"`
+}
+type testBindPost struct {
+    testBindPage
+    UserID  int                     `param:"id"`
+    Title   string                  `json:"title" validate:"required,max=10"`
+    Tags    []string                `json:"tags" form:"tag" validate:"max=2"`
+    State   string                  `json:"state" validate:"enum=draft|published"`
+    Cover   *multipart.FileHeader   `form:"cover"`
+}
+
+func TestBind(t *testing.T) () {
+    var (
+        post    testBindPost
+        err     error
+    )
+    router := httputil.NewRouter()
+    router.Handle("/users/<id:int>/posts", httputil.METHOD_POST, http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        post = testBindPost{}
+        if err = httputil.DefaultBinder.Bind(r, &post); err != nil {
+            err.(*httputil.BindError).ServeHTTP(w, r)
+        }
+    }))
+    send := func (query, ct string, body io.Reader) (*httptest.ResponseRecorder) {
+        req := httptest.NewRequest(httputil.METHOD_POST, "http://example.com/users/7/posts" + query, body)
+        if ct != "" {
+            req.Header.Set(httputil.HEADER_CONTENT_TYPE, ct)
+        }
+        req.Header.Set(httputil.HEADER_ACCEPT, "application/json")
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, req)
+        return w
+    }
+
+    w := send("?page=2", "application/json", strings.NewReader(`{"title":"hello","tags":["a"],"state":"draft"}`))
+    if w.Code != http.StatusOK || post.UserID != 7 || post.Page != 2 || post.Title != "hello" || len(post.Tags) != 1 {
+        t.Fatalf("JSON must bind, but got %d %+v %v\n", w.Code, post, err)
+    }
+    w = send("?page=1", "application/x-www-form-urlencoded", strings.NewReader("title=form&tag=a&tag=b&state=draft"))
+    if w.Code != http.StatusOK || post.Title != "form" || !reflect.DeepEqual(post.Tags, []string{ "a", "b" }) {
+        t.Fatalf("form must bind, but got %d %+v %v\n", w.Code, post, err)
+    }
+
+    var buf bytes.Buffer
+    mw := multipart.NewWriter(&buf)
+    mw.WriteField("title", "upload")
+    mw.WriteField("state", "published")
+    part, _ := mw.CreateFormFile("cover", "cover.png")
+    part.Write([]byte("png"))
+    mw.Close()
+    w = send("?page=1", mw.FormDataContentType(), &buf)
+    if w.Code != http.StatusOK || post.Title != "upload" || post.Cover == nil || post.Cover.Filename != "cover.png" || post.Cover.Size != 3 {
+        t.Fatalf("multipart must bind, but got %d %+v %v\n", w.Code, post, err)
+    }
+
+    w = send("?page=0", "application/json", strings.NewReader(`{"title":"much too long","tags":["a","b","c"],"state":"x"}`))
+    var problem struct {
+        Status  int
+        Errors  []httputil.FieldError
+    }
+    json.Unmarshal(w.Body.Bytes(), &problem)
+    if w.Code != http.StatusUnprocessableEntity || w.Header().Get(httputil.HEADER_CONTENT_TYPE) != httputil.MIME_PROBLEM_JSON || problem.Status != w.Code || len(problem.Errors) != 4 {
+        t.Fatalf("invalid fields must reply 422 problem+json, but got %d %s\n", w.Code, w.Body.String())
+    }
+    if e := problem.Errors[0]; e.Field != "page" || e.Source != "query" || e.Rule != "min" {
+        t.Fatalf("unexpected field error %+v\n", e)
+    }
+    for _, io := range []struct {
+        query   string
+        ct      string
+        body    string
+        code    int
+    }{
+        { "?page=x", "application/json", `{"title":"a"}`, http.StatusUnprocessableEntity },
+        { "", "application/json", `{"title":1}`, http.StatusUnprocessableEntity },
+        { "", "application/json", `{"title":`, http.StatusBadRequest },
+        { "", "text/csv", `a,b`, http.StatusUnsupportedMediaType },
+        { "", "application/json", `{"title":"` + strings.Repeat("x", 11 << 20) + `"}`, http.StatusRequestEntityTooLarge },
+        { "", "", ``, http.StatusUnprocessableEntity },
+    } {
+        if w = send(io.query, io.ct, strings.NewReader(io.body)); w.Code != io.code {
+            t.Fatalf("%q %q must reply %d, but got %d %s\n", io.query, io.ct, io.code, w.Code, w.Body.String())
+        }
+    }
+}
+
+func TestBindValidators(t *testing.T) () {
+    fieldutil.RegisterValidator("lower", func (v reflect.Value, param string) (error) {
+        if v.Kind() == reflect.String && strings.ToLower(v.String()) != v.String() {
+            return fmt.Errorf("must be lowercase")
+        }
+        return nil
+    })
+    var dst struct {
+        Name    string          `query:"name" validate:"lower"`
+        Count   *uint           `query:"count"`
+        On      bool            `query:"on"`
+        Wait    time.Duration   `query:"wait"`
+    }
+    req := httptest.NewRequest(httputil.METHOD_GET, "http://example.com/?name=Bob&count=3&on=on&wait=2s", nil)
+    err := httputil.Bind(req, &dst)
+    if e, ok := err.(*httputil.BindError); !ok || len(e.Errors) != 1 || e.Errors[0].Rule != "lower" {
+        t.Fatalf("registered validators must apply, but got %v\n", err)
+    }
+    if dst.Count == nil || *dst.Count != 3 || !dst.On || dst.Wait != 2*time.Second {
+        t.Fatalf("query values must convert, but got %+v\n", dst)
+    }
+    req = httptest.NewRequest(httputil.METHOD_GET, "http://example.com/?count=-1", nil)
+    if err = httputil.Bind(req, &dst); err == nil || !strings.Contains(err.Error(), "count: must be a positive integer") {
+        t.Fatalf("bad numbers must fail, but got %v\n", err)
+    }
+}
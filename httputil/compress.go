@@ -0,0 +1,334 @@
+package httputil
+
+import (
+    "bufio"
+    "compress/gzip"
+    "compress/zlib"
+    "errors"
+    "io"
+    "net"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+/*
+    chain := NewChainHandler(NewAccessLog(nil), NewCompress(nil), ...)
+
+    Responses are buffered up to MinSize before choosing, smaller ones and
+    types compressed already (IsCompressedType) are sent as they are, as
+    are responses with a Content-Encoding, partial content and HEAD.
+
+    Brotli needs a third party encoder:
+
+    RegisterEncoding("br", func (w io.Writer, level int) (io.WriteCloser, error) {
+        return brotli.NewWriterLevel(w, level), nil
+    })
+
+    Inside the middleware Size() counts the bytes handlers wrote, outer
+    middlewares like the access log see the compressed size sent.
+//*/
+
+// EncoderFunc returns a writer compressing to w.
+type EncoderFunc func (w io.Writer, level int) (io.WriteCloser, error)
+
+var (
+    encodersLock    sync.RWMutex
+    encoders        = map[string]EncoderFunc{
+        "gzip": pooledEncoder(func (w io.Writer, level int) (resetWriter, error) { return gzip.NewWriterLevel(w, level) }),
+        "deflate": pooledEncoder(func (w io.Writer, level int) (resetWriter, error) { return zlib.NewWriterLevel(w, level) }),
+    }
+)
+
+// RegisterEncoding adds or replaces a content encoding.
+func RegisterEncoding(name string, f EncoderFunc) () {
+    encodersLock.Lock()
+    defer encodersLock.Unlock()
+    encoders[name] = f
+}
+func getEncoder(name string) (EncoderFunc, bool) {
+    encodersLock.RLock()
+    defer encodersLock.RUnlock()
+    f, ok := encoders[name]
+    return f, ok
+}
+
+// resetWriter is implemented by the gzip and zlib writers.
+type resetWriter interface {
+    io.WriteCloser
+    Reset(w io.Writer)
+}
+
+// pooledEncoder reuses writers, they are large.
+func pooledEncoder(create func (w io.Writer, level int) (resetWriter, error)) (EncoderFunc) {
+    var (
+        lock    sync.Mutex
+        pools   = make(map[int]*sync.Pool)
+    )
+    return func (w io.Writer, level int) (io.WriteCloser, error) {
+        lock.Lock()
+        pool, ok := pools[level]
+        if !ok {
+            pool = &sync.Pool{}
+            pools[level] = pool
+        }
+        lock.Unlock()
+        if enc, ok := pool.Get().(resetWriter); ok {
+            enc.Reset(w)
+            return &pooledWriter{enc, pool}, nil
+        }
+        enc, err := create(w, level)
+        if err != nil {
+            return nil, err
+        }
+        return &pooledWriter{enc, pool}, nil
+    }
+}
+
+type pooledWriter struct {
+    resetWriter
+    pool    *sync.Pool
+}
+func (this *pooledWriter) Close() (error) {
+    err := this.resetWriter.Close()
+    this.pool.Put(this.resetWriter)
+    return err
+}
+
+//
+
+type CompressOptions struct {
+    // -1 for the default level of each encoding
+    Level       int
+    // responses shorter are sent uncompressed, 1024 by default
+    MinSize     int
+    // in order of preference, by default br (when registered), gzip and deflate
+    Encodings   []string
+}
+
+func NewCompress(opts *CompressOptions) (Handler) {
+    this := &Compress{
+        CompressOptions: CompressOptions{
+            Level: -1,
+            MinSize: 1024,
+            Encodings: []string{ "br", "gzip", "deflate" },
+        },
+    }
+    if opts != nil {
+        this.Level = opts.Level
+        if opts.MinSize > 0 {
+            this.MinSize = opts.MinSize
+        }
+        if len(opts.Encodings) > 0 {
+            this.Encodings = opts.Encodings
+        }
+    }
+    return this
+}
+
+type Compress struct {
+    CompressOptions
+}
+
+// negotiate picks the accepted encoding with the highest q value, ties
+// go to the preferred one.
+func (this *Compress) negotiate(accept string) (name string, f EncoderFunc) {
+    best := 0.0
+    for _, encoding := range this.Encodings {
+        encoder, ok := getEncoder(encoding)
+        if !ok {
+            continue
+        }
+        if q := encodingQuality(accept, encoding); q > best {
+            best, name, f = q, encoding, encoder
+        }
+    }
+    return
+}
+
+func (this *Compress) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+    rw, ok := w.(ResponseWriter)
+    if !ok {
+        rw = NewResponseWriter(w)
+    }
+    rw.Header().Add(HEADER_VARY, HEADER_ACCEPT_ENCODING)
+    encoding, encoder := this.negotiate(r.Header.Get(HEADER_ACCEPT_ENCODING))
+    if encoder == nil || r.Method == METHOD_HEAD {
+        next(rw, r)
+        return
+    }
+    cw := &compressWriter{
+        ResponseWriter: rw,
+        options: &this.CompressOptions,
+        encoding: encoding,
+        encoder: encoder,
+    }
+    rw.Before(func (rw ResponseWriter) () {
+        if cw.enc != nil {
+            rw.Header().Set(HEADER_CONTENT_ENCODING, cw.encoding)
+            rw.Header().Del(HEADER_CONTENT_LENGTH)
+            // the compressed bytes differ from the ones the tag was made for
+            if etag := rw.Header().Get(HEADER_ETAG); etag != "" && !strings.HasPrefix(etag, "W/") {
+                rw.Header().Set(HEADER_ETAG, "W/" + etag)
+            }
+        }
+    })
+    defer cw.close()
+    next(cw, r)
+}
+
+//
+
+var errCompressHijacked = errors.New("httputil: connection hijacked")
+
+// compressWriter holds back the status and the first MinSize bytes
+// until it knows whether to compress.
+type compressWriter struct {
+    ResponseWriter
+    options     *CompressOptions
+    encoding    string
+    encoder     EncoderFunc
+    status      int
+    size        int
+    buf         []byte
+    decided     bool
+    hijacked    bool
+    // nil when sending uncompressed
+    enc         io.WriteCloser
+}
+
+func (this *compressWriter) Status() (int) { return this.status }
+func (this *compressWriter) Written() (bool) { return this.status != 0 }
+func (this *compressWriter) Size() (int) { return this.size }
+
+func (this *compressWriter) WriteHeader(status int) () {
+    if this.status != 0 || this.hijacked {
+        return
+    }
+    this.status = status
+    // no body to compress
+    if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified || status == http.StatusPartialContent {
+        this.decide(false)
+    }
+}
+
+func (this *compressWriter) Write(p []byte) (int, error) {
+    if this.hijacked {
+        return 0, errCompressHijacked
+    }
+    if this.status == 0 {
+        this.WriteHeader(http.StatusOK)
+    }
+    this.size += len(p)
+    if !this.decided {
+        if this.ResponseWriter.Header().Get(HEADER_CONTENT_TYPE) == "" {
+            this.ResponseWriter.Header().Set(HEADER_CONTENT_TYPE, http.DetectContentType(append(this.buf, p...)))
+        }
+        if len(this.buf) + len(p) < this.options.MinSize {
+            this.buf = append(this.buf, p...)
+            return len(p), nil
+        }
+        if err := this.start(true, p); err != nil {
+            return 0, err
+        }
+        return len(p), nil
+    }
+    if this.enc != nil {
+        return this.enc.Write(p)
+    }
+    return this.ResponseWriter.Write(p)
+}
+
+// decide sends the held back status and data.
+func (this *compressWriter) decide(compress bool) (error) {
+    return this.start(compress, nil)
+}
+
+func (this *compressWriter) start(compress bool, p []byte) (err error) {
+    if this.decided {
+        return nil
+    }
+    this.decided = true
+    header := this.ResponseWriter.Header()
+    if compress && this.compressible(header) {
+        if this.enc, err = this.encoder(this.ResponseWriter, this.options.Level); err != nil {
+            this.enc = nil
+        }
+    }
+    status := this.status
+    if status == 0 {
+        status = http.StatusOK
+    }
+    this.ResponseWriter.WriteHeader(status)
+    data := append(this.buf, p...)
+    this.buf = nil
+    if len(data) <= 0 {
+        return nil
+    }
+    if this.enc != nil {
+        _, err = this.enc.Write(data)
+    } else {
+        _, err = this.ResponseWriter.Write(data)
+    }
+    return
+}
+
+func (this *compressWriter) compressible(header http.Header) (bool) {
+    if header.Get(HEADER_CONTENT_ENCODING) != "" {
+        return false
+    }
+    if n, err := strconv.Atoi(header.Get(HEADER_CONTENT_LENGTH)); err == nil && n < this.options.MinSize {
+        return false
+    }
+    ct := header.Get(HEADER_CONTENT_TYPE)
+    if ct == "" {
+        ct = this.ResponseWriter.ContentType()
+    }
+    return !IsCompressedType(ct)
+}
+
+// Flush sends what is held back, compressed when at least MinSize.
+func (this *compressWriter) Flush() () {
+    if this.hijacked {
+        return
+    }
+    if !this.decided {
+        if this.status == 0 {
+            this.status = http.StatusOK
+        }
+        this.start(len(this.buf) >= this.options.MinSize, nil)
+    }
+    if flusher, ok := this.enc.(interface{ Flush() (error) }); ok {
+        flusher.Flush()
+    }
+    this.ResponseWriter.Flush()
+}
+
+func (this *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    hijacker, ok := this.ResponseWriter.(http.Hijacker)
+    if !ok {
+        return nil, nil, ErrNotHijacker
+    }
+    conn, rw, err := hijacker.Hijack()
+    if err == nil {
+        this.hijacked = true
+    }
+    return conn, rw, err
+}
+
+func (this *compressWriter) close() () {
+    if this.hijacked {
+        return
+    }
+    if !this.decided {
+        if this.status == 0 && len(this.buf) <= 0 {
+            // nothing written, let net/http reply
+            return
+        }
+        this.start(len(this.buf) >= this.options.MinSize, nil)
+    }
+    if this.enc != nil {
+        this.enc.Close()
+    }
+}
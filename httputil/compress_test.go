@@ -0,0 +1,69 @@
+package httputil_test
+
+import (
+    "compress/gzip"
+    "io"
+    "strings"
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+func TestCompress(t *testing.T) () {
+    big := strings.Repeat(`{"hello": "world"}`, 100)
+    router := httputil.NewRouter()
+    router.Handle("/big", httputil.METHOD_GET, http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        w.Header().Set(httputil.HEADER_CONTENT_TYPE, "application/json")
+        w.Header().Set(httputil.HEADER_ETAG, `"v1"`)
+        io.WriteString(w, big[:10])
+        io.WriteString(w, big[10:])
+    }))
+    router.Handle("/small", httputil.METHOD_GET, http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        io.WriteString(w, "small")
+    }))
+    router.Handle("/image", httputil.METHOD_GET, http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        w.Header().Set(httputil.HEADER_CONTENT_TYPE, "image/png")
+        io.WriteString(w, big)
+    }))
+    var size int
+    chain := httputil.NewChainHandler(httputil.NewCompress(nil), httputil.HandlerFunc(func (w http.ResponseWriter, r *http.Request, next http.HandlerFunc) () {
+        router.ServeHTTP(w, r)
+        size = w.(httputil.ResponseWriter).Size()
+    }))
+
+    serve := func (path, accept string) (*httptest.ResponseRecorder) {
+        req := httptest.NewRequest(httputil.METHOD_GET, "http://example.com" + path, nil)
+        req.Header.Set(httputil.HEADER_ACCEPT_ENCODING, accept)
+        w := httptest.NewRecorder()
+        chain.ServeHTTP(w, req)
+        return w
+    }
+
+    w := serve("/big", "deflate;q=0.5, gzip")
+    if w.Header().Get(httputil.HEADER_CONTENT_ENCODING) != "gzip" || w.Header().Get(httputil.HEADER_VARY) != httputil.HEADER_ACCEPT_ENCODING {
+        t.Fatalf("/big must be gzipped, but got %v\n", w.Header())
+    }
+    zr, err := gzip.NewReader(w.Body)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if body, _ := io.ReadAll(zr); string(body) != big || size != len(big) {
+        t.Fatalf("unexpected body %d bytes, size %d\n", len(body), size)
+    }
+    if etag := w.Header().Get(httputil.HEADER_ETAG); etag != `W/"v1"` {
+        t.Fatalf("gzipped ETag must be weak, but got %q\n", etag)
+    }
+    if w := serve("/big", "identity"); w.Header().Get(httputil.HEADER_CONTENT_ENCODING) != "" || w.Body.String() != big || w.Header().Get(httputil.HEADER_ETAG) != `"v1"` {
+        t.Fatalf("/big must be sent as is without gzip\n")
+    }
+    if w := serve("/small", "gzip"); w.Header().Get(httputil.HEADER_CONTENT_ENCODING) != "" || w.Body.String() != "small" {
+        t.Fatalf("/small must be sent as is, but got %q\n", w.Body.String())
+    }
+    if w := serve("/image", "gzip"); w.Header().Get(httputil.HEADER_CONTENT_ENCODING) != "" || w.Body.String() != big {
+        t.Fatalf("/image must be sent as is\n")
+    }
+    if !httputil.IsCompressedType("application/zip") || httputil.IsCompressedType("image/svg+xml") {
+        t.Fatalf("unexpected compressed types\n")
+    }
+}
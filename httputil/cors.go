@@ -0,0 +1,207 @@
+package httputil
+
+import (
+    "net/http"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+)
+
+/*
+    cors := NewCORS(&CORSOptions{
+        AllowedOrigins: []string{ "https://app.example.com", "https://*.example.org", `^https://pr-\d+\.preview\.dev$` },
+        AllowedHeaders: []string{ "Authorization", "Content-Type" },
+        AllowCredentials: true,
+        MaxAge: time.Hour,
+    })
+    chain := NewChainHandler(cors, ...)     // actual requests
+    router.Preflight(cors.Preflight)        // preflight requests
+
+    Preflights are answered by the router once it matched the rule, the
+    allowed methods are the ones registered on it, limited to
+    AllowedMethods when set. Refused requests get no CORS headers, the
+    browser then blocks them.
+//*/
+
+type CORSOptions struct {
+    // exact origins, "*" for any, wildcards like https://*.example.com
+    // for one or more labels, or regexps starting with ^. "*" can't go
+    // with AllowCredentials, decide in AllowOriginFunc instead
+    AllowedOrigins      []string
+    // overrides AllowedOrigins when set
+    AllowOriginFunc     func (origin string) (bool)
+    // all registered methods when empty
+    AllowedMethods      []string
+    // request headers besides the CORS-safelisted ones, "*" for any
+    AllowedHeaders      []string
+    ExposedHeaders      []string
+    AllowCredentials    bool
+    // how long browsers cache preflights, not sent when 0
+    MaxAge              time.Duration
+}
+
+func NewCORS(opts *CORSOptions) (*CORS) {
+    this := &CORS{
+        methods: make(map[string]bool),
+        headers: make(map[string]bool),
+    }
+    if opts != nil {
+        this.CORSOptions = *opts
+    }
+    for _, origin := range this.AllowedOrigins {
+        switch {
+        case origin == "*":
+            this.anyOrigin = true
+        case strings.HasPrefix(origin, "^"):
+            this.origins = append(this.origins, regexp.MustCompile(origin))
+        case strings.Contains(origin, "*"):
+            pattern := strings.Replace(regexp.QuoteMeta(strings.ToLower(origin)), `\*`, `[a-z0-9-]+(?:\.[a-z0-9-]+)*`, -1)
+            this.origins = append(this.origins, regexp.MustCompile("^" + pattern + "$"))
+        default:
+            this.exact = append(this.exact, strings.ToLower(origin))
+        }
+    }
+    // credentials would be readable by any site
+    if this.anyOrigin && this.AllowCredentials && this.AllowOriginFunc == nil {
+        panic("httputil: CORS can't allow credentials from any origin, use AllowOriginFunc")
+    }
+    for _, method := range this.AllowedMethods {
+        this.methods[strings.ToUpper(method)] = true
+    }
+    for _, header := range this.AllowedHeaders {
+        if header == "*" {
+            this.anyHeader = true
+        }
+        this.headers[http.CanonicalHeaderKey(header)] = true
+    }
+    return this
+}
+
+type CORS struct {
+    CORSOptions
+    anyOrigin   bool
+    exact       []string
+    origins     []*regexp.Regexp
+    methods     map[string]bool
+    anyHeader   bool
+    headers     map[string]bool
+}
+
+// CORS-safelisted request headers
+var corsSafeHeaders = map[string]bool{
+    "Accept": true,
+    "Accept-Language": true,
+    "Content-Language": true,
+    "Content-Type": true,
+}
+
+func (this *CORS) allowOrigin(origin string) (bool) {
+    if this.AllowOriginFunc != nil {
+        return this.AllowOriginFunc(origin)
+    }
+    if this.anyOrigin {
+        return true
+    }
+    origin = strings.ToLower(origin)
+    for _, exact := range this.exact {
+        if exact == origin {
+            return true
+        }
+    }
+    for _, re := range this.origins {
+        if re.MatchString(origin) {
+            return true
+        }
+    }
+    return false
+}
+
+// setOrigin answers with the origin, "*" only without credentials.
+func (this *CORS) setOrigin(header http.Header, origin string) () {
+    if this.anyOrigin && this.AllowOriginFunc == nil && !this.AllowCredentials {
+        header.Set(HEADER_ACCESS_CONTROL_ALLOW_ORIGIN, "*")
+    } else {
+        header.Set(HEADER_ACCESS_CONTROL_ALLOW_ORIGIN, origin)
+    }
+    if this.AllowCredentials {
+        header.Set(HEADER_ACCESS_CONTROL_ALLOW_CREDENTIALS, "true")
+    }
+}
+
+// ServeHTTP adds CORS headers to actual requests, preflights go on to
+// the router.
+func (this *CORS) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+    origin := r.Header.Get(HEADER_ORIGIN)
+    if origin == "" || isPreflight(r) {
+        next(w, r)
+        return
+    }
+    header := w.Header()
+    header.Add(HEADER_VARY, HEADER_ORIGIN)
+    if !this.allowOrigin(origin) {
+        next(w, r)
+        return
+    }
+    this.setOrigin(header, origin)
+    if len(this.ExposedHeaders) > 0 {
+        header.Set(HEADER_ACCESS_CONTROL_EXPOSE_HEADERS, strings.Join(this.ExposedHeaders, ", "))
+    }
+    next(w, r)
+}
+
+func isPreflight(r *http.Request) (bool) {
+    return r.Method == METHOD_OPTIONS && r.Header.Get(HEADER_ACCESS_CONTROL_REQUEST_METHOD) != ""
+}
+
+// Preflight is the PreflightFunc answering CORS preflights with the
+// methods of the matched rule.
+func (this *CORS) Preflight(w http.ResponseWriter, r *http.Request, allow []string) () {
+    origin := r.Header.Get(HEADER_ORIGIN)
+    if origin == "" || !isPreflight(r) {
+        return
+    }
+    header := w.Header()
+    header.Add(HEADER_VARY, HEADER_ORIGIN)
+    header.Add(HEADER_VARY, HEADER_ACCESS_CONTROL_REQUEST_METHOD)
+    header.Add(HEADER_VARY, HEADER_ACCESS_CONTROL_REQUEST_HEADERS)
+    if !this.allowOrigin(origin) {
+        return
+    }
+
+    var methods []string
+    requested := strings.ToUpper(r.Header.Get(HEADER_ACCESS_CONTROL_REQUEST_METHOD))
+    found := false
+    for _, method := range allow {
+        if method == METHOD_OPTIONS || len(this.methods) > 0 && !this.methods[method] {
+            continue
+        }
+        methods = append(methods, method)
+        found = found || method == requested
+    }
+    if !found {
+        return
+    }
+
+    var headers []string
+    for _, field := range strings.Split(r.Header.Get(HEADER_ACCESS_CONTROL_REQUEST_HEADERS), ",") {
+        field = http.CanonicalHeaderKey(strings.TrimSpace(field))
+        if field == "" {
+            continue
+        }
+        if !this.anyHeader && !this.headers[field] && !corsSafeHeaders[field] {
+            return
+        }
+        headers = append(headers, field)
+    }
+
+    this.setOrigin(header, origin)
+    header.Set(HEADER_ACCESS_CONTROL_ALLOW_METHODS, strings.Join(methods, ", "))
+    if len(headers) > 0 {
+        header.Set(HEADER_ACCESS_CONTROL_ALLOW_HEADERS, strings.Join(headers, ", "))
+    }
+    if this.MaxAge > 0 {
+        header.Set(HEADER_ACCESS_CONTROL_MAX_AGE, strconv.Itoa(int(this.MaxAge.Seconds())))
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
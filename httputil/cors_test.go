@@ -0,0 +1,86 @@
+package httputil_test
+
+import (
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "time"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+func TestCORS(t *testing.T) () {
+    cors := httputil.NewCORS(&httputil.CORSOptions{
+        AllowedOrigins: []string{ "https://app.example.com", "https://*.example.org", `^https://pr-\d+\.dev$` },
+        AllowedHeaders: []string{ "Authorization" },
+        ExposedHeaders: []string{ "X-Total" },
+        AllowCredentials: true,
+        MaxAge: time.Hour,
+    })
+    router := httputil.NewRouter()
+    router.Handle("/items/<id:int>", httputil.METHOD_GET, http.NotFoundHandler())
+    router.Handle("/items/<id:int>", httputil.METHOD_DELETE, http.NotFoundHandler())
+    router.Preflight(cors.Preflight)
+    chain := httputil.NewChainHandler(cors, httputil.HandlerFunc(func (w http.ResponseWriter, r *http.Request, next http.HandlerFunc) () {
+        router.ServeHTTP(w, r)
+    }))
+    serve := func (method, origin string, header map[string]string) (*httptest.ResponseRecorder) {
+        req := httptest.NewRequest(method, "http://api.example.com/items/1", nil)
+        req.Header.Set(httputil.HEADER_ORIGIN, origin)
+        for key, value := range header {
+            req.Header.Set(key, value)
+        }
+        w := httptest.NewRecorder()
+        chain.ServeHTTP(w, req)
+        return w
+    }
+
+    w := serve(httputil.METHOD_OPTIONS, "https://a.b.example.org", map[string]string{
+        httputil.HEADER_ACCESS_CONTROL_REQUEST_METHOD: "DELETE",
+        httputil.HEADER_ACCESS_CONTROL_REQUEST_HEADERS: "authorization, content-type",
+    })
+    if w.Code != http.StatusNoContent || w.Header().Get(httputil.HEADER_ACCESS_CONTROL_ALLOW_ORIGIN) != "https://a.b.example.org" ||
+        w.Header().Get(httputil.HEADER_ACCESS_CONTROL_ALLOW_METHODS) != "DELETE, GET, HEAD" ||
+        w.Header().Get(httputil.HEADER_ACCESS_CONTROL_ALLOW_HEADERS) != "Authorization, Content-Type" ||
+        w.Header().Get(httputil.HEADER_ACCESS_CONTROL_MAX_AGE) != "3600" {
+        t.Fatalf("preflight must be allowed, but got %d %v\n", w.Code, w.Header())
+    }
+    for _, io := range []struct {
+        origin  string
+        method  string
+        headers string
+    }{
+        { "https://evil.com", "GET", "" },
+        { "https://app.example.com", "PUT", "" },
+        { "https://pr-12.dev", "GET", "X-Secret" },
+    } {
+        w := serve(httputil.METHOD_OPTIONS, io.origin, map[string]string{
+            httputil.HEADER_ACCESS_CONTROL_REQUEST_METHOD: io.method,
+            httputil.HEADER_ACCESS_CONTROL_REQUEST_HEADERS: io.headers,
+        })
+        if w.Header().Get(httputil.HEADER_ACCESS_CONTROL_ALLOW_ORIGIN) != "" {
+            t.Fatalf("preflight %+v must be refused, but got %v\n", io, w.Header())
+        }
+    }
+
+    w = serve(httputil.METHOD_GET, "https://pr-7.dev", nil)
+    if w.Header().Get(httputil.HEADER_ACCESS_CONTROL_ALLOW_ORIGIN) != "https://pr-7.dev" || w.Header().Get(httputil.HEADER_ACCESS_CONTROL_ALLOW_CREDENTIALS) != "true" || w.Header().Get(httputil.HEADER_ACCESS_CONTROL_EXPOSE_HEADERS) != "X-Total" {
+        t.Fatalf("actual request must get CORS headers, but got %v\n", w.Header())
+    }
+}
+
+func TestCORSOptions(t *testing.T) () {
+    panics := func (opts *httputil.CORSOptions) (panicked bool) {
+        defer func () () { panicked = recover() != nil }()
+        httputil.NewCORS(opts)
+        return
+    }
+    if panics(nil) || panics(&httputil.CORSOptions{ AllowedOrigins: []string{ "*" } }) {
+        t.Fatalf("valid options must not panic\n")
+    }
+    if !panics(&httputil.CORSOptions{ AllowedOrigins: []string{ "*" }, AllowCredentials: true }) {
+        t.Fatalf("credentials from any origin must panic\n")
+    }
+    if panics(&httputil.CORSOptions{ AllowedOrigins: []string{ "*" }, AllowCredentials: true, AllowOriginFunc: func (string) (bool) { return true } }) {
+        t.Fatalf("credentials with AllowOriginFunc must not panic\n")
+    }
+}
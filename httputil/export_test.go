@@ -0,0 +1,9 @@
+package httputil
+
+// NewRegexpRouter matches every dynamic rule by regexp, the way routers
+// did before the trie.
+func NewRegexpRouter() (*Router) {
+    this := NewRouter()
+    this.regexpOnly = true
+    return this
+}
@@ -0,0 +1,50 @@
+package httputil
+
+import (
+    "net/http"
+)
+
+/*
+    api := router.Group("/api/v1", authHandler)
+    api.HandleFunc("/users/<id:int>", METHOD_GET, showUser)     // "/api/v1/users/<id:int>"
+
+    admin := api.Group("/admin", adminOnly)                     // authHandler, then adminOnly
+    admin.Handle("/stats", METHOD_GET, statsHandler)            // "/api/v1/admin/stats"
+//*/
+
+// Group returns a sub-router registering its rules on this router with
+// prefix prepended. Its handlers run behind the middlewares of every
+// enclosing group, outermost first, inside the router WrapFunc.
+func (this *Router) Group(prefix string, middlewares ...Handler) (*Router) {
+    root := this
+    if this.root != nil {
+        root = this.root
+    }
+    chain := make([]Handler, 0, len(this.middlewares) + len(middlewares))
+    chain = append(append(chain, this.middlewares...), middlewares...)
+    return &Router{
+        parent: this.parent,
+        host: this.host,
+        wrapFunc: this.wrapFunc,
+        root: root,
+        prefix: this.prefix + prefix,
+        middlewares: chain,
+    }
+}
+
+// Group mounts a group on the router of host_pattern.
+func (this *MultiHostRouter) Group(host_pattern, prefix string, middlewares ...Handler) (*Router) {
+    return this.AddRouter(host_pattern).Group(prefix, middlewares...)
+}
+
+// Chain returns h run behind middlewares, the first one runs first.
+func Chain(h http.Handler, middlewares ...Handler) (http.Handler) {
+    if len(middlewares) <= 0 {
+        return h
+    }
+    chain := NewChainHandler(middlewares...)
+    chain.Chain(HandlerFunc(func (w http.ResponseWriter, r *http.Request, next http.HandlerFunc) () {
+        h.ServeHTTP(w, r)
+    }))
+    return chain
+}
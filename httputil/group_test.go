@@ -0,0 +1,48 @@
+package httputil_test
+
+import (
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+func TestGroup(t *testing.T) () {
+    var trace []string
+    middleware := func (name string) (httputil.Handler) {
+        return httputil.HandlerFunc(func (w http.ResponseWriter, r *http.Request, next http.HandlerFunc) () {
+            trace = append(trace, name)
+            next(w, r)
+        })
+    }
+    router := httputil.NewMultiHostRouter()
+    api := router.Group(httputil.MATCH_HOST_ANY, "/api/v1", middleware("api"))
+    admin := api.Group("/admin", middleware("admin"))
+    admin.HandleFunc("/users/<id:int>", httputil.METHOD_GET, func (w http.ResponseWriter, r *http.Request, args []string, kwargs map[string]string) () {
+        trace = append(trace, "user " + kwargs["id"])
+    })
+    api.Handle("/ping", httputil.METHOD_GET, http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        trace = append(trace, "ping")
+    }))
+    admin.Name("user", "/users/<id:int>")
+
+    for _, io := range []struct {
+        path    string
+        trace   []string
+    }{
+        { "/api/v1/admin/users/7", []string{"api", "admin", "user 7"} },
+        { "/api/v1/ping", []string{"api", "ping"} },
+        { "/users/7", nil },
+    } {
+        trace = nil
+        req := httptest.NewRequest(httputil.METHOD_GET, "http://example.com" + io.path, nil)
+        router.ServeHTTP(httptest.NewRecorder(), req)
+        if !reflect.DeepEqual(trace, io.trace) {
+            t.Fatalf("%q must run %q, but got %q\n", io.path, io.trace, trace)
+        }
+    }
+    if url, err := api.URL("user", nil, map[string]string{"id": "7"}); err != nil || url != "/api/v1/admin/users/7" {
+        t.Fatalf("URL(user) must be %q, but got %q %v\n", "/api/v1/admin/users/7", url, err)
+    }
+}
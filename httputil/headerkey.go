@@ -27,6 +27,7 @@ HEADER_TRANSFER_ENCODING = "Transfer-Encoding"
 HEADER_CONNECTION = "Connection"
 HEADER_ACCEPT = "Accept"
 HEADER_ACCEPT_ENCODING = "Accept-Encoding"
+HEADER_ACCEPT_CHARSET = "Accept-Charset"
 HEADER_ACCEPT_LANGUAGE = "Accept-Language"
 HEADER_Authorization = "Authorization"
 HEADER_COOKIE = "Cookie"
@@ -40,6 +41,10 @@ HEADER_IF_MODIFIED_SINCE = "If-Modified-Since"
 XHEADER_FORWARDED_FOR = "X-Forwarded-For"
 XHEADER_REAL_IP = "X-Real-Ip"
 XHEADER_FORWARDED_PROTO = "X-Forwarded-Proto"
+XHEADER_REQUEST_ID = "X-Request-Id"
+XHEADER_TRACEPARENT = "Traceparent"
+XHEADER_TRACESTATE = "Tracestate"
+XHEADER_CONTENT_TYPE_OPTIONS = "X-Content-Type-Options"
 
 HEADER_SET_COOKIE = "Set-Cookie"
 HEADER_LOCATION = "Location"
@@ -55,6 +60,19 @@ HEADER_CACHE_CONTROL = "Cache-Control"
 HEADER_ACCEPT_RANGES = "Accept-Ranges"
 HEADER_VARY = "Vary"
 HEADER_ALLOW = "Allow"
+HEADER_RETRY_AFTER = "Retry-After"
+HEADER_RATELIMIT_LIMIT = "RateLimit-Limit"
+HEADER_RATELIMIT_REMAINING = "RateLimit-Remaining"
+HEADER_RATELIMIT_RESET = "RateLimit-Reset"
+HEADER_RATELIMIT_POLICY = "RateLimit-Policy"
+HEADER_ACCESS_CONTROL_ALLOW_ORIGIN = "Access-Control-Allow-Origin"
+HEADER_ACCESS_CONTROL_ALLOW_METHODS = "Access-Control-Allow-Methods"
+HEADER_ACCESS_CONTROL_ALLOW_HEADERS = "Access-Control-Allow-Headers"
+HEADER_ACCESS_CONTROL_ALLOW_CREDENTIALS = "Access-Control-Allow-Credentials"
+HEADER_ACCESS_CONTROL_EXPOSE_HEADERS = "Access-Control-Expose-Headers"
+HEADER_ACCESS_CONTROL_MAX_AGE = "Access-Control-Max-Age"
+HEADER_ACCESS_CONTROL_REQUEST_METHOD = "Access-Control-Request-Method"
+HEADER_ACCESS_CONTROL_REQUEST_HEADERS = "Access-Control-Request-Headers"
 
 
 UA_CHROME = "Mozilla/5.0 (Windows NT 6.1; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/27.0.1453.116 Safari/537.36"
@@ -0,0 +1,160 @@
+package httputil
+
+import (
+    "net"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+/*
+    Host patterns are structured unless they hold regexp syntax:
+
+    example.com                 exact host, any port and scheme
+    https://example.com:8443    with scheme and port
+    *.example.com               one label, not captured
+    {tenant}.example.com        one label, captured as host param "tenant"
+    [::1]:8080                  IPv6 literal
+    ^api\d+\.example\.com$      regexp on the host without port
+
+    Hosts are compared lowercase, without trailing dot. The scheme is the
+    first X-Forwarded-Proto value when the peer is one of the router's
+    TrustedProxies, see TrustedProxies.Scheme.
+
+    Routers of every matching pattern are tried in this order, the first
+    one with a rule matching the path serves the request:
+
+    1. exact hosts
+    2. wildcards and captures, the more literal labels the sooner
+    3. regexps
+    4. MATCH_HOST_ANY, only when no other pattern matched
+
+    Within each step patterns with a scheme or port come first, then
+    patterns are kept in registration order.
+//*/
+
+var hostLabel = regexp.MustCompile(`^(?:[a-zA-Z0-9_-]+|\*|\{[a-zA-Z_][a-zA-Z_0-9]*\})$`)
+
+const (
+    hostExact = iota
+    hostWildcard
+    hostRegexp
+)
+
+type hostPattern struct {
+    pattern     string
+    scheme      string
+    port        string
+    // nil for regexps
+    labels      []string
+    rexp        *regexp.Regexp
+    kind        int
+    literals    int
+}
+
+func compileHostPattern(pattern string) (*hostPattern) {
+    this := &hostPattern{
+        pattern: pattern,
+    }
+    rest := pattern
+    if i := strings.Index(rest, "://"); i > 0 {
+        this.scheme, rest = strings.ToLower(rest[:i]), rest[i+3:]
+    }
+    host, port := splitHostPort(rest)
+    this.port = port
+    if net.ParseIP(host) != nil && strings.Contains(host, ":") {
+        this.labels, this.literals = []string{ strings.ToLower(host) }, 1
+        return this
+    }
+    this.labels = strings.Split(host, ".")
+    for i, label := range this.labels {
+        if !hostLabel.MatchString(label) {
+            // the whole pattern is a regexp
+            return &hostPattern{
+                pattern: pattern,
+                rexp: regexp.MustCompile(pattern),
+                kind: hostRegexp,
+            }
+        }
+        if label[0] != '*' && label[0] != '{' {
+            this.labels[i] = strings.ToLower(label)
+            this.literals++
+        }
+    }
+    if this.literals < len(this.labels) {
+        this.kind = hostWildcard
+    }
+    return this
+}
+
+// match tests a lowercase host split by splitHostPort, an empty scheme
+// matches any scheme.
+func (this *hostPattern) match(scheme, host, port string) (params Params, ok bool) {
+    if this.rexp != nil {
+        return nil, this.rexp.MatchString(host)
+    }
+    if this.scheme != "" && scheme != "" && this.scheme != scheme || this.port != "" && this.port != port {
+        return nil, false
+    }
+    labels := []string{ host }
+    if this.kind != hostExact || len(this.labels) > 1 {
+        labels = strings.Split(host, ".")
+    }
+    if len(labels) != len(this.labels) {
+        return nil, false
+    }
+    for i, label := range this.labels {
+        switch {
+        case label == "*":
+        case label[0] == '{':
+            params = append(params, Param{
+                Key: label[1:len(label)-1],
+                Value: labels[i],
+                Filter: "host",
+            })
+        case label != labels[i]:
+            return nil, false
+        }
+    }
+    return params, true
+}
+
+// less tells whether this pattern is tried before other.
+func (this *hostPattern) less(other *hostPattern) (bool) {
+    if this.kind != other.kind {
+        return this.kind < other.kind
+    }
+    if this.literals != other.literals {
+        return this.literals > other.literals
+    }
+    qualified := func (p *hostPattern) (bool) { return p.scheme != "" || p.port != "" }
+    return qualified(this) && !qualified(other)
+}
+
+// splitHostPort splits a Host header, IPv6 literals lose their brackets.
+func splitHostPort(hostport string) (host, port string) {
+    host = hostport
+    if i := strings.LastIndexByte(hostport, ':'); i >= 0 && !strings.Contains(hostport[i:], "]") {
+        // bare IPv6 literals hold several colons and no port
+        if strings.HasPrefix(hostport, "[") || strings.Count(hostport, ":") == 1 {
+            host, port = hostport[:i], hostport[i+1:]
+        }
+    }
+    if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+        host = host[1:len(host)-1]
+    }
+    return strings.TrimSuffix(host, "."), port
+}
+
+//
+
+type hostMatch struct {
+    router  *Router
+    params  Params
+}
+
+func sortHostMatches(matches []hostMatch) () {
+    sort.SliceStable(matches, func (i, j int) (bool) {
+        return matches[i].router.host.less(matches[j].router.host)
+    })
+}
@@ -0,0 +1,69 @@
+package httputil_test
+
+import (
+    "testing"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+func TestHosts(t *testing.T) () {
+    router := httputil.NewMultiHostRouter()
+    // httptest requests come from 192.0.2.1
+    router.TrustedProxies, _ = httputil.ParseTrustedProxies("192.0.2.0/24")
+    for _, pattern := range []string{
+        httputil.MATCH_HOST_ANY,
+        `^.*\.example\.com$`,
+        "{tenant}.example.com",
+        "*.example.com",
+        "https://*.example.com",
+        "www.example.com",
+        "*.*.example.com",
+        "[::1]:8080",
+    } {
+        pattern := pattern
+        router.HandleFunc(pattern, "/", httputil.METHOD_GET, func (w http.ResponseWriter, r *http.Request, args []string, kwargs map[string]string) () {
+            fmt.Fprintf(w, "%s %s", pattern, kwargs["tenant"])
+        })
+    }
+    router.HandleFunc(`^.*\.example\.com$`, "/regexp", httputil.METHOD_GET, func (w http.ResponseWriter, r *http.Request, args []string, kwargs map[string]string) () {
+        fmt.Fprint(w, "regexp")
+    })
+
+    for _, io := range []struct {
+        url     string
+        proto   string
+        body    string
+    }{
+        { "http://www.example.com/"          , ""     , "www.example.com " },
+        { "http://WWW.Example.com.:80/"      , ""     , "www.example.com " },
+        { "http://acme.example.com/"         , ""     , "{tenant}.example.com acme" },
+        { "http://acme.example.com/"         , "https", "https://*.example.com " },
+        { "http://a.b.example.com/"          , ""     , "*.*.example.com " },
+        { "http://acme.example.com/regexp"   , ""     , "regexp" },
+        { "http://[::1]:8080/"               , ""     , "[::1]:8080 " },
+        { "http://[::1]:8081/"               , ""     , ".*$ " },
+        { "http://other.org/"                , ""     , ".*$ " },
+    } {
+        req := httptest.NewRequest(httputil.METHOD_GET, io.url, nil)
+        if io.proto != "" {
+            req.Header.Set(httputil.XHEADER_FORWARDED_PROTO, io.proto)
+        }
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, req)
+        if w.Body.String() != io.body {
+            t.Fatalf("%s (%s) must be served by %q, but got %q\n", io.url, io.proto, io.body, w.Body.String())
+        }
+    }
+
+    // X-Forwarded-Proto of untrusted peers is ignored
+    req := httptest.NewRequest(httputil.METHOD_GET, "http://acme.example.com/", nil)
+    req.RemoteAddr = "198.51.100.7:1234"
+    req.Header.Set(httputil.XHEADER_FORWARDED_PROTO, "https")
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, req)
+    if w.Body.String() != "{tenant}.example.com acme" {
+        t.Fatalf("untrusted https must be served by %q, but got %q\n", "{tenant}.example.com acme", w.Body.String())
+    }
+}
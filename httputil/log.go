@@ -9,12 +9,14 @@ import (
 
 type GetLogger func () (*log.Logger)
 
+// Deprecated: NewAccessLog writes structured entries.
 func NewLogger(logger *log.Logger, getter GetLogger) (Handler) {
     return &Logger{
         logger: logger,
         getter: getter,
     }
 }
+// Deprecated: use AccessLog.
 type Logger struct {
     logger  *log.Logger
     getter  GetLogger
@@ -0,0 +1,124 @@
+package httputil
+
+import (
+    "net/http"
+    "sort"
+    "strings"
+)
+
+/*
+    Once a rule matched the path, the method picks its handler:
+
+    registered method or ANY    the handler
+    HEAD                        the GET handler, body discarded
+    OPTIONS                     Allow header, then the preflight hook
+    others                      405 Method Not Allowed with Allow header
+//*/
+
+// PreflightFunc answers OPTIONS requests of matched rules, e.g. for CORS.
+// The Allow header is set already, when nothing is written the router
+// replies 204 No Content.
+type PreflightFunc func (w http.ResponseWriter, r *http.Request, allow []string) ()
+
+// allowedMethods lists the methods registered on a rule, plus the ones
+// the router answers on its behalf.
+func allowedMethods(targets map[string]*Route) (allow []string) {
+    seen := map[string]bool{ METHOD_OPTIONS: true }
+    for method := range targets {
+        seen[method] = true
+    }
+    if seen[METHOD_GET] {
+        seen[METHOD_HEAD] = true
+    }
+    for method := range seen {
+        allow = append(allow, method)
+    }
+    sort.Strings(allow)
+    return
+}
+
+func (this *Router) dispatch(rule string, targets map[string]*Route, method string, params Params) (h http.Handler, allow []string) {
+    if route := getRouteByMethod(targets, method); route != nil {
+        return route.makeHandler(this, rule, params), nil
+    }
+    if route, ok := targets[METHOD_GET]; ok && method == METHOD_HEAD {
+        return &headHandler{route.makeHandler(this, rule, params)}, nil
+    }
+    allow = allowedMethods(targets)
+    if method == METHOD_OPTIONS {
+        return this.wrapped(&optionsHandler{allow, this.preflight}), allow
+    }
+    return nil, allow
+}
+
+// Preflight sets the hook answering OPTIONS requests.
+func (this *Router) Preflight(f PreflightFunc) () {
+    if this.root != nil {
+        this.root.Preflight(f)
+        return
+    }
+    this.Lock()
+    defer this.Unlock()
+    this.preflight = f
+}
+
+// Preflight sets the hook of every host router, present and future.
+func (this *MultiHostRouter) Preflight(f PreflightFunc) () {
+    this.Lock()
+    this.preflight = f
+    routers := this.load().routers()
+    this.Unlock()
+    for _, router := range routers {
+        router.Preflight(f)
+    }
+}
+
+// MethodNotAllowedHandler replies 405 listing the allowed methods.
+func MethodNotAllowedHandler(allow []string) (http.Handler) {
+    return http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        w.Header().Set(HEADER_ALLOW, strings.Join(allow, ", "))
+        WriteError(w, r, NewError(http.StatusMethodNotAllowed, "method not allowed"))
+    })
+}
+
+//
+
+type optionsHandler struct {
+    allow       []string
+    preflight   PreflightFunc
+}
+func (this *optionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) () {
+    w.Header().Set(HEADER_ALLOW, strings.Join(this.allow, ", "))
+    rw, ok := w.(ResponseWriter)
+    if !ok {
+        rw = NewResponseWriter(w)
+    }
+    if this.preflight != nil {
+        this.preflight(rw, r, this.allow)
+    }
+    if !rw.Written() {
+        rw.WriteHeader(http.StatusNoContent)
+    }
+}
+
+type headHandler struct {
+    handler http.Handler
+}
+func (this *headHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) () {
+    rw, ok := w.(ResponseWriter)
+    if !ok {
+        rw = NewResponseWriter(w)
+    }
+    this.handler.ServeHTTP(&headResponseWriter{rw}, r)
+}
+
+// headResponseWriter drops the body, handlers still see it written.
+type headResponseWriter struct {
+    ResponseWriter
+}
+func (this *headResponseWriter) Write(buf []byte) (int, error) {
+    if !this.Written() {
+        this.WriteHeader(http.StatusOK)
+    }
+    return len(buf), nil
+}
@@ -0,0 +1,51 @@
+package httputil_test
+
+import (
+    "testing"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+func TestMethods(t *testing.T) () {
+    router := httputil.NewRouter()
+    router.Handle("/items/<id:int>", httputil.METHOD_GET, http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        fmt.Fprint(w, "item")
+    }))
+    router.Handle("/items/<id:int>", httputil.METHOD_PUT, http.NotFoundHandler())
+    router.Handle("/any", httputil.METHOD_ANY, http.NotFoundHandler())
+
+    for _, io := range []struct {
+        method  string
+        path    string
+        code    int
+        allow   string
+        body    string
+    }{
+        { httputil.METHOD_GET    , "/items/1", http.StatusOK, "", "item" },
+        { httputil.METHOD_HEAD   , "/items/1", http.StatusOK, "", "" },
+        { httputil.METHOD_POST   , "/items/1", http.StatusMethodNotAllowed, "GET, HEAD, OPTIONS, PUT", "405 method not allowed\n" },
+        { httputil.METHOD_OPTIONS, "/items/1", http.StatusNoContent, "GET, HEAD, OPTIONS, PUT", "" },
+        { httputil.METHOD_POST   , "/any"    , http.StatusNotFound, "", "404 page not found\n" },
+        { httputil.METHOD_POST   , "/none"   , http.StatusNotFound, "", "404 page not found\n" },
+    } {
+        req := httptest.NewRequest(io.method, "http://example.com" + io.path, nil)
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, req)
+        if w.Code != io.code || w.Header().Get(httputil.HEADER_ALLOW) != io.allow || w.Body.String() != io.body {
+            t.Fatalf("%s %s must reply %d %q %q, but got %d %q %q\n", io.method, io.path, io.code, io.allow, io.body, w.Code, w.Header().Get(httputil.HEADER_ALLOW), w.Body.String())
+        }
+    }
+
+    var preflight []string
+    router.Preflight(func (w http.ResponseWriter, r *http.Request, allow []string) () {
+        preflight = allow
+        w.WriteHeader(http.StatusOK)
+    })
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, httptest.NewRequest(httputil.METHOD_OPTIONS, "http://example.com/items/1", nil))
+    if w.Code != http.StatusOK || len(preflight) != 4 {
+        t.Fatalf("preflight must reply 200 with 4 methods, but got %d %q\n", w.Code, preflight)
+    }
+}
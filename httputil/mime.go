@@ -595,3 +595,38 @@ func ContentTypeByFilename2(filename, charset string) string {
 func ContentTypeByFilename(filename string) string {
     return ContentTypeByFilename2(filename, "")
 }
+
+// Extensions of compressed formats, their types aren't worth compressing.
+var compressedExtensions = []string{
+    "7z", "bz2", "gz", "gzip", "tgz", "rar", "z", "zip", "pdf",
+    "jpg", "jpeg", "png", "gif", "mp3", "mp4", "mpeg", "ogg", "avi", "mov",
+}
+// Compressed types missing from the table.
+var compressedTypes = []string{
+    "application/gzip", "application/x-xz", "application/zstd", "application/x-brotli",
+    "font/woff", "font/woff2",
+}
+
+// IsCompressedType tells whether content of type ct is compressed already,
+// from the types of compressedExtensions and the image, audio and video
+// families, SVG excepted.
+func IsCompressedType(ct string) (bool) {
+    ct = strings.ToLower(strings.TrimSpace(strings.Split(ct, ";")[0]))
+    switch {
+    case ct == "image/svg+xml":
+        return false
+    case strings.HasPrefix(ct, "image/"), strings.HasPrefix(ct, "audio/"), strings.HasPrefix(ct, "video/"):
+        return true
+    }
+    for _, ext := range compressedExtensions {
+        if mimeDict[ext] == ct {
+            return true
+        }
+    }
+    for _, t := range compressedTypes {
+        if t == ct {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,134 @@
+package httputil
+
+import (
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+/*
+    Accept, Accept-Charset, Accept-Encoding and Accept-Language share one
+    syntax, a list of ranges weighted by q values:
+
+    Accept: text/html, application/json;q=0.9, text/*;q=0.1
+
+    items := ResolveAccept(r)
+    best, ok := items.Negotiate("application/json", "text/html")
+
+    Negotiate returns the offer with the highest q value of its most
+    specific matching range. Ties go to the offer matched more exactly,
+    a media type named in the header beats one only matched by a
+    wildcard, then to the earlier offer. No header accepts anything,
+    q=0 refuses.
+//*/
+
+// AcceptItem is one range of an Accept* header.
+type AcceptItem struct {
+    // lowercase, e.g. "text/html", "gzip", "en-us" or "*"
+    Value   string
+    // media type parameters besides q
+    Params  map[string]string
+    Quality float32
+}
+
+// AcceptItems are sorted by quality, ranges of equal quality keep the
+// header order.
+type AcceptItems []AcceptItem
+
+// ParseAccept parses any Accept* header, malformed q values count as 1.
+func ParseAccept(header string) (AcceptItems) { return parseAccept(header, true) }
+
+func parseAccept(header string, lower bool) (items AcceptItems) {
+    for _, field := range strings.Split(header, ",") {
+        parts := strings.Split(field, ";")
+        item := AcceptItem{
+            Value: strings.TrimSpace(parts[0]),
+            Quality: 1,
+        }
+        if lower {
+            item.Value = strings.ToLower(item.Value)
+        }
+        if item.Value == "" {
+            continue
+        }
+        for _, param := range parts[1:] {
+            kv := strings.SplitN(param, "=", 2)
+            key := strings.ToLower(strings.TrimSpace(kv[0]))
+            value := ""
+            if len(kv) == 2 {
+                value = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+            }
+            if key == "q" {
+                if q, err := strconv.ParseFloat(value, 32); err == nil && q >= 0 && q <= 1 {
+                    item.Quality = float32(q)
+                }
+                continue
+            }
+            if item.Params == nil {
+                item.Params = make(map[string]string)
+            }
+            item.Params[key] = value
+        }
+        items = append(items, item)
+    }
+    sort.SliceStable(items, func (i, j int) (bool) { return items[i].Quality > items[j].Quality })
+    return
+}
+
+func ResolveAccept(req *http.Request) (AcceptItems) { return ParseAccept(req.Header.Get(HEADER_ACCEPT)) }
+func ResolveAcceptCharset(req *http.Request) (AcceptItems) { return ParseAccept(req.Header.Get(HEADER_ACCEPT_CHARSET)) }
+func ResolveAcceptEncoding(req *http.Request) (AcceptItems) { return ParseAccept(req.Header.Get(HEADER_ACCEPT_ENCODING)) }
+func ResolveAcceptLanguageItems(req *http.Request) (AcceptItems) { return ParseAccept(req.Header.Get(HEADER_ACCEPT_LANGUAGE)) }
+
+// Quality returns the q value of the most specific range matching offer,
+// a media type like "text/html" or a token like "gzip", 0 when none does.
+func (this AcceptItems) Quality(offer string) (float32) {
+    q, _ := this.match(offer)
+    return q
+}
+
+// match returns the q value and the specificity of the range matching
+// offer: 2 for the offer itself, 1 for type/*, 0 for */*, -1 for none.
+func (this AcceptItems) match(offer string) (float32, int) {
+    offer = strings.ToLower(offer)
+    slash := strings.IndexByte(offer, '/')
+    best, specificity := float32(0), -1
+    for _, item := range this {
+        s := -1
+        switch {
+        case item.Value == offer:
+            s = 2
+        case item.Value == "*" || item.Value == "*/*":
+            s = 0
+        case slash > 0 && item.Value == offer[:slash] + "/*":
+            s = 1
+        }
+        if s > specificity {
+            best, specificity = item.Quality, s
+        }
+    }
+    return best, specificity
+}
+
+// Negotiate returns the preferred acceptable offer.
+func (this AcceptItems) Negotiate(offers ...string) (string, bool) {
+    if len(offers) <= 0 {
+        return "", false
+    }
+    if len(this) <= 0 {
+        return offers[0], true
+    }
+    var (
+        best        string
+        quality     float32
+        specificity int
+    )
+    for _, offer := range offers {
+        q, s := this.match(offer)
+        if q > quality || (q == quality && q > 0 && s > specificity) {
+            best, quality, specificity = offer, q, s
+        }
+    }
+    return best, quality > 0
+}
@@ -0,0 +1,33 @@
+package httputil_test
+
+import (
+    "testing"
+    "net/http/httptest"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+func TestNegotiate(t *testing.T) () {
+    for _, io := range []struct {
+        accept  string
+        format  string
+    }{
+        { ""                                , "html" },
+        { "application/json, */*"           , "json" },
+        { "*/*, application/json"           , "json" },
+        { "text/*, text/plain"              , "txt" },
+        { "application/json;q=0.5, */*"     , "html" },
+        { "application/xml, application/json", "xml" },
+    } {
+        req := httptest.NewRequest(httputil.METHOD_GET, "http://example.com/", nil)
+        req.Header.Set(httputil.HEADER_ACCEPT, io.accept)
+        if format := httputil.ResolveFormat(req); format != io.format {
+            t.Fatalf("Accept %q must resolve %q, but got %q\n", io.accept, io.format, format)
+        }
+    }
+
+    req := httptest.NewRequest(httputil.METHOD_GET, "http://example.com/", nil)
+    req.Header.Set(httputil.HEADER_ACCEPT_LANGUAGE, "en-US;q=0.8, zh-Hant-TW")
+    if languages := httputil.ResolveAcceptLanguage(req).String(); languages != "zh-Hant-TW (1.0), en-US (0.8)" {
+        t.Fatalf("languages must keep their case, but got %q\n", languages)
+    }
+}
@@ -0,0 +1,236 @@
+package httputil
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "path"
+    "strconv"
+    "time"
+)
+
+/*
+    Matched rule parts are kept in the request context:
+
+    router.Handle("/users/<id:int>/<at:date>", METHOD_GET, handler)
+
+    id, err := ParamInt(r, "id")                // int64
+    v, err := ParamValue(r, "at")               // time.Time
+    args := RequestParams(r).Args()             // anonymous parts
+
+    Each filter has a converter giving the typed value, typed accessors
+    only accept parts whose filter converts to their type. Captures of
+    host patterns like {tenant}.example.com follow with the "host" filter.
+//*/
+
+var (
+    ErrParamNotFound = errors.New("httputil: no such route param")
+    ErrParamType = errors.New("httputil: route param has another type")
+)
+
+// ConvertFunc turns a matched value into the typed value of its filter.
+type ConvertFunc func (value, conf string) (interface{}, error)
+
+var converters = map[string]ConvertFunc{
+    "re": convertString,
+    "int": func (value, conf string) (interface{}, error) { return strconv.ParseInt(value, 10, 64) },
+    "float": func (value, conf string) (interface{}, error) { return strconv.ParseFloat(value, 64) },
+    // cleaned, ".." can't climb above the matched part
+    "path": func (value, conf string) (interface{}, error) { return path.Clean("/" + value)[1:], nil },
+}
+
+func convertString(value, conf string) (interface{}, error) { return value, nil }
+
+func init() () {
+    RegisterFilter("uuid", func (conf string) (string) {
+        return `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`
+    }, nil)
+    RegisterFilter("slug", func (conf string) (string) {
+        return `[a-z0-9]+(?:-[a-z0-9]+)*`
+    }, nil)
+    // <day:date> or <month:date:2006-01> with a time layout as conf
+    RegisterFilter("date", func (conf string) (string) {
+        return `\d{4}-\d{2}(?:-\d{2})?`
+    }, func (value, conf string) (interface{}, error) {
+        if conf == "" {
+            conf = "2006-01-02"
+        }
+        return time.Parse(conf, value)
+    })
+}
+
+// RegisterFilter adds a filter usable in rules as <name:filter:conf>.
+// A nil convert keeps values as strings. Register filters before adding
+// the rules using them.
+func RegisterFilter(name string, f FilterFunc, convert ConvertFunc) () {
+    if convert == nil {
+        convert = convertString
+    }
+    filters[name] = f
+    converters[name] = convert
+}
+
+//
+
+type Param struct {
+    // "" for anonymous parts
+    Key     string
+    Value   string
+    Filter  string
+    Conf    string
+}
+
+// Typed converts the value with the converter of its filter.
+func (this *Param) Typed() (interface{}, error) {
+    convert, ok := converters[this.Filter]
+    if !ok {
+        convert = convertString
+    }
+    v, err := convert(this.Value, this.Conf)
+    if err != nil {
+        return nil, fmt.Errorf("httputil: route param %q: %v", this.Key, err)
+    }
+    return v, nil
+}
+
+// Params are the dynamic parts of the matched rule, in rule order.
+type Params []Param
+
+func newParams(parts []*builderPart, values []string) (params Params) {
+    for i, part := range parts {
+        params = append(params, Param{
+            Key: part.key,
+            Value: values[i],
+            Filter: part.filter,
+            Conf: part.conf,
+        })
+    }
+    return
+}
+func (this Params) Get(key string) (*Param, bool) {
+    for i := range this {
+        if this[i].Key == key && key != "" {
+            return &this[i], true
+        }
+    }
+    return nil, false
+}
+// Args returns the values of anonymous parts.
+func (this Params) Args() (args []string) {
+    for _, param := range this {
+        if param.Key == "" {
+            args = append(args, param.Value)
+        }
+    }
+    return
+}
+// Kwargs returns the values of named parts, the first of a key wins
+// like with Get.
+func (this Params) Kwargs() (kwargs map[string]string) {
+    for _, param := range this {
+        if param.Key == "" {
+            continue
+        }
+        if _, ok := kwargs[param.Key]; ok {
+            continue
+        }
+        if kwargs == nil {
+            kwargs = make(map[string]string)
+        }
+        kwargs[param.Key] = param.Value
+    }
+    return
+}
+
+type routeKey struct{}
+type routeValue struct {
+    router  *Router
+    rule    string
+    params  Params
+}
+
+// matchedKey holds a *matchedRoute set by middlewares running before
+// routing, e.g. the access log, which only see the request they passed on.
+type matchedKey struct{}
+type matchedRoute struct {
+    rule    string
+}
+
+func withRoute(h http.Handler, router *Router, rule string, params Params) (http.Handler) {
+    if h == nil {
+        return nil
+    }
+    return http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        if matched, ok := r.Context().Value(matchedKey{}).(*matchedRoute); ok {
+            matched.rule = rule
+        }
+        h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeKey{}, &routeValue{router, rule, params})))
+    })
+}
+
+// RequestRule returns the rule r was routed by.
+func RequestRule(r *http.Request) (string) {
+    if route, ok := r.Context().Value(routeKey{}).(*routeValue); ok {
+        return route.rule
+    }
+    return ""
+}
+
+// RequestParams returns the params of the rule r was routed by.
+func RequestParams(r *http.Request) (Params) {
+    if route, ok := r.Context().Value(routeKey{}).(*routeValue); ok {
+        return route.params
+    }
+    return nil
+}
+
+// GetParam returns the raw value of a named part, "" when missing.
+func GetParam(r *http.Request, key string) (string) {
+    if param, ok := RequestParams(r).Get(key); ok {
+        return param.Value
+    }
+    return ""
+}
+func ParamValue(r *http.Request, key string) (interface{}, error) {
+    param, ok := RequestParams(r).Get(key)
+    if !ok {
+        return nil, ErrParamNotFound
+    }
+    return param.Typed()
+}
+func ParamInt(r *http.Request, key string) (int64, error) {
+    v, err := ParamValue(r, key)
+    if err != nil {
+        return 0, err
+    }
+    if n, ok := v.(int64); ok {
+        return n, nil
+    }
+    return 0, ErrParamType
+}
+func ParamFloat(r *http.Request, key string) (float64, error) {
+    v, err := ParamValue(r, key)
+    if err != nil {
+        return 0, err
+    }
+    if n, ok := v.(float64); ok {
+        return n, nil
+    }
+    return 0, ErrParamType
+}
+// ParamPath returns a <key:path> part cleaned, without leading slash.
+func ParamPath(r *http.Request, key string) (string, error) {
+    param, ok := RequestParams(r).Get(key)
+    if !ok {
+        return "", ErrParamNotFound
+    }
+    if param.Filter != "path" {
+        return "", ErrParamType
+    }
+    v, err := param.Typed()
+    if err != nil {
+        return "", err
+    }
+    return v.(string), nil
+}
@@ -0,0 +1,62 @@
+package httputil_test
+
+import (
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "strconv"
+    "time"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+func TestParams(t *testing.T) () {
+    httputil.RegisterFilter("hex", func (conf string) (string) { return `[0-9a-f]+` }, func (value, conf string) (interface{}, error) {
+        return strconv.ParseInt(value, 16, 64)
+    })
+    var got []interface{}
+    handler := http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        got = nil
+        for _, param := range httputil.RequestParams(r) {
+            v, err := param.Typed()
+            if err != nil {
+                t.Fatal(err)
+            }
+            got = append(got, v)
+        }
+    })
+    router := httputil.NewRouter()
+    router.Handle("/a/<id:int>/<v:float>/<p:path>", httputil.METHOD_GET, handler)
+    router.Handle("/b/<:re:(x|y)z>/<h:hex>/<d:date>", httputil.METHOD_GET, handler)
+    router.Handle("/c/<u:uuid>/<s:slug>", httputil.METHOD_GET, handler)
+
+    for _, io := range []struct {
+        path    string
+        values  []interface{}
+    }{
+        { "/a/-7/1.5/x/../../y", []interface{}{ int64(-7), 1.5, "y" } },
+        { "/b/yz/ff/2024-02-29", []interface{}{ "yz", int64(255), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC) } },
+        { "/c/123e4567-e89b-12d3-a456-426614174000/hello-world", []interface{}{ "123e4567-e89b-12d3-a456-426614174000", "hello-world" } },
+    } {
+        router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(httputil.METHOD_GET, "http://example.com" + io.path, nil))
+        if !reflect.DeepEqual(got, io.values) {
+            t.Fatalf("%q must get %v, but got %v\n", io.path, io.values, got)
+        }
+    }
+
+    req := httptest.NewRequest(httputil.METHOD_GET, "http://example.com/a/3/2/f", nil)
+    router.Handle("/a/<id:int>/<v:float>/<p:path>", httputil.METHOD_POST, http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        req = r
+    }))
+    req.Method = httputil.METHOD_POST
+    router.ServeHTTP(httptest.NewRecorder(), req)
+    if id, err := httputil.ParamInt(req, "id"); err != nil || id != 3 {
+        t.Fatalf("ParamInt(id) must be 3, but got %d %v\n", id, err)
+    }
+    if _, err := httputil.ParamInt(req, "v"); err != httputil.ErrParamType {
+        t.Fatalf("ParamInt(v) must fail with %v, but got %v\n", httputil.ErrParamType, err)
+    }
+    if p, err := httputil.ParamPath(req, "p"); err != nil || p != "f" {
+        t.Fatalf("ParamPath(p) must be %q, but got %q %v\n", "f", p, err)
+    }
+}
@@ -0,0 +1,331 @@
+package httputil
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "html"
+    "net/http"
+    "strconv"
+    "strings"
+)
+
+/*
+    Errors of the router (404, 405, unknown hosts), of the middlewares and
+    helpers (Recovery, Bind, Render, static files) and errors returned by
+    handlers are written in one place:
+
+    router.HandleErrFunc("/users/<id:int>", METHOD_GET, func (w http.ResponseWriter, r *http.Request) (error) {
+        user, err := load(GetParam(r, "id"))
+        if err == sql.ErrNoRows {
+            return NewError(http.StatusNotFound, "no such user")
+        }
+        if err != nil {
+            return err      // 500, the cause is not sent
+        }
+        return Render(w, r, http.StatusOK, user)
+    })
+
+    router.StatusHandler(http.StatusNotFound, notFoundPage)
+    router.OnError(func (w http.ResponseWriter, r *http.Request, err *Error) () {
+        if err.Status >= 500 {
+            log.Print(err)
+        }
+        RenderError(w, r, err)
+    })
+
+    A handler set for the status answers first, RequestError tells it the
+    error, then the OnError hook, then RenderError: RFC 7807
+    application/problem+json, an HTML page or plain text as negotiated.
+    Clients without an Accept header get plain text like http.Error.
+//*/
+
+const MIME_PROBLEM_JSON = "application/problem+json"
+
+// Error is an HTTP error, sent as an RFC 7807 problem.
+type Error struct {
+    Status      int
+    // a URI identifying the kind of problem, "about:blank" when empty
+    Type        string
+    // the status text when empty
+    Title       string
+    Detail      string
+    Instance    string
+    // additional members of the problem, e.g. "errors"
+    Extensions  map[string]interface{}
+    // the cause, not sent
+    Err         error
+}
+
+func NewError(status int, detail string) (*Error) {
+    return &Error{ Status: status, Detail: detail }
+}
+
+func (this *Error) Error() (string) {
+    msg := this.text()
+    if this.Err != nil {
+        msg += ": " + this.Err.Error()
+    }
+    return msg
+}
+func (this *Error) Unwrap() (error) { return this.Err }
+
+func (this *Error) status() (int) {
+    if this.Status <= 0 {
+        return http.StatusInternalServerError
+    }
+    return this.Status
+}
+func (this *Error) title() (string) {
+    if this.Title != "" {
+        return this.Title
+    }
+    return http.StatusText(this.status())
+}
+// text is e.g. "404 page not found", the detail or the lowercase title.
+func (this *Error) text() (string) {
+    if this.Detail != "" {
+        return strconv.Itoa(this.status()) + " " + this.Detail
+    }
+    return strconv.Itoa(this.status()) + " " + strings.ToLower(this.title())
+}
+
+func (this *Error) MarshalJSON() ([]byte, error) {
+    problem := make(map[string]interface{}, len(this.Extensions) + 5)
+    for key, value := range this.Extensions {
+        problem[key] = value
+    }
+    problem["type"] = "about:blank"
+    if this.Type != "" {
+        problem["type"] = this.Type
+    }
+    problem["title"] = this.title()
+    problem["status"] = this.status()
+    if this.Detail != "" {
+        problem["detail"] = this.Detail
+    }
+    if this.Instance != "" {
+        problem["instance"] = this.Instance
+    }
+    return json.Marshal(problem)
+}
+
+// AsError finds the *Error in err's chain, bind errors keep their status
+// and field errors, anything else is a 500.
+func AsError(err error) (*Error) {
+    var (
+        e           *Error
+        bindErr     *BindError
+        tooLarge    *http.MaxBytesError
+    )
+    switch {
+    case errors.As(err, &e):
+        return e
+    case errors.As(err, &bindErr):
+        e = &Error{ Status: bindErr.Status, Detail: bindErr.Detail, Err: bindErr }
+        if len(bindErr.Errors) > 0 {
+            e.Extensions = map[string]interface{}{ "errors": bindErr.Errors }
+        }
+        return e
+    case errors.As(err, &tooLarge):
+        return &Error{ Status: http.StatusRequestEntityTooLarge, Err: err }
+    }
+    return &Error{ Status: http.StatusInternalServerError, Err: err }
+}
+
+// ErrorFunc writes the errors of a router, see OnError.
+type ErrorFunc func (w http.ResponseWriter, r *http.Request, err *Error) ()
+
+var errorOffers = []string{ MIME_PROBLEM_JSON, "application/json", "text/html", "text/plain" }
+
+// RenderError writes err as problem+json, HTML or plain text.
+func RenderError(w http.ResponseWriter, r *http.Request, err *Error) () {
+    mediaType := "text/plain"
+    if r.Header.Get(HEADER_ACCEPT) != "" {
+        if best, ok := ResolveAccept(r).Negotiate(errorOffers...); ok {
+            mediaType = best
+        }
+    }
+    var body []byte
+    switch mediaType {
+    case "text/plain":
+        body = []byte(err.text() + "\n")
+    case "text/html":
+        title := html.EscapeString(strconv.Itoa(err.status()) + " " + err.title())
+        page := "<!DOCTYPE html>\n<html><head><title>" + title + "</title></head>\n<body><h1>" + title + "</h1>"
+        if err.Detail != "" {
+            page += "<p>" + html.EscapeString(err.Detail) + "</p>"
+        }
+        body = []byte(page + "</body></html>\n")
+    default:
+        body, _ = json.Marshal(err)
+        mediaType = MIME_PROBLEM_JSON
+    }
+
+    ct := mediaType
+    if strings.HasPrefix(ct, "text/") {
+        ct += "; charset=utf-8"
+    }
+    if rw, ok := w.(ResponseWriter); ok {
+        rw.SetContentType(ct)
+    }
+    header := w.Header()
+    header.Add(HEADER_VARY, HEADER_ACCEPT)
+    header.Set(HEADER_CONTENT_TYPE, ct)
+    header.Set(HEADER_CONTENT_LENGTH, strconv.Itoa(len(body)))
+    header.Set(XHEADER_CONTENT_TYPE_OPTIONS, "nosniff")
+    w.WriteHeader(err.status())
+    if r.Method != METHOD_HEAD {
+        w.Write(body)
+    }
+}
+
+// WriteError writes err with the hooks of the router r was routed by,
+// with RenderError before routing.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) () {
+    if route, ok := r.Context().Value(routeKey{}).(*routeValue); ok && route.router != nil {
+        route.router.WriteError(w, r, err)
+        return
+    }
+    RenderError(w, r, AsError(err))
+}
+
+type errorKey struct{}
+
+// RequestError returns the error a StatusHandler is called for.
+func RequestError(r *http.Request) (*Error) {
+    err, _ := r.Context().Value(errorKey{}).(*Error)
+    return err
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err *Error, h http.Handler, onError ErrorFunc) () {
+    switch {
+    case h != nil:
+        h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), errorKey{}, err)))
+    case onError != nil:
+        onError(w, r, err)
+    default:
+        RenderError(w, r, err)
+    }
+}
+
+//
+
+// OnError sets the hook writing errors without a StatusHandler.
+func (this *Router) OnError(f ErrorFunc) () {
+    if this.root != nil {
+        this.root.OnError(f)
+        return
+    }
+    this.Lock()
+    defer this.Unlock()
+    this.onError = f
+}
+
+// StatusHandler sets the handler of errors with status, e.g. a 404 page,
+// nil removes it.
+func (this *Router) StatusHandler(status int, h http.Handler) () {
+    if this.root != nil {
+        this.root.StatusHandler(status, h)
+        return
+    }
+    this.Lock()
+    defer this.Unlock()
+    if h == nil {
+        delete(this.statusHandlers, status)
+        return
+    }
+    if this.statusHandlers == nil {
+        this.statusHandlers = make(map[int]http.Handler)
+    }
+    this.statusHandlers[status] = h
+}
+
+// WriteError writes err with the hooks of the router.
+func (this *Router) WriteError(w http.ResponseWriter, r *http.Request, err error) () {
+    if this.root != nil {
+        this.root.WriteError(w, r, err)
+        return
+    }
+    e := AsError(err)
+    this.RLock()
+    h, onError := this.statusHandlers[e.status()], this.onError
+    this.RUnlock()
+    writeError(w, r, e, h, onError)
+}
+
+func (this *Router) errorHandler(err error) (http.Handler) {
+    return http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () { this.WriteError(w, r, err) })
+}
+func (this *Router) methodNotAllowed(allow []string) (http.Handler) {
+    return http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        w.Header().Set(HEADER_ALLOW, strings.Join(allow, ", "))
+        this.WriteError(w, r, NewError(http.StatusMethodNotAllowed, "method not allowed"))
+    })
+}
+
+// OnError sets the hook of every host router, present and future, and
+// of unknown hosts.
+func (this *MultiHostRouter) OnError(f ErrorFunc) () {
+    this.Lock()
+    this.onError = f
+    routers := this.load().routers()
+    this.Unlock()
+    for _, router := range routers {
+        router.OnError(f)
+    }
+}
+
+// StatusHandler sets the handler of every host router, present and
+// future, and of unknown hosts.
+func (this *MultiHostRouter) StatusHandler(status int, h http.Handler) () {
+    this.Lock()
+    statusHandlers := make(map[int]http.Handler, len(this.statusHandlers) + 1)
+    for code, handler := range this.statusHandlers {
+        statusHandlers[code] = handler
+    }
+    if h == nil {
+        delete(statusHandlers, status)
+    } else {
+        statusHandlers[status] = h
+    }
+    this.statusHandlers = statusHandlers
+    routers := this.load().routers()
+    this.Unlock()
+    for _, router := range routers {
+        router.StatusHandler(status, h)
+    }
+}
+
+// WriteError writes err with the hooks of the multi host router, e.g. for
+// Recovery running before routing.
+func (this *MultiHostRouter) WriteError(w http.ResponseWriter, r *http.Request, err error) () {
+    e := AsError(err)
+    this.RLock()
+    h, onError := this.statusHandlers[e.status()], this.onError
+    this.RUnlock()
+    writeError(w, r, e, h, onError)
+}
+
+//
+
+// ErrHandlerFunc is a handler returning its error, which is written by
+// WriteError unless the response was started.
+type ErrHandlerFunc func (w http.ResponseWriter, r *http.Request) (error)
+
+func (this ErrHandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) () {
+    rw, ok := w.(ResponseWriter)
+    if !ok {
+        rw = NewResponseWriter(w)
+    }
+    if err := this(rw, r); err != nil && !rw.Written() {
+        WriteError(rw, r, err)
+    }
+}
+
+func (this *Router) HandleErrFunc(rule, method string, f ErrHandlerFunc) () {
+    this.Handle(rule, method, f)
+}
+func (this *MultiHostRouter) HandleErrFunc(host_pattern, path_pattern, method string, f ErrHandlerFunc) () {
+    this.AddRouter(host_pattern).HandleErrFunc(path_pattern, method, f)
+}
@@ -0,0 +1,103 @@
+package httputil_test
+
+import (
+    "io"
+    "log"
+    "testing"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+func TestErrors(t *testing.T) () {
+    router := httputil.NewMultiHostRouter()
+    var logged []string
+    router.OnError(func (w http.ResponseWriter, r *http.Request, err *httputil.Error) () {
+        logged = append(logged, err.Error())
+        httputil.RenderError(w, r, err)
+    })
+    router.HandleErrFunc("www.example.com", "/fail", httputil.METHOD_GET, func (w http.ResponseWriter, r *http.Request) (error) {
+        return fmt.Errorf("secret cause")
+    })
+    router.HandleErrFunc("www.example.com", "/gone", httputil.METHOD_GET, func (w http.ResponseWriter, r *http.Request) (error) {
+        return &httputil.Error{ Status: http.StatusGone, Detail: "moved away", Extensions: map[string]interface{}{ "id": 3 } }
+    })
+    router.Handle("www.example.com", "/panic", httputil.METHOD_GET, http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        panic("boom")
+    }))
+    router.StatusHandler(http.StatusNotFound, http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        w.WriteHeader(httputil.RequestError(r).Status)
+        io.WriteString(w, "custom " + r.URL.Path)
+    }))
+    recovery := httputil.NewRecovery(log.New(io.Discard, "", 0), nil, false)
+    recovery.(*httputil.Recovery).WriteError = router.WriteError
+    chain := httputil.NewChainHandler(recovery)
+    chain.Chain(httputil.HandlerFunc(func (w http.ResponseWriter, r *http.Request, next http.HandlerFunc) () {
+        router.ServeHTTP(w, r)
+    }))
+
+    for _, io := range []struct {
+        url     string
+        accept  string
+        code    int
+        ct      string
+        body    string
+    }{
+        { "http://www.example.com/fail", "", http.StatusInternalServerError, "text/plain; charset=utf-8", "500 internal server error\n" },
+        { "http://www.example.com/fail", "application/json", http.StatusInternalServerError, httputil.MIME_PROBLEM_JSON, `{"status":500,"title":"Internal Server Error","type":"about:blank"}` },
+        { "http://www.example.com/gone", "application/problem+json", http.StatusGone, httputil.MIME_PROBLEM_JSON, `{"detail":"moved away","id":3,"status":410,"title":"Gone","type":"about:blank"}` },
+        { "http://www.example.com/gone", "text/html", http.StatusGone, "text/html; charset=utf-8", "<!DOCTYPE html>\n<html><head><title>410 Gone</title></head>\n<body><h1>410 Gone</h1><p>moved away</p></body></html>\n" },
+        { "http://www.example.com/none", "", http.StatusNotFound, "", "custom /none" },
+        { "http://other.example.com/", "", http.StatusNotAcceptable, "text/plain; charset=utf-8", "406 unknown host\n" },
+        { "http://www.example.com/panic", "application/json", http.StatusInternalServerError, httputil.MIME_PROBLEM_JSON, `{"status":500,"title":"Internal Server Error","type":"about:blank"}` },
+    } {
+        req := httptest.NewRequest(httputil.METHOD_GET, io.url, nil)
+        if io.accept != "" {
+            req.Header.Set(httputil.HEADER_ACCEPT, io.accept)
+        }
+        w := httptest.NewRecorder()
+        chain.ServeHTTP(w, req)
+        if w.Code != io.code || w.Header().Get(httputil.HEADER_CONTENT_TYPE) != io.ct && io.ct != "" || w.Body.String() != io.body {
+            t.Fatalf("%s (%q) must reply %d %q %q, but got %d %q %q\n", io.url, io.accept, io.code, io.ct, io.body, w.Code, w.Header().Get(httputil.HEADER_CONTENT_TYPE), w.Body.String())
+        }
+    }
+    if len(logged) != 6 || logged[0] != "500 internal server error: secret cause" || logged[5] != "500 internal server error: panic: boom" {
+        t.Fatalf("OnError must see every error but the 404, but got %q\n", logged)
+    }
+}
+
+func TestErrorsReload(t *testing.T) () {
+    router := httputil.NewMultiHostRouter()
+    var logged int
+    router.OnError(func (w http.ResponseWriter, r *http.Request, err *httputil.Error) () {
+        logged++
+        httputil.RenderError(w, r, err)
+    })
+    router.StatusHandler(http.StatusNotFound, http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        w.WriteHeader(http.StatusNotFound)
+        io.WriteString(w, "custom")
+    }))
+    fail := func (w http.ResponseWriter, r *http.Request) (error) { return fmt.Errorf("fail") }
+    serve := func (url string) (*httptest.ResponseRecorder) {
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest(httputil.METHOD_GET, url, nil))
+        return w
+    }
+
+    set := httputil.NewRouter()
+    set.HandleErrFunc("/fail", httputil.METHOD_GET, fail)
+    next := httputil.NewMultiHostRouter()
+    next.HandleErrFunc("swap.example.com", "/fail", httputil.METHOD_GET, fail)
+    router.Swap(next)
+    router.SetRouter("set.example.com", set)
+    for _, host := range []string{ "set.example.com", "swap.example.com" } {
+        logged = 0
+        if w := serve("http://" + host + "/none"); w.Body.String() != "custom" {
+            t.Fatalf("%s must use the status handlers, but got %q\n", host, w.Body.String())
+        }
+        if serve("http://" + host + "/fail"); logged != 1 {
+            t.Fatalf("%s must use OnError, but got %d calls\n", host, logged)
+        }
+    }
+}
@@ -0,0 +1,220 @@
+package httputil
+
+import (
+    "hash/fnv"
+    "math"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+)
+
+/*
+    // 100 requests per minute and client
+    chain := NewChainHandler(NewRateLimit(&RateLimitOptions{
+        Limit: 100,
+        Period: time.Minute,
+        Key: KeyByIP(proxies),
+    }), ...)
+
+    // 10 logins per minute and client on one rule
+    auth := router.Group("", NewRateLimit(&RateLimitOptions{
+        Limit: 10,
+        Period: time.Minute,
+        Key: KeyByRoute(KeyByIP(proxies)),
+    }))
+
+    Each key owns a token bucket of Limit tokens refilled over Period, so
+    bursts up to Limit pass, then requests are spread evenly. Responses
+    carry RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+    RateLimit-Policy, refused ones get 429 with Retry-After.
+//*/
+
+// KeyFunc names the bucket of a request, "" lets it pass unlimited.
+type KeyFunc func (r *http.Request) (string)
+
+// KeyByIP limits each client address.
+func KeyByIP(proxies TrustedProxies) (KeyFunc) {
+    return func (r *http.Request) (string) { return "ip:" + proxies.ClientIP(r) }
+}
+
+// KeyByHeader limits each value of a header, e.g. an API key. Requests
+// without it are unlimited, authenticate them before.
+func KeyByHeader(name string) (KeyFunc) {
+    return func (r *http.Request) (string) {
+        if value := r.Header.Get(name); value != "" {
+            return name + ":" + value
+        }
+        return ""
+    }
+}
+
+// KeyByRoute gives every rule its own buckets, it needs to run after
+// routing, e.g. as group middleware.
+func KeyByRoute(key KeyFunc) (KeyFunc) {
+    return func (r *http.Request) (string) {
+        k := key(r)
+        if k == "" {
+            return ""
+        }
+        rule := RequestRule(r)
+        if rule == "" {
+            rule = r.URL.Path
+        }
+        return r.Method + " " + rule + " " + k
+    }
+}
+
+//
+
+type RateLimitResult struct {
+    Allowed     bool
+    Remaining   int
+    // until the bucket is full again
+    Reset       time.Duration
+    // until the next token, when refused
+    RetryAfter  time.Duration
+}
+
+// RateLimitStore keeps the buckets, implement it on shared storage when
+// several servers limit together.
+type RateLimitStore interface {
+    Take(key string, limit int, period time.Duration, now time.Time) (RateLimitResult, error)
+}
+
+const memoryStoreShards = 32
+
+// NewMemoryStore keeps buckets in memory, full buckets are dropped.
+func NewMemoryStore() (*MemoryStore) {
+    this := &MemoryStore{}
+    for i := range this.shards {
+        this.shards[i].buckets = make(map[string]*bucket)
+    }
+    return this
+}
+
+type MemoryStore struct {
+    shards  [memoryStoreShards]memoryShard
+}
+type memoryShard struct {
+    sync.Mutex
+    buckets map[string]*bucket
+    takes   int
+}
+type bucket struct {
+    tokens  float64
+    last    time.Time
+}
+
+func (this *MemoryStore) Take(key string, limit int, period time.Duration, now time.Time) (RateLimitResult, error) {
+    h := fnv.New32a()
+    h.Write([]byte(key))
+    shard := &this.shards[h.Sum32() % memoryStoreShards]
+    rate := float64(limit) / period.Seconds()
+
+    shard.Lock()
+    defer shard.Unlock()
+    if shard.takes++; shard.takes % 1024 == 0 {
+        shard.sweep(now, period)
+    }
+    b, ok := shard.buckets[key]
+    if !ok {
+        b = &bucket{ tokens: float64(limit), last: now }
+        shard.buckets[key] = b
+    }
+    if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+        b.tokens = math.Min(float64(limit), b.tokens + elapsed*rate)
+        b.last = now
+    }
+    var result RateLimitResult
+    if b.tokens >= 1 {
+        b.tokens--
+        result.Allowed = true
+    } else {
+        result.RetryAfter = seconds((1 - b.tokens) / rate)
+    }
+    result.Remaining = int(b.tokens)
+    result.Reset = seconds((float64(limit) - b.tokens) / rate)
+    return result, nil
+}
+
+// sweep drops the buckets refilled by now, the caller holds the lock.
+func (this *memoryShard) sweep(now time.Time, period time.Duration) () {
+    for key, b := range this.buckets {
+        if now.Sub(b.last) >= period {
+            delete(this.buckets, key)
+        }
+    }
+}
+
+func seconds(s float64) (time.Duration) { return time.Duration(s * float64(time.Second)) }
+
+//
+
+type RateLimitOptions struct {
+    // 60 requests a minute by default
+    Limit       int
+    Period      time.Duration
+    // KeyByIP(nil) by default
+    Key         KeyFunc
+    // a MemoryStore by default, requests pass when the store fails
+    Store       RateLimitStore
+    // replies to refused requests, 429 by default
+    Refused     http.Handler
+}
+
+func NewRateLimit(opts *RateLimitOptions) (Handler) {
+    this := &RateLimit{}
+    if opts != nil {
+        this.RateLimitOptions = *opts
+    }
+    if this.Limit == 0 {
+        this.Limit = 60
+    }
+    if this.Period == 0 {
+        this.Period = time.Minute
+    }
+    if this.Limit < 0 || this.Period < 0 {
+        panic("httputil: rate limit needs a positive Limit and Period")
+    }
+    if this.Key == nil {
+        this.Key = KeyByIP(nil)
+    }
+    if this.Store == nil {
+        this.Store = NewMemoryStore()
+    }
+    if this.Refused == nil {
+        this.Refused = ErrorHandler("429 too many requests", http.StatusTooManyRequests)
+    }
+    return this
+}
+
+type RateLimit struct {
+    RateLimitOptions
+}
+
+func (this *RateLimit) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+    key := this.Key(r)
+    if key == "" {
+        next(w, r)
+        return
+    }
+    result, err := this.Store.Take(key, this.Limit, this.Period, time.Now())
+    if err != nil {
+        next(w, r)
+        return
+    }
+    header := w.Header()
+    header.Set(HEADER_RATELIMIT_LIMIT, strconv.Itoa(this.Limit))
+    header.Set(HEADER_RATELIMIT_REMAINING, strconv.Itoa(result.Remaining))
+    header.Set(HEADER_RATELIMIT_RESET, strconv.Itoa(ceilSeconds(result.Reset)))
+    header.Set(HEADER_RATELIMIT_POLICY, strconv.Itoa(this.Limit) + ";w=" + strconv.Itoa(ceilSeconds(this.Period)))
+    if !result.Allowed {
+        header.Set(HEADER_RETRY_AFTER, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+        this.Refused.ServeHTTP(w, r)
+        return
+    }
+    next(w, r)
+}
+
+func ceilSeconds(d time.Duration) (int) { return int(math.Ceil(d.Seconds())) }
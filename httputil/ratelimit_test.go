@@ -0,0 +1,60 @@
+package httputil_test
+
+import (
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "time"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+func TestRateLimit(t *testing.T) () {
+    store := httputil.NewMemoryStore()
+    now := time.Now()
+    for i := 0; i < 2; i++ {
+        if result, _ := store.Take("k", 2, time.Minute, now); !result.Allowed || result.Remaining != 1-i {
+            t.Fatalf("take #%d must pass, but got %+v\n", i, result)
+        }
+    }
+    if result, _ := store.Take("k", 2, time.Minute, now); result.Allowed || result.RetryAfter != 30*time.Second {
+        t.Fatalf("third take must wait 30s, but got %+v\n", result)
+    }
+    if result, _ := store.Take("k", 2, time.Minute, now.Add(30*time.Second)); !result.Allowed {
+        t.Fatalf("take must pass after 30s, but got %+v\n", result)
+    }
+
+    chain := httputil.NewChainHandler(httputil.NewRateLimit(&httputil.RateLimitOptions{
+        Limit: 1,
+        Period: time.Hour,
+        Key: httputil.KeyByHeader("X-Api-Key"),
+    }), httputil.HandlerFunc(func (w http.ResponseWriter, r *http.Request, next http.HandlerFunc) () {
+        w.WriteHeader(http.StatusNoContent)
+    }))
+    serve := func (key string) (*httptest.ResponseRecorder) {
+        req := httptest.NewRequest(httputil.METHOD_GET, "http://example.com/", nil)
+        req.Header.Set("X-Api-Key", key)
+        w := httptest.NewRecorder()
+        chain.ServeHTTP(w, req)
+        return w
+    }
+    if w := serve("a"); w.Code != http.StatusNoContent || w.Header().Get(httputil.HEADER_RATELIMIT_REMAINING) != "0" {
+        t.Fatalf("first request must pass, but got %d %v\n", w.Code, w.Header())
+    }
+    if w := serve("a"); w.Code != http.StatusTooManyRequests || w.Header().Get(httputil.HEADER_RETRY_AFTER) != "3600" {
+        t.Fatalf("second request must be refused, but got %d %v\n", w.Code, w.Header())
+    }
+    if w := serve("b"); w.Code != http.StatusNoContent {
+        t.Fatalf("other keys must pass, but got %d\n", w.Code)
+    }
+    if w := serve(""); w.Code != http.StatusNoContent || w.Header().Get(httputil.HEADER_RATELIMIT_LIMIT) != "" {
+        t.Fatalf("requests without key must pass unlimited, but got %d\n", w.Code)
+    }
+
+    // defaults to 60 requests a minute
+    chain = httputil.NewChainHandler(httputil.NewRateLimit(nil))
+    w := httptest.NewRecorder()
+    chain.ServeHTTP(w, httptest.NewRequest(httputil.METHOD_GET, "http://example.com/", nil))
+    if w.Header().Get(httputil.HEADER_RATELIMIT_LIMIT) != "60" {
+        t.Fatalf("default limit must be 60, but got %v\n", w.Header())
+    }
+}
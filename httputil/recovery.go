@@ -19,6 +19,9 @@ type Recovery struct {
     logger  *log.Logger
     getter  GetLogger
     PrintStack bool
+    // writes the 500 unless PrintStack, WriteError by default, set a
+    // router's WriteError to use its hooks
+    WriteError func (w http.ResponseWriter, r *http.Request, err error) ()
 }
 func (this *Recovery) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
     if this.logger == nil { this.logger = this.getter() }
@@ -26,15 +29,24 @@ func (this *Recovery) ServeHTTP(w http.ResponseWriter, r *http.Request, next htt
     host := r.Host
     defer func() {
         if err := recover(); err != nil {
-            w.WriteHeader(http.StatusInternalServerError)
             stack := debug.Stack()
             f := "[%s]PANIC: %s\n%s"
             msg := fmt.Sprintf(f, host, err, stack)
+            if id := requestID(w, r); id != "" {
+                msg = fmt.Sprintf("[%s]PANIC(request %s): %s\n%s", host, id, err, stack)
+            }
             this.logger.Print(msg)
 
             if this.PrintStack {
+                w.WriteHeader(http.StatusInternalServerError)
                 fmt.Fprint(w, msg)
+                return
+            }
+            writeError := this.WriteError
+            if writeError == nil {
+                writeError = WriteError
             }
+            writeError(w, r, &Error{ Status: http.StatusInternalServerError, Err: fmt.Errorf("panic: %v", err) })
         }
     }()
 
@@ -0,0 +1,148 @@
+package httputil
+
+import (
+    "net/http"
+)
+
+/*
+    Routes may change while serving:
+
+    router.RemoveRouter(`^tenant1\.example\.com$`)
+    router.AddRouter(pattern).Unhandle("/beta/<id:int>", METHOD_GET)
+
+    or be built off to the side and swapped in at once:
+
+    next := NewRouter()
+    next.Handle("/", METHOD_GET, home)
+    router.SetRouter(`^tenant2\.example\.com$`, next)
+
+    Requests being routed keep the table they started with.
+//*/
+
+// RemoveRouter drops the router of host_pattern.
+func (this *MultiHostRouter) RemoveRouter(host_pattern string) (bool) {
+    this.Lock()
+    defer this.Unlock()
+    table := this.load()
+    old, found := table.cached[host_pattern]
+    if !found {
+        return false
+    }
+    table = table.clone()
+    delete(table.cached, host_pattern)
+    if table.defaults == old {
+        table.defaults = nil
+    }
+    for i, router := range table.hosts {
+        if router == old {
+            table.hosts = append(table.hosts[:i], table.hosts[i+1:]...)
+            break
+        }
+    }
+    this.table.Store(table)
+    return true
+}
+
+// SetRouter adds or replaces the router of host_pattern by a router
+// built with NewRouter, keeping its WrapFunc. Error hooks and status
+// handlers of this apply where router sets none.
+func (this *MultiHostRouter) SetRouter(host_pattern string, router *Router) () {
+    host := compileHostPattern(host_pattern)
+    this.adopt(router)
+    router.Lock()
+    router.host = host
+    router.Unlock()
+    this.Lock()
+    defer this.Unlock()
+    this.store(this.load(), host_pattern, router)
+}
+
+// Swap replaces every host router by the ones of next, which should not
+// be used afterwards. Settings like DefaultHost are kept, error hooks and
+// status handlers apply as with SetRouter.
+func (this *MultiHostRouter) Swap(next *MultiHostRouter) () {
+    next.Lock()
+    table := next.load().clone()
+    next.Unlock()
+    for _, router := range table.routers() {
+        this.adopt(router)
+    }
+    this.Lock()
+    defer this.Unlock()
+    this.table.Store(table)
+}
+
+// adopt makes router a host router of this, the error hook and status
+// handlers of this fill in the ones router lacks.
+func (this *MultiHostRouter) adopt(router *Router) () {
+    this.RLock()
+    onError, statusHandlers := this.onError, this.statusHandlers
+    this.RUnlock()
+    router.Lock()
+    defer router.Unlock()
+    router.parent = this
+    if router.onError == nil {
+        router.onError = onError
+    }
+    for status, h := range statusHandlers {
+        if _, ok := router.statusHandlers[status]; ok {
+            continue
+        }
+        if router.statusHandlers == nil {
+            router.statusHandlers = make(map[int]http.Handler)
+        }
+        router.statusHandlers[status] = h
+    }
+}
+
+//
+
+// Unhandle removes the handler of method from rule, an empty method
+// removes the whole rule. Other rules are rebuilt aside and installed
+// at once.
+func (this *Router) Unhandle(rule, method string) (bool) {
+    if this.root != nil {
+        return this.root.Unhandle(this.prefix + rule, method)
+    }
+    this.editLock.Lock()
+    defer this.editLock.Unlock()
+
+    this.RLock()
+    targets, found := this.rules[rule]
+    if method == "*" {
+        method = METHOD_ANY
+    }
+    if _, ok := targets[method]; found && method != "" && !ok {
+        found = false
+    }
+    this.RUnlock()
+    if !found {
+        return false
+    }
+
+    // edits are serialized, the tables can be read without the lock
+    next := NewRouter()
+    next.regexpOnly = this.regexpOnly
+    next.OnConflict = func (*RouteConflict) () {}
+    for _, r := range this.ruleOrder {
+        for m, route := range this.rules[r] {
+            if r == rule && (method == "" || m == method) {
+                continue
+            }
+            next.handle(r, m, route)
+        }
+    }
+    for name, r := range this.names {
+        if _, ok := next.rules[r]; ok || r != rule {
+            next.names[name] = r
+        }
+    }
+
+    this.Lock()
+    defer this.Unlock()
+    this.rules, this.ruleOrder, this.builder, this.names = next.rules, next.ruleOrder, next.builder, next.names
+    this.static, this.staticRules = next.static, next.staticRules
+    this.dynamic, this.trie, this.order = next.dynamic, next.trie, next.order
+    this.conflicts = next.conflicts
+    return true
+}
@@ -0,0 +1,61 @@
+package httputil_test
+
+import (
+    "testing"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+func TestReload(t *testing.T) () {
+    text := func (s string) (http.Handler) {
+        return http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () { fmt.Fprint(w, s) })
+    }
+    router := httputil.NewMultiHostRouter()
+    router.Handle(`^a\.example\.com$`, "/items/<id:int>", httputil.METHOD_GET, text("get"))
+    router.Handle(`^a\.example\.com$`, "/items/<id:int>", httputil.METHOD_PUT, text("put"))
+    router.Handle(`^a\.example\.com$`, "/about", httputil.METHOD_GET, text("about"))
+    router.Handle(`^b\.example\.com$`, "/", httputil.METHOD_GET, text("b"))
+
+    get := func (method, url string) (int, string) {
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+        return w.Code, w.Body.String()
+    }
+
+    a := router.AddRouter(`^a\.example\.com$`)
+    if !a.Unhandle("/items/<id:int>", httputil.METHOD_PUT) || a.Unhandle("/items/<id:int>", httputil.METHOD_PUT) {
+        t.Fatalf("PUT /items/<id:int> must be removed once\n")
+    }
+    if code, _ := get(httputil.METHOD_PUT, "http://a.example.com/items/1"); code != http.StatusMethodNotAllowed {
+        t.Fatalf("PUT must reply 405, but got %d\n", code)
+    }
+    if code, body := get(httputil.METHOD_GET, "http://a.example.com/items/1"); code != http.StatusOK || body != "get" {
+        t.Fatalf("GET must still reply, but got %d %q\n", code, body)
+    }
+    a.Unhandle("/items/<id:int>", "")
+    if code, _ := get(httputil.METHOD_GET, "http://a.example.com/items/1"); code != http.StatusNotFound {
+        t.Fatalf("removed rule must reply 404, but got %d\n", code)
+    }
+    if _, body := get(httputil.METHOD_GET, "http://a.example.com/about"); body != "about" {
+        t.Fatalf("static rule must survive, but got %q\n", body)
+    }
+
+    if !router.RemoveRouter(`^b\.example\.com$`) {
+        t.Fatalf("router of b must be removed\n")
+    }
+    if code, _ := get(httputil.METHOD_GET, "http://b.example.com/"); code != http.StatusNotAcceptable {
+        t.Fatalf("removed host must reply 406, but got %d\n", code)
+    }
+
+    next := httputil.NewMultiHostRouter()
+    next.Handle(`^c\.example\.com$`, "/", httputil.METHOD_GET, text("c"))
+    router.Swap(next)
+    if code, _ := get(httputil.METHOD_GET, "http://a.example.com/about"); code != http.StatusNotAcceptable {
+        t.Fatalf("swapped out host must reply 406, but got %d\n", code)
+    }
+    if _, body := get(httputil.METHOD_GET, "http://c.example.com/"); body != "c" {
+        t.Fatalf("swapped in host must reply, but got %q\n", body)
+    }
+}
@@ -0,0 +1,139 @@
+package httputil
+
+import (
+    "bytes"
+    "encoding/json"
+    "encoding/xml"
+    "fmt"
+    "html/template"
+    "io"
+    "net/http"
+    "strings"
+    "sync"
+)
+
+/*
+    Render(w, r, http.StatusOK, user)
+    // JSON, XML or text as the client prefers
+
+    Render(w, r, http.StatusOK, &HTML{ Template: tmpl, Name: "user.html", Data: user })
+    // also HTML, other formats render Data
+
+    The value is rendered before anything is written, failures reply 500
+    and nothing acceptable replies 406, both with WriteError. Bodies are
+    UTF-8.
+//*/
+
+// RenderFunc writes value in the media type it is registered for.
+type RenderFunc func (w io.Writer, value interface{}) (error)
+
+// HTML is a value rendered by a template for HTML clients.
+type HTML struct {
+    Template    *template.Template
+    // template to execute, the root template when empty
+    Name        string
+    Data        interface{}
+}
+
+type renderer struct {
+    mediaType   string
+    render      RenderFunc
+}
+
+var (
+    renderersLock   sync.RWMutex
+    // in order of preference
+    renderers       = []renderer{
+        { "application/json", renderJSON },
+        { "application/xml", renderXML },
+        { "text/xml", renderXML },
+        { "text/plain", renderText },
+    }
+)
+
+// RegisterRenderer adds or replaces the renderer of a media type, new
+// types are preferred least.
+func RegisterRenderer(mediaType string, f RenderFunc) () {
+    renderersLock.Lock()
+    defer renderersLock.Unlock()
+    for i := range renderers {
+        if renderers[i].mediaType == mediaType {
+            renderers[i].render = f
+            return
+        }
+    }
+    renderers = append(renderers, renderer{ mediaType, f })
+}
+
+func renderJSON(w io.Writer, value interface{}) (error) { return json.NewEncoder(w).Encode(value) }
+func renderXML(w io.Writer, value interface{}) (error) {
+    if _, err := io.WriteString(w, xml.Header); err != nil {
+        return err
+    }
+    return xml.NewEncoder(w).Encode(value)
+}
+func renderText(w io.Writer, value interface{}) (error) {
+    _, err := fmt.Fprint(w, value)
+    return err
+}
+func renderHTML(w io.Writer, value interface{}) (error) {
+    h := value.(*HTML)
+    if h.Name == "" {
+        return h.Template.Execute(w, h.Data)
+    }
+    return h.Template.ExecuteTemplate(w, h.Name, h.Data)
+}
+
+// Render writes value with status in the media type the request prefers.
+func Render(w http.ResponseWriter, r *http.Request, status int, value interface{}) (error) {
+    renderersLock.RLock()
+    offers := append([]renderer(nil), renderers...)
+    renderersLock.RUnlock()
+    html, isHTML := value.(*HTML)
+    if isHTML {
+        offers = append([]renderer{{ "text/html", renderHTML }}, offers...)
+        value = html.Data
+    }
+    mediaTypes := make([]string, len(offers))
+    for i, offer := range offers {
+        mediaTypes[i] = offer.mediaType
+    }
+
+    w.Header().Add(HEADER_VARY, HEADER_ACCEPT)
+    mediaType, ok := ResolveAccept(r).Negotiate(mediaTypes...)
+    if charsets := ResolveAcceptCharset(r); ok && len(charsets) > 0 {
+        _, ok = charsets.Negotiate("utf-8")
+    }
+    if !ok {
+        WriteError(w, r, NewError(http.StatusNotAcceptable, "available: " + strings.Join(mediaTypes, ", ")))
+        return nil
+    }
+
+    var buf bytes.Buffer
+    for _, offer := range offers {
+        if offer.mediaType != mediaType {
+            continue
+        }
+        rendered := value
+        if isHTML && offer.mediaType == "text/html" {
+            rendered = html
+        }
+        if err := offer.render(&buf, rendered); err != nil {
+            WriteError(w, r, &Error{ Status: http.StatusInternalServerError, Err: err })
+            return err
+        }
+        break
+    }
+
+    ct := mediaType + "; charset=utf-8"
+    if rw, ok := w.(ResponseWriter); ok {
+        rw.SetContentType(ct)
+    }
+    w.Header().Set(HEADER_CONTENT_TYPE, ct)
+    w.WriteHeader(status)
+    if r.Method == METHOD_HEAD {
+        return nil
+    }
+    _, err := w.Write(buf.Bytes())
+    return err
+}
@@ -0,0 +1,50 @@
+package httputil_test
+
+import (
+    "encoding/xml"
+    "html/template"
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+type testRenderUser struct {
+    XMLName struct{}    `json:"-" xml:"user"`
+    Name    string      `json:"name" xml:"name"`
+}
+func (this testRenderUser) String() (string) { return "user " + this.Name }
+
+func TestRender(t *testing.T) () {
+    items := httputil.ParseAccept("text/*;q=0.5, application/json;q=0.8, text/html, */*;q=0.1, application/xml;q=0")
+    if items[0].Value != "text/html" || items.Quality("text/plain") != 0.5 || items.Quality("image/png") != 0.1 || items.Quality("application/xml") != 0 {
+        t.Fatalf("unexpected accept items %+v\n", items)
+    }
+    if best, _ := items.Negotiate("application/xml", "text/plain", "application/json"); best != "application/json" {
+        t.Fatalf("application/json must be preferred, but got %q\n", best)
+    }
+
+    tmpl := template.Must(template.New("user").Parse(`<b>{{.Name}}</b>`))
+    user := testRenderUser{ Name: "ann" }
+    for _, io := range []struct {
+        accept  string
+        value   interface{}
+        code    int
+        ct      string
+        body    string
+    }{
+        { "", user, http.StatusOK, "application/json; charset=utf-8", "{\"name\":\"ann\"}\n" },
+        { "text/plain", user, http.StatusOK, "text/plain; charset=utf-8", "user ann" },
+        { "application/xml", user, http.StatusOK, "application/xml; charset=utf-8", xml.Header + "<user><name>ann</name></user>" },
+        { "text/html, */*;q=0.1", &httputil.HTML{ Template: tmpl, Data: user }, http.StatusOK, "text/html; charset=utf-8", "<b>ann</b>" },
+        { "text/html", user, http.StatusNotAcceptable, "", "" },
+    } {
+        req := httptest.NewRequest(httputil.METHOD_GET, "http://example.com/", nil)
+        req.Header.Set(httputil.HEADER_ACCEPT, io.accept)
+        w := httptest.NewRecorder()
+        httputil.Render(w, req, http.StatusOK, io.value)
+        if w.Code != io.code || io.ct != "" && (w.Header().Get(httputil.HEADER_CONTENT_TYPE) != io.ct || w.Body.String() != io.body) {
+            t.Fatalf("Accept %q must reply %d %q %q, but got %d %q %q\n", io.accept, io.code, io.ct, io.body, w.Code, w.Header().Get(httputil.HEADER_CONTENT_TYPE), w.Body.String())
+        }
+    }
+}
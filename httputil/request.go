@@ -2,12 +2,11 @@
 package httputil
 
 import (
+    "net"
     "net/http"
     "strings"
     "fmt"
     "bytes"
-    "strconv"
-    "sort"
 )
 
 // Get the content type.
@@ -21,30 +20,128 @@ func ResolveContentType(req *http.Request) string {
     return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
 }
 
+// ResolveScheme returns "https" for TLS connections, "http" otherwise.
+// X-Forwarded-Proto is ignored, see TrustedProxies.Scheme.
+func ResolveScheme(req *http.Request) string {
+    return TrustedProxies(nil).Scheme(req)
+}
+
+// TrustedProxies are the networks of proxies whose X-Forwarded-For and
+// X-Real-Ip headers are believed.
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies parses addresses and CIDR networks.
+func ParseTrustedProxies(addrs ...string) (TrustedProxies, error) {
+    var proxies TrustedProxies
+    for _, addr := range addrs {
+        if strings.Contains(addr, "/") {
+            _, network, err := net.ParseCIDR(addr)
+            if err != nil {
+                return nil, fmt.Errorf("httputil: invalid proxy network %q: %v", addr, err)
+            }
+            proxies = append(proxies, network)
+            continue
+        }
+        ip := net.ParseIP(addr)
+        if ip == nil {
+            return nil, fmt.Errorf("httputil: invalid proxy address %q", addr)
+        }
+        bits := 8*net.IPv6len
+        if ip4 := ip.To4(); ip4 != nil {
+            ip, bits = ip4, 8*net.IPv4len
+        }
+        proxies = append(proxies, &net.IPNet{ IP: ip, Mask: net.CIDRMask(bits, bits) })
+    }
+    return proxies, nil
+}
+func (this TrustedProxies) Contains(ip net.IP) bool {
+    for _, network := range this {
+        if network.Contains(ip) {
+            return true
+        }
+    }
+    return false
+}
+
+// remoteAddr returns the peer address and whether it is a trusted proxy.
+func (this TrustedProxies) remoteAddr(req *http.Request) (string, bool) {
+    addr, _, err := net.SplitHostPort(req.RemoteAddr)
+    if err != nil {
+        addr = req.RemoteAddr
+    }
+    ip := net.ParseIP(addr)
+    return addr, ip != nil && this.Contains(ip)
+}
+
+// Scheme returns "http" or "https", as told by the first X-Forwarded-Proto
+// value when a trusted proxy set one.
+func (this TrustedProxies) Scheme(req *http.Request) string {
+    if _, trusted := this.remoteAddr(req); trusted {
+        if proto := req.Header.Get(XHEADER_FORWARDED_PROTO); proto != "" {
+            return strings.ToLower(strings.TrimSpace(strings.Split(proto, ",")[0]))
+        }
+    }
+    if req.TLS != nil {
+        return "https"
+    }
+    return "http"
+}
+
+// ClientIP returns the address of the client. X-Forwarded-For is walked
+// from the right as long as the hops are trusted proxies, the first
+// untrusted hop is the client.
+func (this TrustedProxies) ClientIP(req *http.Request) string {
+    addr, trusted := this.remoteAddr(req)
+    if !trusted {
+        return addr
+    }
+    var hops []string
+    for _, value := range req.Header.Values(XHEADER_FORWARDED_FOR) {
+        hops = append(hops, strings.Split(value, ",")...)
+    }
+    if len(hops) <= 0 {
+        if real := net.ParseIP(strings.TrimSpace(req.Header.Get(XHEADER_REAL_IP))); real != nil {
+            return real.String()
+        }
+    }
+    for i := len(hops)-1; i >= 0; i-- {
+        ip := net.ParseIP(strings.TrimSpace(hops[i]))
+        if ip == nil {
+            break
+        }
+        addr = ip.String()
+        if !this.Contains(ip) {
+            break
+        }
+    }
+    return addr
+}
+
 // ResolveFormat maps the request's Accept MIME type declaration to
 // a Request.Format attribute, specifically "html", "xml", "json", or "txt",
 // returning a default of "html" when Accept header cannot be mapped to a
-// value above.
+// value above. Ties of q values go to the formats in that order.
 func ResolveFormat(req *http.Request) string {
-    accept := req.Header.Get("accept")
-
-    switch {
-    case accept == "",
-        strings.HasPrefix(accept, "*/*"), // */
-        strings.Contains(accept, "application/xhtml"),
-        strings.Contains(accept, "text/html"):
+    offer, ok := ResolveAccept(req).Negotiate(
+        "text/html", "application/xhtml+xml",
+        "application/xml", "text/xml",
+        "text/plain",
+        "application/json", "text/javascript",
+    )
+    if !ok {
         return "html"
-    case strings.Contains(accept, "application/xml"),
-        strings.Contains(accept, "text/xml"):
-        return "xml"
-    case strings.Contains(accept, "text/plain"):
-        return "txt"
-    case strings.Contains(accept, "application/json"),
-        strings.Contains(accept, "text/javascript"):
-        return "json"
     }
+    return formatOfMediaType[offer]
+}
 
-    return "html"
+var formatOfMediaType = map[string]string{
+    "text/html": "html",
+    "application/xhtml+xml": "html",
+    "application/xml": "xml",
+    "text/xml": "xml",
+    "text/plain": "txt",
+    "application/json": "json",
+    "text/javascript": "json",
 }
 
 // AcceptLanguage is a single language from the Accept-Language HTTP header.
@@ -78,28 +175,14 @@ func (this AcceptLanguages) String() string {
 // See the HTTP header fields specification
 // (http://www.w3.org/Protocols/rfc2616/rfc2616-sec14.html#sec14.4) for more details.
 func ResolveAcceptLanguage(req *http.Request) AcceptLanguages {
-    header := req.Header.Get("Accept-Language")
-    if header == "" {
+    // tags keep their case, e.g. "en-US"
+    items := parseAccept(req.Header.Get(HEADER_ACCEPT_LANGUAGE), false)
+    if len(items) <= 0 {
         return nil
     }
-
-    acceptLanguageHeaderValues := strings.Split(header, ",")
-    acceptLanguages := make(AcceptLanguages, len(acceptLanguageHeaderValues))
-
-    for i, languageRange := range acceptLanguageHeaderValues {
-        if qualifiedRange := strings.Split(languageRange, ";q="); len(qualifiedRange) == 2 {
-            quality, error := strconv.ParseFloat(qualifiedRange[1], 32)
-            if error != nil {
-                //WARN.Printf("Detected malformed Accept-Language header quality in '%s', assuming quality is 1\n", languageRange)
-                acceptLanguages[i] = AcceptLanguage{qualifiedRange[0], 1}
-            } else {
-                acceptLanguages[i] = AcceptLanguage{qualifiedRange[0], float32(quality)}
-            }
-        } else {
-            acceptLanguages[i] = AcceptLanguage{languageRange, 1}
-        }
+    acceptLanguages := make(AcceptLanguages, len(items))
+    for i, item := range items {
+        acceptLanguages[i] = AcceptLanguage{item.Value, item.Quality}
     }
-
-    sort.Sort(acceptLanguages)
     return acceptLanguages
 }
@@ -0,0 +1,197 @@
+package httputil
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "fmt"
+    "net/http"
+    "strings"
+)
+
+/*
+    chain := NewChainHandler(
+        NewRequestID(nil),          // first, the others read the context
+        NewAccessLog(nil),
+        NewRecovery(logger, nil, false),
+    )
+
+    id := RequestID(r.Context())
+    trace, ok := TraceFromContext(r.Context())
+    outgoing.Header.Set(XHEADER_TRACEPARENT, trace.Traceparent())
+
+    Incoming IDs and W3C trace headers are kept when valid, otherwise new
+    ones are generated. Each request gets a new span of the trace, the
+    caller's span becomes its parent. See https://www.w3.org/TR/trace-context/
+//*/
+
+// maximum length of accepted request IDs and tracestate values
+const (
+    maxRequestID = 128
+    maxTracestate = 512
+)
+
+// TraceContext is the W3C trace context of a request.
+type TraceContext struct {
+    // 32 hex digits
+    TraceID     string
+    // span of the caller, empty when the trace starts here
+    ParentID    string
+    // 16 hex digits, span of this request
+    SpanID      string
+    Flags       byte
+    // vendor data passed through unchanged
+    State       string
+}
+
+// Traceparent formats the header propagating the trace below this span.
+func (this *TraceContext) Traceparent() (string) {
+    return fmt.Sprintf("00-%s-%s-%02x", this.TraceID, this.SpanID, this.Flags)
+}
+func (this *TraceContext) Sampled() (bool) { return this.Flags & 0x01 != 0 }
+
+// ParseTraceparent reads a version 00 traceparent header, SpanID holds
+// the span of the sender.
+func ParseTraceparent(header string) (trace TraceContext, ok bool) {
+    parts := strings.Split(strings.TrimSpace(header), "-")
+    if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || parts[0] == "00" && len(parts) != 4 {
+        return
+    }
+    if !isLowerHex(parts[0]) || !isLowerHex(parts[1]) || !isLowerHex(parts[2]) || !isLowerHex(parts[3]) {
+        return
+    }
+    if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+        return
+    }
+    if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+        return
+    }
+    flags, _ := hex.DecodeString(parts[3])
+    return TraceContext{
+        TraceID: parts[1],
+        SpanID: parts[2],
+        Flags: flags[0],
+    }, true
+}
+
+func isLowerHex(s string) (bool) {
+    for i := 0; i < len(s); i++ {
+        if (s[i] < '0' || s[i] > '9') && (s[i] < 'a' || s[i] > 'f') {
+            return false
+        }
+    }
+    return true
+}
+
+// randomHex returns n random bytes in hex.
+func randomHex(n int) (string) {
+    buf := make([]byte, n)
+    if _, err := rand.Read(buf); err != nil {
+        panic(err)
+    }
+    return hex.EncodeToString(buf)
+}
+
+func validRequestID(id string) (bool) {
+    if id == "" || len(id) > maxRequestID {
+        return false
+    }
+    for i := 0; i < len(id); i++ {
+        if id[i] <= ' ' || id[i] >= 0x7f {
+            return false
+        }
+    }
+    return true
+}
+
+//
+
+type correlationKey struct{}
+type correlation struct {
+    id      string
+    trace   TraceContext
+}
+
+// RequestID returns the ID NewRequestID gave the request of ctx.
+func RequestID(ctx context.Context) (string) {
+    if c, ok := ctx.Value(correlationKey{}).(*correlation); ok {
+        return c.id
+    }
+    return ""
+}
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+    if c, ok := ctx.Value(correlationKey{}).(*correlation); ok {
+        return c.trace, true
+    }
+    return TraceContext{}, false
+}
+
+// requestID falls back to the response header for middlewares running
+// before NewRequestID, the client's header is only used when no
+// middleware assigned an ID.
+func requestID(w http.ResponseWriter, r *http.Request) (string) {
+    if id := RequestID(r.Context()); id != "" {
+        return id
+    }
+    if id := w.Header().Get(XHEADER_REQUEST_ID); id != "" {
+        return id
+    }
+    return r.Header.Get(XHEADER_REQUEST_ID)
+}
+
+type RequestIDOptions struct {
+    // ignore IDs and trace headers sent by clients, e.g. on public edges
+    Untrusted   bool
+    // new request IDs, 16 random bytes in hex by default
+    Generate    func () (string)
+}
+
+func NewRequestID(opts *RequestIDOptions) (Handler) {
+    this := &RequestIDHandler{}
+    if opts != nil {
+        this.RequestIDOptions = *opts
+    }
+    if this.Generate == nil {
+        this.Generate = func () (string) { return randomHex(16) }
+    }
+    return this
+}
+
+type RequestIDHandler struct {
+    RequestIDOptions
+}
+
+func (this *RequestIDHandler) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+    c := &correlation{}
+    var ok bool
+    if !this.Untrusted {
+        c.id = r.Header.Get(XHEADER_REQUEST_ID)
+        c.trace, ok = ParseTraceparent(r.Header.Get(XHEADER_TRACEPARENT))
+    }
+    if !validRequestID(c.id) {
+        c.id = this.Generate()
+    }
+    if ok {
+        c.trace.ParentID = c.trace.SpanID
+        if state := r.Header.Get(XHEADER_TRACESTATE); len(state) <= maxTracestate {
+            c.trace.State = state
+        }
+    } else {
+        c.trace = TraceContext{ TraceID: randomHex(16) }
+    }
+    c.trace.SpanID = randomHex(8)
+
+    rw, isRW := w.(ResponseWriter)
+    if !isRW {
+        rw = NewResponseWriter(w)
+    }
+    rw.Before(func (rw ResponseWriter) () {
+        header := rw.Header()
+        header.Set(XHEADER_REQUEST_ID, c.id)
+        header.Set(XHEADER_TRACEPARENT, c.trace.Traceparent())
+        if c.trace.State != "" {
+            header.Set(XHEADER_TRACESTATE, c.trace.State)
+        }
+    })
+    next(rw, r.WithContext(context.WithValue(r.Context(), correlationKey{}, c)))
+}
@@ -0,0 +1,60 @@
+package httputil_test
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+func TestRequestID(t *testing.T) () {
+    var id string
+    var trace httputil.TraceContext
+    chain := httputil.NewChainHandler(httputil.NewRequestID(nil), httputil.HandlerFunc(func (w http.ResponseWriter, r *http.Request, next http.HandlerFunc) () {
+        id = httputil.RequestID(r.Context())
+        trace, _ = httputil.TraceFromContext(r.Context())
+        w.WriteHeader(http.StatusNoContent)
+    }))
+
+    req := httptest.NewRequest(httputil.METHOD_GET, "http://example.com/", nil)
+    req.Header.Set(httputil.XHEADER_REQUEST_ID, "abc-123")
+    req.Header.Set(httputil.XHEADER_TRACEPARENT, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+    req.Header.Set(httputil.XHEADER_TRACESTATE, "congo=t61rcWkgMzE")
+    w := httptest.NewRecorder()
+    chain.ServeHTTP(w, req)
+    if id != "abc-123" || trace.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || trace.ParentID != "00f067aa0ba902b7" || !trace.Sampled() {
+        t.Fatalf("incoming ids must be kept, but got %q %+v\n", id, trace)
+    }
+    if trace.SpanID == trace.ParentID || len(trace.SpanID) != 16 {
+        t.Fatalf("request must get a new span, but got %q\n", trace.SpanID)
+    }
+    if w.Header().Get(httputil.XHEADER_REQUEST_ID) != id || w.Header().Get(httputil.XHEADER_TRACEPARENT) != trace.Traceparent() || w.Header().Get(httputil.XHEADER_TRACESTATE) != "congo=t61rcWkgMzE" {
+        t.Fatalf("ids must be echoed, but got %v\n", w.Header())
+    }
+
+    req = httptest.NewRequest(httputil.METHOD_GET, "http://example.com/", nil)
+    req.Header.Set(httputil.XHEADER_REQUEST_ID, "bad id")
+    req.Header.Set(httputil.XHEADER_TRACEPARENT, "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+    chain.ServeHTTP(httptest.NewRecorder(), req)
+    if len(id) != 32 || trace.ParentID != "" || len(trace.TraceID) != 32 || trace.TraceID == "00000000000000000000000000000000" {
+        t.Fatalf("invalid ids must be replaced, but got %q %+v\n", id, trace)
+    }
+
+    // an access log running first records the assigned ID, not the client's
+    var sink bytes.Buffer
+    chain = httputil.NewChainHandler(
+        httputil.NewAccessLog(&httputil.AccessLogOptions{ Sink: &sink }),
+        httputil.NewRequestID(&httputil.RequestIDOptions{ Untrusted: true, Generate: func () (string) { return "server-1" } }),
+        httputil.HandlerFunc(func (w http.ResponseWriter, r *http.Request, next http.HandlerFunc) () {
+            w.WriteHeader(http.StatusNoContent)
+        }),
+    )
+    req = httptest.NewRequest(httputil.METHOD_GET, "http://example.com/", nil)
+    req.Header.Set(httputil.XHEADER_REQUEST_ID, "forged")
+    chain.ServeHTTP(httptest.NewRecorder(), req)
+    if !strings.Contains(sink.String(), `"request_id":"server-1"`) {
+        t.Fatalf("access log must record the assigned id, but got %q\n", sink.String())
+    }
+}
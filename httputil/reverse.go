@@ -0,0 +1,161 @@
+package httputil
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "net/url"
+    "strings"
+)
+
+/*
+    router.HandleFunc("/users/<id:int>/<:re:[a-z]+>", METHOD_GET, showUser)
+    router.Name("user", "/users/<id:int>/<:re:[a-z]+>")
+
+    router.URL("user", []string{"posts"}, map[string]string{"id": "7", "page": "2"})
+    // "/users/7/posts?page=2"
+
+    Anonymous parts take args in order, named parts take kwargs, the
+    kwargs left over are appended as query string. A rule may be used
+    as name directly.
+//*/
+
+var ErrUnknownRoute = errors.New("httputil: unknown route")
+
+// Name gives rule a name for URL building.
+func (this *Router) Name(name, rule string) () {
+    if this.root != nil {
+        this.root.Name(name, this.prefix + rule)
+        return
+    }
+    this.editLock.Lock()
+    defer this.editLock.Unlock()
+    this.Lock()
+    defer this.Unlock()
+    this.names[name] = rule
+}
+func (this *Router) lookupRule(name string) (rule string, ok bool) {
+    if rule, ok = this.names[name]; ok {
+        return
+    }
+    if _, ok = this.builder[name]; ok {
+        return name, true
+    }
+    return
+}
+
+// URL builds the path of the named route.
+func (this *Router) URL(name string, args []string, kwargs map[string]string) (string, error) {
+    if root := this.root; root != nil {
+        // rules given as name are relative to the group
+        root.RLock()
+        _, ok := root.lookupRule(name)
+        root.RUnlock()
+        if !ok {
+            name = this.prefix + name
+        }
+        return root.URL(name, args, kwargs)
+    }
+    this.RLock()
+    defer this.RUnlock()
+    rule, ok := this.lookupRule(name)
+    if !ok {
+        return "", fmt.Errorf("%w %q", ErrUnknownRoute, name)
+    }
+    return this.reverse(rule, args, kwargs)
+}
+
+func (this *Router) reverse(rule string, args []string, kwargs map[string]string) (string, error) {
+    builder, ok := this.builder[rule]
+    if !ok {
+        return "", fmt.Errorf("%w %q", ErrUnknownRoute, rule)
+    }
+    var (
+        b       bytes.Buffer
+        used    = make(map[string]bool)
+        n       int
+    )
+    for _, part := range builder {
+        if part.static {
+            b.WriteString(part.key)
+            continue
+        }
+        var value string
+        if part.key == "" {
+            if n >= len(args) {
+                return "", fmt.Errorf("httputil: rule %q needs argument #%d", rule, n)
+            }
+            value, n = args[n], n+1
+        } else {
+            if value, ok = kwargs[part.key]; !ok {
+                return "", fmt.Errorf("httputil: rule %q needs argument %q", rule, part.key)
+            }
+            used[part.key] = true
+        }
+        if !part.rexp.MatchString(value) {
+            return "", fmt.Errorf("httputil: rule %q: value %q does not match filter %q", rule, value, part.filter)
+        }
+        b.WriteString(escapePathValue(value, part.filter == "path"))
+    }
+    if n < len(args) {
+        return "", fmt.Errorf("httputil: rule %q takes %d arguments, got %d", rule, n, len(args))
+    }
+    query := url.Values{}
+    for key, value := range kwargs {
+        if !used[key] {
+            query.Set(key, value)
+        }
+    }
+    if len(query) > 0 {
+        b.WriteString("?" + query.Encode())
+    }
+    return b.String(), nil
+}
+
+// "path" values keep their slashes, other values are a single segment.
+func escapePathValue(value string, keepSlash bool) (string) {
+    if !keepSlash {
+        return url.PathEscape(value)
+    }
+    segments := strings.Split(value, "/")
+    for i, segment := range segments {
+        segments[i] = url.PathEscape(segment)
+    }
+    return strings.Join(segments, "/")
+}
+
+//
+
+// Name names a rule of the router of host_pattern.
+func (this *MultiHostRouter) Name(host_pattern, name, rule string) () {
+    this.AddRouter(host_pattern).Name(name, rule)
+}
+
+// URL builds the absolute URL of the named route served on host, an
+// empty host means DefaultHost.
+func (this *MultiHostRouter) URL(host, name string, args []string, kwargs map[string]string) (string, error) {
+    if host == "" {
+        host = this.DefaultHost
+    }
+    matches, _ := this.findAllRouters(this.load(), "", host)
+    for _, m := range matches {
+        router := m.router
+        router.RLock()
+        rule, ok := router.lookupRule(name)
+        if !ok {
+            router.RUnlock()
+            continue
+        }
+        path, err := router.reverse(rule, args, kwargs)
+        router.RUnlock()
+        if err != nil {
+            return "", err
+        }
+        schema := this.DefaultSchema
+        if schema == "" {
+            schema = "http"
+        }
+        return fmt.Sprintf("%s://%s%s", schema, host, path), nil
+    }
+    return "", fmt.Errorf("%w %q on host %q", ErrUnknownRoute, name, host)
+}
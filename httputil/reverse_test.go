@@ -0,0 +1,83 @@
+package httputil_test
+
+import (
+    "errors"
+    "testing"
+    "net/http"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+func TestURL(t *testing.T) () {
+    router := httputil.NewRouter()
+    for _, rule := range []string{
+        "/users/<id:int>/<:re:[a-z]+>",
+        "/files/<name:path>",
+        "/tags/<tag>",
+        "/search/<q:re:.+>",
+    } {
+        router.Handle(rule, httputil.METHOD_GET, http.NotFoundHandler())
+    }
+    router.Name("user", "/users/<id:int>/<:re:[a-z]+>")
+    router.Name("file", "/files/<name:path>")
+
+    for _, io := range []struct {
+        name    string
+        args    []string
+        kwargs  map[string]string
+        url     string
+    }{
+        { "user", []string{ "posts" }, map[string]string{ "id": "7" }, "/users/7/posts" },
+        { "user", []string{ "posts" }, map[string]string{ "id": "7", "page": "2", "q": "a b" }, "/users/7/posts?page=2&q=a+b" },
+        { "file", nil, map[string]string{ "name": "docs/a b/c?.txt" }, "/files/docs/a%20b/c%3F.txt" },
+        { "/tags/<tag>", nil, map[string]string{ "tag": "a b?" }, "/tags/a%20b%3F" },
+        // a segment matching slashes still escapes them, only path keeps them
+        { "/search/<q:re:.+>", nil, map[string]string{ "q": "a/b" }, "/search/a%2Fb" },
+    } {
+        if url, err := router.URL(io.name, io.args, io.kwargs); err != nil || url != io.url {
+            t.Fatalf("URL(%q, %q, %v) must be %q, but got %q %v\n", io.name, io.args, io.kwargs, io.url, url, err)
+        }
+    }
+
+    for _, io := range []struct {
+        name    string
+        args    []string
+        kwargs  map[string]string
+    }{
+        { "user", []string{ "posts" }, map[string]string{ "id": "abc" } },
+        { "user", []string{ "Posts" }, map[string]string{ "id": "7" } },
+        { "/tags/<tag>", nil, map[string]string{ "tag": "a/b" } },
+        { "user", nil, map[string]string{ "id": "7" } },
+        { "user", []string{ "posts", "more" }, map[string]string{ "id": "7" } },
+        { "user", []string{ "posts" }, nil },
+    } {
+        if url, err := router.URL(io.name, io.args, io.kwargs); err == nil {
+            t.Fatalf("URL(%q, %q, %v) must fail, but got %q\n", io.name, io.args, io.kwargs, url)
+        }
+    }
+    if _, err := router.URL("nobody", nil, nil); !errors.Is(err, httputil.ErrUnknownRoute) {
+        t.Fatalf("unknown names must fail with ErrUnknownRoute, but got %v\n", err)
+    }
+}
+
+func TestMultiHostURL(t *testing.T) () {
+    router := httputil.NewMultiHostRouter()
+    router.DefaultHost = "www.example.com"
+    router.Handle("www.example.com", "/users/<id:int>", httputil.METHOD_GET, http.NotFoundHandler())
+    router.Name("www.example.com", "user", "/users/<id:int>")
+    router.Handle("{tenant}.example.org", "/home", httputil.METHOD_GET, http.NotFoundHandler())
+    router.Name("{tenant}.example.org", "home", "/home")
+
+    if url, err := router.URL("", "user", nil, map[string]string{ "id": "7" }); err != nil || url != "http://www.example.com/users/7" {
+        t.Fatalf("URL on DefaultHost must be %q, but got %q %v\n", "http://www.example.com/users/7", url, err)
+    }
+    router.DefaultSchema = "https"
+    if url, err := router.URL("acme.example.org", "home", nil, nil); err != nil || url != "https://acme.example.org/home" {
+        t.Fatalf("URL on a tenant must be %q, but got %q %v\n", "https://acme.example.org/home", url, err)
+    }
+    if _, err := router.URL("acme.example.org", "user", nil, map[string]string{ "id": "7" }); !errors.Is(err, httputil.ErrUnknownRoute) {
+        t.Fatalf("names of other hosts must fail with ErrUnknownRoute, but got %v\n", err)
+    }
+    if _, err := router.URL("", "user", nil, map[string]string{ "id": "x" }); err == nil {
+        t.Fatalf("values failing the filter must fail\n")
+    }
+}
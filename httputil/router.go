@@ -4,9 +4,11 @@ package httputil
 import (
     "net/http"
     "regexp"
+    "strconv"
     "strings"
     "fmt"
     "sync"
+    "sync/atomic"
     "github.com/princeofdatamining/golib/strutil"
 )
 
@@ -127,7 +129,11 @@ type errorHandler struct {
     error   string
     code    int
 }
-func (this *errorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) () { http.Error(w, this.error, this.code) }
+// ServeHTTP writes the error with WriteError, a leading status code in
+// the text is dropped from the detail.
+func (this *errorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) () {
+    WriteError(w, r, NewError(this.code, strings.TrimPrefix(this.error, strconv.Itoa(this.code) + " ")))
+}
 
 //
 
@@ -147,17 +153,24 @@ var _ http.Handler = NewMultiHostRouter()
 
 func NewMultiHostRouter() (*MultiHostRouter) {
     return &MultiHostRouter{
-        cached: make(map[string]*Router),
         DefaultSchema: "http",
     }
 }
+// Requests read the host table without locking, writers serialize on
+// the embedded mutex and publish a modified copy.
 type MultiHostRouter struct {
     sync.RWMutex
-    hosts           []*Router
-    defaults        *Router
-    cached          map[string]*Router
+    table           atomic.Value
     //
     wrapFunc        WrapperFunc
+    preflight       PreflightFunc
+    onError         ErrorFunc
+    // replaced as a whole, host routers get copies
+    statusHandlers  map[int]http.Handler
+    // copied to host routers when they are added
+    OnConflict      ConflictFunc
+    // proxies whose X-Forwarded-Proto picks the scheme of host patterns
+    TrustedProxies  TrustedProxies
     DefaultSchema   string
     DefaultHost     string
     UseDefaultHost  bool
@@ -167,10 +180,7 @@ func (this *MultiHostRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) (
     h.ServeHTTP(w, r)
 }
 func (this *MultiHostRouter) Handler(r *http.Request) (h http.Handler) {
-    this.RLock()
-    defer this.RUnlock()
-
-    routers, found := this.findAllRouters(r.Host)
+    matches, found := this.findAllRouters(this.load(), this.TrustedProxies.Scheme(r), r.Host)
     if !found {
         if this.UseDefaultHost && this.DefaultHost != r.Host {
             url := *r.URL
@@ -178,35 +188,106 @@ func (this *MultiHostRouter) Handler(r *http.Request) (h http.Handler) {
             url.Host = this.DefaultHost
             return this.wrapped(http.RedirectHandler(url.String(), http.StatusTemporaryRedirect))
         } else {
-            return this.wrapped(ErrorHandler("406 Unknown Host", http.StatusNotAcceptable))
+            return this.wrapped(http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+                this.WriteError(w, r, NewError(http.StatusNotAcceptable, "unknown host"))
+            }))
         }
     }
-    for _, router := range routers {
-        if h = router.match(r.URL.Path, r.Method); h != nil {
+    var (
+        allow       []string
+        allowRouter *Router
+    )
+    for _, m := range matches {
+        h, methods := m.router.match(r.URL.Path, r.Method, m.params)
+        if h != nil {
             return h
         }
+        if allow == nil {
+            allow, allowRouter = methods, m.router
+        }
+    }
+    if allow != nil {
+        return this.wrapped(allowRouter.methodNotAllowed(allow))
+    }
+    return this.wrapped(matches[0].router.errorHandler(NewError(http.StatusNotFound, "page not found")))
+}
+func (this *MultiHostRouter) load() (*hostTable) {
+    if table, ok := this.table.Load().(*hostTable); ok {
+        return table
     }
-    return this.wrapped(http.NotFoundHandler())
+    return &hostTable{}
 }
-func (this *MultiHostRouter) findAllRouters(host string) (routers []*Router, found bool) {
-    routers, found = this.findRouters(host)
+func (this *MultiHostRouter) findAllRouters(table *hostTable, scheme, host string) (matches []hostMatch, found bool) {
+    matches, found = table.findRouters(scheme, host)
     if found || !this.UseDefaultHost {
         return 
     }
-    return this.findRouters(this.DefaultHost)
+    return table.findRouters(scheme, this.DefaultHost)
+}
+
+// hostTable is not modified once published, except for its cache.
+type hostTable struct {
+    hosts           []*Router
+    defaults        *Router
+    cached          map[string]*Router
+    // scheme and Host header => matching routers, Host headers are client
+    // input so the cache is bounded and dropped when full
+    hostLock        sync.Mutex
+    hostCache       map[string][]hostMatch
+}
+func (this *hostTable) clone() (*hostTable) {
+    table := &hostTable{
+        hosts: append([]*Router(nil), this.hosts...),
+        defaults: this.defaults,
+        cached: make(map[string]*Router, len(this.cached)),
+    }
+    for pattern, router := range this.cached {
+        table.cached[pattern] = router
+    }
+    return table
+}
+func (this *hostTable) routers() (routers []*Router) {
+    routers = append(routers, this.hosts...)
+    if this.defaults != nil {
+        routers = append(routers, this.defaults)
+    }
+    return
+}
+
+const maxHostCache = 256
+
+func (this *hostTable) findRouters(scheme, hostport string) (matches []hostMatch, found bool) {
+    key := scheme + "://" + hostport
+    this.hostLock.Lock()
+    matches, found = this.hostCache[key]
+    this.hostLock.Unlock()
+    if found {
+        return matches, matches != nil
+    }
+    host, port := splitHostPort(hostport)
+    matches, found = this.matchRouters(scheme, strings.ToLower(host), port)
+    this.hostLock.Lock()
+    if this.hostCache == nil || len(this.hostCache) >= maxHostCache {
+        this.hostCache = make(map[string][]hostMatch)
+    }
+    this.hostCache[key] = matches
+    this.hostLock.Unlock()
+    return 
 }
-func (this *MultiHostRouter) findRouters(host string) (routers []*Router, found bool) {
-    host = strings.Split(host, ":")[0]
+// matchRouters returns the matching routers in precedence order, see
+// host.go.
+func (this *hostTable) matchRouters(scheme, host, port string) (matches []hostMatch, found bool) {
     for _, router := range this.hosts {
-        if router.matchHost(host) {
-            routers, found = append(routers, router), true
+        if params, ok := router.host.match(scheme, host, port); ok {
+            matches, found = append(matches, hostMatch{router, params}), true
         }
     }
     if router := this.defaults; !found && router != nil {
-        if router.matchHost(host) {
-            routers, found = append(routers, router), true
+        if params, ok := router.host.match(scheme, host, port); ok {
+            matches, found = append(matches, hostMatch{router, params}), true
         }
     }
+    sortHostMatches(matches)
     return 
 }
 func (this *MultiHostRouter) WrapFunc(f WrapperFunc) () { this.wrapFunc = f }
@@ -228,31 +309,41 @@ func (this *MultiHostRouter) HandleFunc (host_pattern, path_pattern, method stri
 func (this *MultiHostRouter) HandleFuncs(host_pattern, path_pattern string, methods map[string]ArgsHandler ) () {
     this.AddRouter(host_pattern).HandleFuncs(path_pattern, methods  )
 }
-func (this *MultiHostRouter) cacheRouter(host_pattern string) (router *Router, found bool) {
-    if this.cached == nil {
-        this.cached = make(map[string]*Router)
-    }
-    router, found = this.cached[host_pattern]
-    return 
-}
 func (this *MultiHostRouter) AddRouter(host_pattern string) (router *Router) {
-    this.Lock()
-    if router, found := this.cacheRouter(host_pattern); found {
-        this.Unlock()
+    if router, found := this.load().cached[host_pattern]; found {
         return router
     }
+    this.Lock()
     defer this.Unlock()
+    table := this.load()
+    if router, found := table.cached[host_pattern]; found {
+        return router
+    }
 
     router = newRouter(this, host_pattern)
-    //
-    if host_pattern == MATCH_HOST_ANY {
-        this.defaults = router
-    } else {
-        this.hosts = append(this.hosts, router)
-    }
-    this.cached[host_pattern] = router
+    this.store(table, host_pattern, router)
     return 
 }
+// store publishes a copy of table with router set for host_pattern, the
+// caller holds the lock.
+func (this *MultiHostRouter) store(table *hostTable, host_pattern string, router *Router) () {
+    table = table.clone()
+    old := table.cached[host_pattern]
+    switch {
+    case host_pattern == MATCH_HOST_ANY:
+        table.defaults = router
+    case old == nil:
+        table.hosts = append(table.hosts, router)
+    default:
+        for i, host := range table.hosts {
+            if host == old {
+                table.hosts[i] = router
+            }
+        }
+    }
+    table.cached[host_pattern] = router
+    this.table.Store(table)
+}
 
 //
 
@@ -260,48 +351,96 @@ func NewRouter() (*Router) {
     return &Router{
         rules: make(map[string]map[string]*Route),
         builder: make(map[string][]*builderPart),
+        names: make(map[string]string),
         static: make(map[string]map[string]*Route),
+        staticRules: make(map[string]string),
+        trie: newTrieNode(),
     }
 }
 type Router struct {
     sync.RWMutex
+    // serializes edits, Unhandle rebuilds the tables without blocking lookups
+    editLock    sync.Mutex
     rules       map[string]map[string]*Route
+    ruleOrder   []string
     builder     map[string][]*builderPart
+    names       map[string]string
     static      map[string]map[string]*Route
+    staticRules map[string]string
     dynamic     []*dynamicPart
+    trie        *trieNode
+    // registration order of dynamic rules
+    order       int
+    // match every dynamic rule by regexp, for benchmarks
+    regexpOnly  bool
     //
     parent      *MultiHostRouter
-    host_re     *regexp.Regexp
+    host        *hostPattern
     wrapFunc    WrapperFunc
+    preflight   PreflightFunc
+    onError     ErrorFunc
+    statusHandlers map[int]http.Handler
+    conflicts   []*RouteConflict
+    OnConflict  ConflictFunc
+    // groups register their prefixed rules on root
+    root        *Router
+    prefix      string
+    middlewares []Handler
 }
 type builderPart struct {
     key     string
     static  bool
+    filter  string
+    conf    string
+    // validates values when building URLs
+    rexp    *regexp.Regexp
 }
 type dynamicPart struct {
     pattern     string
     rexp        *regexp.Regexp
+    groups      int
     pairs       []*pairGetargsRule
 }
 type pairGetargsRule struct {
-    getargs     func (string) ([]string, map[string]string)
+    rule        string
+    order       int
+    // submatch index of the rule in the combined regexp, filter masks
+    // may hold groups of their own
+    group       int
+    getvalues   func (string) ([]string)
+    parts       []*builderPart
     targets     map[string]*Route
 }
 func newRouter(p *MultiHostRouter, host_pattern string) (*Router) {
     this := NewRouter()
     this.parent = p
-    this.host_re = regexp.MustCompile(host_pattern)
+    this.host = compileHostPattern(host_pattern)
     this.WrapFunc(p.wrapFunc)
+    this.preflight = p.preflight
+    this.onError = p.onError
+    for status, h := range p.statusHandlers {
+        if this.statusHandlers == nil {
+            this.statusHandlers = make(map[int]http.Handler)
+        }
+        this.statusHandlers[status] = h
+    }
+    this.OnConflict = p.OnConflict
     return this
 }
 func (this *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) () { this.Handler(r).ServeHTTP(w, r) }
 func (this *Router) Handler(r *http.Request) (h http.Handler) {
-    if h = this.match(r.URL.Path, r.Method); h != nil {
+    if this.root != nil {
+        return this.root.Handler(r)
+    }
+    h, allow := this.match(r.URL.Path, r.Method, nil)
+    switch {
+    case h != nil:
         return h
+    case allow != nil:
+        return this.wrapped(this.methodNotAllowed(allow))
     }
-    return this.wrapped(http.NotFoundHandler())
+    return this.wrapped(this.errorHandler(NewError(http.StatusNotFound, "page not found")))
 }
-func (this *Router) matchHost(host string) (bool) { return this.host_re.MatchString(host) }
 func getRouteByMethod(targets map[string]*Route, method string) (route *Route) {
     var ok bool
     if route, ok = targets[method]; ok {
@@ -312,38 +451,60 @@ func getRouteByMethod(targets map[string]*Route, method string) (route *Route) {
     }
     return nil
 }
-func (this *Router) match(path, method string) (h http.Handler) {
+// match returns the handler of the first rule matching path, or the
+// methods it allows when method has no handler. Host params follow the
+// params of the rule.
+func (this *Router) match(path, method string, hostParams Params) (h http.Handler, allow []string) {
     this.RLock()
     defer this.RUnlock()
 
+    if rule, targets, params, found := this.lookup(path); found {
+        return this.dispatch(rule, targets, method, append(params, hostParams...))
+    }
+    return nil, nil
+}
+// lookup finds the rule matching path, the caller holds the lock.
+func (this *Router) lookup(path string) (rule string, targets map[string]*Route, params Params, found bool) {
     // fmt.Printf("match static rule...\n")
-    if targets, found := this.static[path]; found {
-        route := getRouteByMethod(targets, method)
-        return route.makeHandler(nil, nil, nil)
+    if targets, found = this.static[path]; found {
+        return this.staticRules[path], targets, nil, true
     }
 
     // fmt.Printf("match dynamic rule...\n")
+    best := this.trie.lookup(path)
     for _, d := range this.dynamic {
+        // rules registered after the trie match can't win
+        if best.leaf != nil && d.pairs[0].order > best.leaf.order {
+            break
+        }
         // fmt.Printf("dynamic %q\n", d.pattern)
         subindex := d.rexp.FindStringSubmatchIndex(path)
         // fmt.Printf("\t% d\n", subindex)
-        i := indexCombined(subindex, len(d.pairs))-1
+        i := indexCombined(subindex, d.pairs)-1
         if i < 0 {
             continue
         }
-        getargs, targets := d.pairs[i].getargs, d.pairs[i].targets
-        args, kwargs := getargs(path)
-        // fmt.Printf("\tmatched args: % q; kwargs: %+v\n", args, kwargs)
-        route := getRouteByMethod(targets, method)
-        return route.makeHandler(args, kwargs, this.wrapped)
+        if best.leaf != nil && d.pairs[i].order > best.leaf.order {
+            break
+        }
+        pair := d.pairs[i]
+        params = newParams(pair.parts, pair.getvalues(path))
+        // fmt.Printf("\tmatched params: %+v\n", params)
+        return pair.rule, pair.targets, params, true
+    }
+    if leaf := best.leaf; leaf != nil {
+        return leaf.rule, leaf.targets, newParams(leaf.parts, best.values), true
     }
 
-    return nil
+    return "", nil, nil, false
 }
-func indexCombined(subs []int, n int) (i int) {
-    for i < n {
+func indexCombined(subs []int, pairs []*pairGetargsRule) (i int) {
+    if subs == nil {
+        return 0
+    }
+    for i < len(pairs) {
         i++
-        if subs[i*2+1] >= 0 {
+        if subs[pairs[i-1].group*2+1] >= 0 {
             return i
         }
     }
@@ -357,6 +518,19 @@ func (this *Router) wrapped(h http.Handler) (http.Handler) {
     return this.wrapFunc(h)
 }
 func (this *Router) handle(rule, method string, route *Route) () {
+    if this.root != nil {
+        this.root.handle(this.prefix + rule, method, route)
+        return
+    }
+    // report after unlocking, the hook may use the router
+    var conflict *RouteConflict
+    defer func () () {
+        if conflict != nil {
+            this.reportConflict(conflict)
+        }
+    }()
+    this.editLock.Lock()
+    defer this.editLock.Unlock()
     this.Lock()
     defer this.Unlock()
 
@@ -369,13 +543,21 @@ func (this *Router) handle(rule, method string, route *Route) () {
     }
     targets := map[string]*Route{method:route}
     this.rules[rule] = targets
+    this.ruleOrder = append(this.ruleOrder, rule)
 
     pattern := ""
     flat_pattern := ""
     builder := []*builderPart{}
     static := true
     anons := 0
-    for _, parts := range ParseBottleRule(rule) {
+    // dynamic parts with their submatch index in pattern
+    var (
+        dynParts    []*builderPart
+        subIndexes  []int
+    )
+    groups := 0
+    parsed := ParseBottleRule(rule)
+    for _, parts := range parsed {
         var se string
         prefix, key, filter, conf := parts[0], parts[1], parts[2], parts[3]
         // fmt.Printf("\t%q %q %q %q\n", prefix, key, filter, conf)
@@ -383,11 +565,23 @@ func (this *Router) handle(rule, method string, route *Route) () {
             se = regexp.QuoteMeta(prefix)
             pattern += se
             flat_pattern += se
-            builder = append(builder, &builderPart{ prefix, true })
+            builder = append(builder, &builderPart{ key: prefix, static: true })
             continue
         }
         static = false
-        mask := filters[filter](conf)
+        // ":name#conf#" and "<name>" carry no filter name
+        if filter == "" {
+            filter = "re"
+        }
+        filterFunc, ok := filters[filter]
+        if !ok {
+            panic(fmt.Sprintf("httputil: unknown filter %q in rule %q", filter, rule))
+        }
+        mask := filterFunc(conf)
+        maskExp := regexp.MustCompile(fmt.Sprintf("^(?:%s)$", mask))
+        groups++
+        subIndexes = append(subIndexes, groups)
+        groups += maskExp.NumSubexp()
         if key == "" {
             // key = fmt.Sprintf("anon%d", anons)
             pattern += fmt.Sprintf("(%s)", mask)
@@ -396,7 +590,14 @@ func (this *Router) handle(rule, method string, route *Route) () {
             pattern += fmt.Sprintf("(?P<%s>%s)", key, mask)
         }
         flat_pattern += fmt.Sprintf("(?:%s)", mask)
-        builder = append(builder, &builderPart{ key, false })
+        part := &builderPart{
+            key: key,
+            filter: filter,
+            conf: conf,
+            rexp: maskExp,
+        }
+        builder = append(builder, part)
+        dynParts = append(dynParts, part)
     }
     this.builder[rule] = builder
 
@@ -404,25 +605,30 @@ func (this *Router) handle(rule, method string, route *Route) () {
         path := this.build(rule)
         // fmt.Printf("\tstatic %q\n", path)
         this.static[path] = targets
+        this.staticRules[path] = rule
         return 
     }
 
+    conflict = this.checkShadowed(rule, builder)
+    this.order++
+    if segments, filters, ok := trieSegments(parsed); ok && !this.regexpOnly {
+        this.trie.insert(segments, filters, &trieLeaf{
+            rule: rule,
+            order: this.order,
+            parts: dynParts,
+            targets: targets,
+        })
+        return
+    }
+
     reMatch := regexp.MustCompile(fmt.Sprintf("^%s$", pattern))
-    subNames := reMatch.SubexpNames()
-    getargs := func (path string) (args []string, kwargs map[string]string) {
+    // groups inside filter masks are skipped
+    getvalues := func (path string) (values []string) {
         subs := reMatch.FindStringSubmatch(path)
-        for i, subName := range subNames {
-            if i == 0 { continue }
-            if subName == "" {
-                args = append(args, subs[i])
-            } else {
-                if kwargs == nil {
-                    kwargs = make(map[string]string)
-                }
-                kwargs[subName] = subs[i]
-            }
+        for _, i := range subIndexes {
+            values = append(values, subs[i])
         }
-        return args, kwargs
+        return values
     }
 
     var (
@@ -454,13 +660,20 @@ func (this *Router) handle(rule, method string, route *Route) () {
         this.dynamic = append(this.dynamic, last)
     }
     last.pairs = append(last.pairs, &pairGetargsRule{
-        getargs: getargs,
+        rule: rule,
+        order: this.order,
+        group: last.groups + 1,
+        getvalues: getvalues,
+        parts: dynParts,
         targets: targets,
     })
+    // the rule group plus the groups inside its masks
+    last.groups += 1 + groups - len(dynParts)
 }
 func (this *Router) Handle     (rule, method string, h http.Handler) () {
     this.handle(rule, method, &Route{
-        nullArgsHandler: this.wrapped(h),
+        name: handlerName(h),
+        nullArgsHandler: this.wrapped(Chain(h, this.middlewares...)),
     })
 }
 func (this *Router) Handles    (rule string, methods map[string]http.Handler) () {
@@ -470,7 +683,9 @@ func (this *Router) Handles    (rule string, methods map[string]http.Handler) ()
 }
 func (this *Router) HandleFunc (rule, method string, f ArgsHandler ) () {
     this.handle(rule, method, &Route{
+        name: handlerName(f),
         withArgsHandler: f,
+        middlewares: this.middlewares,
     })
 }
 func (this *Router) HandleFuncs(rule string, methods map[string]ArgsHandler ) () {
@@ -491,22 +706,26 @@ func (this *Router) build(rule string) (url string) {
 //
 
 type Route struct {
+    // of the registered handler, for RouteInfo
+    name                string
     nullArgsHandler     http.Handler
     withArgsHandler     ArgsHandler
+    // group middlewares, run inside the router WrapFunc
+    middlewares         []Handler
 }
-func (this *Route) makeHandler(args []string, kwargs map[string]string, wrapped WrapperFunc) (http.Handler) {
+func (this *Route) makeHandler(router *Router, rule string, params Params) (http.Handler) {
     if this == nil {
         return nil
     }
     if this.nullArgsHandler != nil {
-        return this.nullArgsHandler
+        return withRoute(this.nullArgsHandler, router, rule, params)
     }
     if this.withArgsHandler != nil {
-        return wrapped(&argsHandler{
+        return withRoute(router.wrapped(Chain(&argsHandler{
             handler : this.withArgsHandler,
-            args    : args,
-            kwargs  : kwargs,
-        })
+            args    : params.Args(),
+            kwargs  : params.Kwargs(),
+        }, this.middlewares...)), router, rule, params)
     }
     return nil
 }
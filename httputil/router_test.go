@@ -0,0 +1,138 @@
+package httputil_test
+
+import (
+    "testing"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+type testMatchIO struct {
+    path    string
+    rule    string
+    args    []string
+    kwargs  map[string]string
+}
+
+var testMatchRules = []string{
+    "/users",
+    "/users/<id:int>",
+    "/users/<name>",
+    "/users/<id:int>/posts/<:int>",
+    "/users/<name>/posts/<slug>",
+    "/files/<name>.json",
+    "/files/<p:path>",
+    "/<lang:re:[a-z]{2}>/about",
+    "/<page>/about",
+    "/price/<v:float>/<:re:usd|eur>",
+    "/static/<p:path>",
+}
+
+var testMatchIOs = []*testMatchIO{
+    &testMatchIO{ "/users"                , "/users"                      , nil, nil },
+    &testMatchIO{ "/users/12"             , "/users/<id:int>"             , nil, map[string]string{"id": "12"} },
+    &testMatchIO{ "/users/-3"             , "/users/<id:int>"             , nil, map[string]string{"id": "-3"} },
+    &testMatchIO{ "/users/bob"            , "/users/<name>"               , nil, map[string]string{"name": "bob"} },
+    &testMatchIO{ "/users/12/posts/3"     , "/users/<id:int>/posts/<:int>", []string{"3"}, map[string]string{"id": "12"} },
+    &testMatchIO{ "/users/12/posts/x"     , "/users/<name>/posts/<slug>"  , nil, map[string]string{"name": "12", "slug": "x"} },
+    &testMatchIO{ "/files/a.json"         , "/files/<name>.json"          , nil, map[string]string{"name": "a"} },
+    &testMatchIO{ "/files/a/b.json"       , "/files/<p:path>"             , nil, map[string]string{"p": "a/b.json"} },
+    &testMatchIO{ "/en/about"             , "/<lang:re:[a-z]{2}>/about"   , nil, map[string]string{"lang": "en"} },
+    &testMatchIO{ "/eng/about"            , "/<page>/about"               , nil, map[string]string{"page": "eng"} },
+    &testMatchIO{ "/price/1.5/eur"        , "/price/<v:float>/<:re:usd|eur>", []string{"eur"}, map[string]string{"v": "1.5"} },
+    &testMatchIO{ "/static/"              , ""                            , nil, nil },
+    &testMatchIO{ "/static//x"            , "/static/<p:path>"            , nil, map[string]string{"p": "/x"} },
+    &testMatchIO{ "/users/"               , ""                            , nil, nil },
+    &testMatchIO{ "/nowhere"              , ""                            , nil, nil },
+}
+
+type matchResult struct {
+    rule    string
+    args    []string
+    kwargs  map[string]string
+}
+
+func serveMatch(router *httputil.Router, path string) (res *matchResult) {
+    req := httptest.NewRequest(httputil.METHOD_GET, "http://example.com/", nil)
+    req.URL.Path = path
+    w := httptest.NewRecorder()
+    router.Handler(req).ServeHTTP(w, req)
+    if w.Code != http.StatusOK {
+        return &matchResult{}
+    }
+    return &matchResult{ w.Body.String(), nil, nil }
+}
+
+func newMatchRouter(router *httputil.Router, rules []string, results map[string]*matchResult) (*httputil.Router) {
+    for _, rule := range rules {
+        rule := rule
+        router.HandleFunc(rule, httputil.METHOD_GET, func (w http.ResponseWriter, r *http.Request, args []string, kwargs map[string]string) () {
+            results[r.URL.Path] = &matchResult{ rule, args, kwargs }
+            fmt.Fprint(w, rule)
+        })
+    }
+    return router
+}
+
+func TestMatch(t *testing.T) () {
+    for name, router := range map[string]*httputil.Router{"trie": httputil.NewRouter(), "regexp": httputil.NewRegexpRouter()} {
+        results := map[string]*matchResult{}
+        newMatchRouter(router, testMatchRules, results)
+        for _, io := range testMatchIOs {
+            if res := serveMatch(router, io.path); res.rule != io.rule {
+                t.Fatalf("%s: %q must match %q, but got %q\n", name, io.path, io.rule, res.rule)
+            }
+            res, ok := results[io.path]
+            if !ok {
+                continue
+            }
+            if !reflect.DeepEqual(res.args, io.args) || !reflect.DeepEqual(res.kwargs, io.kwargs) {
+                t.Fatalf("%s: %q must get %q %v, but got %q %v\n", name, io.path, io.args, io.kwargs, res.args, res.kwargs)
+            }
+        }
+    }
+}
+
+//
+
+func benchmarkRules(n int) (rules []string, paths []string) {
+    for i := 0; i < n; i++ {
+        rules = append(rules, fmt.Sprintf("/api/v1/resource%d/<id:int>/items/<item>", i))
+        paths = append(paths, fmt.Sprintf("/api/v1/resource%d/%d/items/x", i, i))
+    }
+    return
+}
+
+func benchmarkMatch(b *testing.B, router *httputil.Router, n int) () {
+    rules, paths := benchmarkRules(n)
+    for _, rule := range rules {
+        router.HandleFunc(rule, httputil.METHOD_GET, func (w http.ResponseWriter, r *http.Request, args []string, kwargs map[string]string) () {})
+    }
+    req := httptest.NewRequest(httputil.METHOD_GET, "http://example.com/", nil)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        req.URL.Path = paths[i % n]
+        if router.Handler(req) == nil {
+            b.Fatal("no handler")
+        }
+    }
+}
+
+func BenchmarkMatchTrie10    (b *testing.B) () { benchmarkMatch(b, httputil.NewRouter(), 10) }
+func BenchmarkMatchRegexp10  (b *testing.B) () { benchmarkMatch(b, httputil.NewRegexpRouter(), 10) }
+func BenchmarkMatchTrie500   (b *testing.B) () { benchmarkMatch(b, httputil.NewRouter(), 500) }
+func BenchmarkMatchRegexp500 (b *testing.B) () { benchmarkMatch(b, httputil.NewRegexpRouter(), 500) }
+
+func BenchmarkMultiHost(b *testing.B) () {
+    router := httputil.NewMultiHostRouter()
+    for i := 0; i < 100; i++ {
+        router.Handle(fmt.Sprintf(`^host%d\.example\.com$`, i), "/", httputil.METHOD_GET, http.NotFoundHandler())
+    }
+    req := httptest.NewRequest(httputil.METHOD_GET, "http://host99.example.com/", nil)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        router.Handler(req)
+    }
+}
@@ -0,0 +1,266 @@
+package httputil
+
+import (
+    "fmt"
+    "log"
+    "html/template"
+    "net/http"
+    "reflect"
+    "regexp/syntax"
+    "runtime"
+    "sort"
+    "strings"
+    "unicode"
+    "encoding/json"
+)
+
+type RouteParam struct {
+    Key     string  `json:"key,omitempty"`
+    Filter  string  `json:"filter"`
+    Conf    string  `json:"conf,omitempty"`
+}
+
+// RouteInfo describes one registered rule.
+type RouteInfo struct {
+    Host        string              `json:"host,omitempty"`
+    Rule        string              `json:"rule"`
+    Names       []string            `json:"names,omitempty"`
+    Methods     []string            `json:"methods"`
+    // method => handler function or type name
+    Handlers    map[string]string   `json:"handlers"`
+    Params      []RouteParam        `json:"params,omitempty"`
+}
+
+// RouteConflict reports a rule an earlier one takes paths from. Rules
+// are checked with an example path built from their filters, so partial
+// overlaps may go unnoticed.
+type RouteConflict struct {
+    Host        string  `json:"host,omitempty"`
+    Rule        string  `json:"rule"`
+    ShadowedBy  string  `json:"shadowed_by"`
+    Path        string  `json:"path"`
+}
+func (this *RouteConflict) Error() (string) {
+    return fmt.Sprintf("rule %q is shadowed by %q, e.g. on %q", this.Rule, this.ShadowedBy, this.Path)
+}
+
+// ConflictFunc is called once per conflicting rule, e.g. panic(c) to
+// refuse them. Without one conflicts are logged.
+type ConflictFunc func (c *RouteConflict) ()
+
+type RouteLister interface {
+    Routes() ([]RouteInfo)
+    Conflicts() ([]*RouteConflict)
+}
+
+var _, _ RouteLister = &Router{}, &MultiHostRouter{}
+
+//
+
+func (this *Router) hostPattern() (string) {
+    if this.host == nil {
+        return ""
+    }
+    return this.host.pattern
+}
+
+// Routes lists the rules in registration order.
+func (this *Router) Routes() (routes []RouteInfo) {
+    if this.root != nil {
+        return this.root.Routes()
+    }
+    this.RLock()
+    defer this.RUnlock()
+    names := make(map[string][]string)
+    for name, rule := range this.names {
+        names[rule] = append(names[rule], name)
+    }
+    for _, rule := range this.ruleOrder {
+        info := RouteInfo{
+            Host: this.hostPattern(),
+            Rule: rule,
+            Names: names[rule],
+            Handlers: make(map[string]string),
+        }
+        sort.Strings(info.Names)
+        for method, route := range this.rules[rule] {
+            info.Methods = append(info.Methods, method)
+            info.Handlers[method] = route.name
+        }
+        sort.Strings(info.Methods)
+        for _, part := range this.builder[rule] {
+            if !part.static {
+                info.Params = append(info.Params, RouteParam{ part.key, part.filter, part.conf })
+            }
+        }
+        routes = append(routes, info)
+    }
+    return
+}
+func (this *Router) Conflicts() ([]*RouteConflict) {
+    if this.root != nil {
+        return this.root.Conflicts()
+    }
+    this.RLock()
+    defer this.RUnlock()
+    return append([]*RouteConflict(nil), this.conflicts...)
+}
+
+// Routes lists the rules of every host, the default host last.
+func (this *MultiHostRouter) Routes() (routes []RouteInfo) {
+    for _, router := range this.load().routers() {
+        routes = append(routes, router.Routes()...)
+    }
+    return
+}
+func (this *MultiHostRouter) Conflicts() (conflicts []*RouteConflict) {
+    for _, router := range this.load().routers() {
+        conflicts = append(conflicts, router.Conflicts()...)
+    }
+    return
+}
+
+//
+
+func handlerName(h interface{}) (string) {
+    v := reflect.ValueOf(h)
+    if v.Kind() == reflect.Func {
+        if f := runtime.FuncForPC(v.Pointer()); f != nil {
+            return f.Name()
+        }
+    }
+    return fmt.Sprintf("%T", h)
+}
+
+// checkShadowed tests the example path of a new dynamic rule against the
+// rules registered before, the caller holds the lock.
+func (this *Router) checkShadowed(rule string, builder []*builderPart) (*RouteConflict) {
+    var path string
+    for _, part := range builder {
+        if part.static {
+            path += part.key
+            continue
+        }
+        example, ok := exampleOf(part.rexp.String())
+        if !ok {
+            return nil
+        }
+        path += example
+    }
+    other, _, _, found := this.lookup(path)
+    if !found || other == rule {
+        return nil
+    }
+    conflict := &RouteConflict{
+        Host: this.hostPattern(),
+        Rule: rule,
+        ShadowedBy: other,
+        Path: path,
+    }
+    this.conflicts = append(this.conflicts, conflict)
+    return conflict
+}
+func (this *Router) reportConflict(conflict *RouteConflict) () {
+    if this.OnConflict != nil {
+        this.OnConflict(conflict)
+        return
+    }
+    log.Printf("httputil: %v", conflict)
+}
+
+// exampleOf builds a short string matching the regexp.
+func exampleOf(expr string) (string, bool) {
+    re, err := syntax.Parse(expr, syntax.Perl)
+    if err != nil {
+        return "", false
+    }
+    var b strings.Builder
+    ok := writeExample(&b, re.Simplify())
+    return b.String(), ok
+}
+func writeExample(b *strings.Builder, re *syntax.Regexp) (bool) {
+    switch re.Op {
+    case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText, syntax.OpStar, syntax.OpQuest:
+        return true
+    case syntax.OpLiteral:
+        b.WriteString(string(re.Rune))
+        return true
+    case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+        b.WriteByte('x')
+        return true
+    case syntax.OpCharClass:
+        if len(re.Rune) < 2 {
+            return false
+        }
+        // prefer readable characters
+        for _, r := range "x1a0-" {
+            for i := 0; i+1 < len(re.Rune); i += 2 {
+                if re.Rune[i] <= r && r <= re.Rune[i+1] {
+                    b.WriteRune(r)
+                    return true
+                }
+            }
+        }
+        for i := 0; i+1 < len(re.Rune); i += 2 {
+            for r := re.Rune[i]; r <= re.Rune[i+1]; r++ {
+                if unicode.IsPrint(r) && r != '/' {
+                    b.WriteRune(r)
+                    return true
+                }
+            }
+        }
+        b.WriteRune(re.Rune[0])
+        return true
+    case syntax.OpCapture, syntax.OpPlus:
+        return writeExample(b, re.Sub[0])
+    case syntax.OpRepeat:
+        for i := 0; i < re.Min; i++ {
+            if !writeExample(b, re.Sub[0]) {
+                return false
+            }
+        }
+        return true
+    case syntax.OpConcat:
+        for _, sub := range re.Sub {
+            if !writeExample(b, sub) {
+                return false
+            }
+        }
+        return true
+    case syntax.OpAlternate:
+        return writeExample(b, re.Sub[0])
+    }
+    return false
+}
+
+//
+
+var routesTemplate = template.Must(template.New("routes").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Routes</title></head><body>
+<table border="1" cellspacing="0" cellpadding="4">
+<tr><th>Host</th><th>Rule</th><th>Names</th><th>Methods</th><th>Params</th></tr>
+{{range .Routes}}<tr><td>{{.Host}}</td><td>{{.Rule}}</td><td>{{range .Names}}{{.}} {{end}}</td><td>{{range $method, $handler := .Handlers}}{{$method}} {{$handler}}<br>{{end}}</td><td>{{range .Params}}{{.Key}}:{{.Filter}}{{if .Conf}}:{{.Conf}}{{end}} {{end}}</td></tr>
+{{end}}</table>
+{{if .Conflicts}}<h3>Conflicts</h3><ul>
+{{range .Conflicts}}<li>{{.Error}}</li>
+{{end}}</ul>{{end}}
+</body></html>
+`))
+
+// RoutesHandler renders the route table for debugging, as JSON when the
+// request accepts it, otherwise as HTML. Don't expose it publicly.
+func RoutesHandler(lister RouteLister) (http.Handler) {
+    return http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        data := struct {
+            Routes      []RouteInfo         `json:"routes"`
+            Conflicts   []*RouteConflict    `json:"conflicts"`
+        }{ lister.Routes(), lister.Conflicts() }
+        if ResolveFormat(r) == "json" || r.URL.Query().Get("format") == "json" {
+            w.Header().Set(HEADER_CONTENT_TYPE, "application/json; charset=utf-8")
+            json.NewEncoder(w).Encode(data)
+            return
+        }
+        w.Header().Set(HEADER_CONTENT_TYPE, "text/html; charset=utf-8")
+        routesTemplate.Execute(w, data)
+    })
+}
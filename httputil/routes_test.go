@@ -0,0 +1,44 @@
+package httputil_test
+
+import (
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "encoding/json"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+func TestRoutes(t *testing.T) () {
+    var conflicts []*httputil.RouteConflict
+    router := httputil.NewMultiHostRouter()
+    router.OnConflict = func (c *httputil.RouteConflict) () {
+        conflicts = append(conflicts, c)
+    }
+    router.Handle(httputil.MATCH_HOST_ANY, "/users/<name>", httputil.METHOD_GET, http.NotFoundHandler())
+    router.Handle(httputil.MATCH_HOST_ANY, "/users/<id:int>", httputil.METHOD_GET, http.NotFoundHandler())
+    router.Handle(httputil.MATCH_HOST_ANY, "/users/<id:int>/posts", httputil.METHOD_POST, http.NotFoundHandler())
+    router.Name(httputil.MATCH_HOST_ANY, "user", "/users/<name>")
+
+    if len(conflicts) != 1 || conflicts[0].Rule != "/users/<id:int>" || conflicts[0].ShadowedBy != "/users/<name>" {
+        t.Fatalf("/users/<id:int> must be shadowed by /users/<name>, but got %v\n", conflicts)
+    }
+    routes := router.Routes()
+    if len(routes) != 3 || routes[0].Rule != "/users/<name>" || routes[0].Names[0] != "user" || routes[2].Methods[0] != httputil.METHOD_POST {
+        t.Fatalf("unexpected routes %+v\n", routes)
+    }
+    if params := routes[1].Params; len(params) != 1 || params[0].Key != "id" || params[0].Filter != "int" {
+        t.Fatalf("unexpected params %+v\n", params)
+    }
+
+    w := httptest.NewRecorder()
+    req := httptest.NewRequest(httputil.METHOD_GET, "http://example.com/debug/routes", nil)
+    req.Header.Set(httputil.HEADER_ACCEPT, "application/json")
+    httputil.RoutesHandler(router).ServeHTTP(w, req)
+    var data struct {
+        Routes      []httputil.RouteInfo
+        Conflicts   []*httputil.RouteConflict
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &data); err != nil || len(data.Routes) != 3 || len(data.Conflicts) != 1 {
+        t.Fatalf("unexpected route table %s %v\n", w.Body.String(), err)
+    }
+}
@@ -0,0 +1,368 @@
+package httputil
+
+import (
+    "context"
+    "crypto/tls"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net"
+    "net/http"
+    "os"
+    "os/signal"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "syscall"
+    "time"
+)
+
+/*
+    router := NewMultiHostRouter()
+    server := NewServer(router, &ServerOptions{
+        Listen: []string{ ":8080", "unix:/run/app/http.sock" },
+        ListenTLS: []string{ ":8443" },
+        CertFile: "/etc/tls/app.crt",
+        KeyFile: "/etc/tls/app.key",
+        Middlewares: []Handler{ NewRequestID(nil), NewAccessLog(nil) },
+        DrainDelay: 5 * time.Second,
+    })
+    router.Handle(MATCH_HOST_ANY, "/healthz", METHOD_GET, server.Live())
+    router.Handle(MATCH_HOST_ANY, "/readyz", METHOD_GET, server.Ready())
+    if err := server.ListenAndServe(); err != nil {
+        log.Fatal(err)
+    }
+
+    ListenAndServe returns once SIGINT or SIGTERM drained the server:
+    readiness fails at once, DrainDelay later the listeners close, then
+    requests in flight, hijacked ones too, get until ShutdownTimeout before
+    connections are closed. Certificates are loaded again when their files
+    change and on SIGHUP.
+//*/
+
+type ServerOptions struct {
+    // served over HTTP, "host:port" or "unix:/path/to.sock"
+    Listen              []string
+    // served over HTTPS with CertFile and KeyFile
+    ListenTLS           []string
+    CertFile            string
+    KeyFile             string
+    // e.g. MinVersion, certificates are set from CertFile and KeyFile
+    TLSConfig           *tls.Config
+    // run in front of the router, after the in flight tracking
+    Middlewares         []Handler
+    // readiness fails with 503 when it returns an error
+    ReadyCheck          func () (error)
+    // between readiness failing and listeners closing, for load balancers
+    // to notice
+    DrainDelay          time.Duration
+    // for requests in flight once listeners closed, 30s by default
+    ShutdownTimeout     time.Duration
+    // SIGINT and SIGTERM by default
+    Signals             []os.Signal
+    // 10s by default
+    ReadHeaderTimeout   time.Duration
+    ReadTimeout         time.Duration
+    WriteTimeout        time.Duration
+    // 2m by default
+    IdleTimeout         time.Duration
+    // log.Default() when nil
+    Logger              *log.Logger
+}
+
+func NewServer(router *MultiHostRouter, opts *ServerOptions) (*Server) {
+    this := &Server{
+        router: router,
+        stop: make(chan struct{}),
+    }
+    if opts != nil {
+        this.ServerOptions = *opts
+    }
+    if this.ShutdownTimeout <= 0 {
+        this.ShutdownTimeout = 30 * time.Second
+    }
+    if len(this.Signals) <= 0 {
+        this.Signals = []os.Signal{ os.Interrupt, syscall.SIGTERM }
+    }
+    if this.ReadHeaderTimeout <= 0 {
+        this.ReadHeaderTimeout = 10 * time.Second
+    }
+    if this.IdleTimeout <= 0 {
+        this.IdleTimeout = 2 * time.Minute
+    }
+    if this.Logger == nil {
+        this.Logger = log.Default()
+    }
+    this.server = &http.Server{
+        Handler: Chain(router, append([]Handler{ HandlerFunc(this.track) }, this.Middlewares...)...),
+        ReadHeaderTimeout: this.ReadHeaderTimeout,
+        ReadTimeout: this.ReadTimeout,
+        WriteTimeout: this.WriteTimeout,
+        IdleTimeout: this.IdleTimeout,
+        ErrorLog: this.Logger,
+    }
+    return this
+}
+
+type Server struct {
+    ServerOptions
+    router      *MultiHostRouter
+    server      *http.Server
+    certs       *CertReloader
+    inFlight    int64
+    draining    int32
+    stop        chan struct{}
+    stopOnce    sync.Once
+}
+
+// InFlight returns the number of requests being served.
+func (this *Server) InFlight() (int) { return int(atomic.LoadInt64(&this.inFlight)) }
+// Draining tells whether the server is shutting down.
+func (this *Server) Draining() (bool) { return atomic.LoadInt32(&this.draining) != 0 }
+
+func (this *Server) track(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) () {
+    atomic.AddInt64(&this.inFlight, 1)
+    defer atomic.AddInt64(&this.inFlight, -1)
+    if this.Draining() {
+        // keep-alive clients move on to other servers
+        w.Header().Set(HEADER_CONNECTION, CONNECTION_CLOSE)
+    }
+    next(w, r)
+}
+
+// Live answers liveness probes, 200 while the process serves.
+func (this *Server) Live() (http.Handler) {
+    return http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        w.Header().Set(HEADER_CONTENT_TYPE, "text/plain; charset=utf-8")
+        io.WriteString(w, "ok\n")
+    })
+}
+
+// Ready answers readiness probes, 503 once draining or when ReadyCheck
+// fails.
+func (this *Server) Ready() (http.Handler) {
+    return http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        if this.Draining() {
+            WriteError(w, r, NewError(http.StatusServiceUnavailable, "shutting down"))
+            return
+        }
+        if this.ReadyCheck != nil {
+            if err := this.ReadyCheck(); err != nil {
+                WriteError(w, r, &Error{ Status: http.StatusServiceUnavailable, Detail: "not ready", Err: err })
+                return
+            }
+        }
+        w.Header().Set(HEADER_CONTENT_TYPE, "text/plain; charset=utf-8")
+        io.WriteString(w, "ok\n")
+    })
+}
+
+// Stop starts the shutdown a signal starts.
+func (this *Server) Stop() () {
+    this.stopOnce.Do(func () () { close(this.stop) })
+}
+
+// ListenAndServe listens on every address, then serves like Serve.
+func (this *Server) ListenAndServe() (err error) {
+    var listeners []net.Listener
+    defer func () () {
+        if err != nil {
+            for _, ln := range listeners {
+                ln.Close()
+            }
+        }
+    }()
+    for _, addr := range this.Listen {
+        ln, err := listen(addr)
+        if err != nil {
+            return err
+        }
+        listeners = append(listeners, ln)
+    }
+    if len(this.ListenTLS) > 0 {
+        config, err := this.tlsConfig()
+        if err != nil {
+            return err
+        }
+        for _, addr := range this.ListenTLS {
+            ln, err := listen(addr)
+            if err != nil {
+                return err
+            }
+            listeners = append(listeners, tls.NewListener(ln, config))
+        }
+    }
+    if len(listeners) <= 0 {
+        return errors.New("httputil: server has no addresses to listen on")
+    }
+    return this.Serve(listeners...)
+}
+
+// tlsConfig loads the certificate, the server reloads it on SIGHUP.
+func (this *Server) tlsConfig() (*tls.Config, error) {
+    config := &tls.Config{}
+    if this.TLSConfig != nil {
+        config = this.TLSConfig.Clone()
+    }
+    if this.CertFile == "" && this.KeyFile == "" && (len(config.Certificates) > 0 || config.GetCertificate != nil) {
+        return config, nil
+    }
+    certs, err := NewCertReloader(this.CertFile, this.KeyFile)
+    if err != nil {
+        return nil, err
+    }
+    this.certs = certs
+    config.GetCertificate = certs.GetCertificate
+    if len(config.NextProtos) <= 0 {
+        config.NextProtos = []string{ "h2", "http/1.1" }
+    }
+    return config, nil
+}
+
+// listen opens "unix:/path" sockets, removing stale ones, or TCP
+// addresses.
+func listen(addr string) (net.Listener, error) {
+    path := strings.TrimPrefix(addr, "unix:")
+    if path == addr {
+        return net.Listen("tcp", addr)
+    }
+    if info, err := os.Stat(path); err == nil && info.Mode() & os.ModeSocket != 0 {
+        if conn, err := net.Dial("unix", path); err == nil {
+            conn.Close()
+            return nil, fmt.Errorf("httputil: socket %s is in use", path)
+        }
+        os.Remove(path)
+    }
+    return net.Listen("unix", path)
+}
+
+// Serve serves on listeners, wrap HTTPS ones with tls.NewListener, until
+// a signal or Stop, then drains. It returns nil after a graceful
+// shutdown.
+func (this *Server) Serve(listeners ...net.Listener) (error) {
+    signals := make(chan os.Signal, 1)
+    signal.Notify(signals, this.Signals...)
+    defer signal.Stop(signals)
+    hangups := make(chan os.Signal, 1)
+    if this.certs != nil {
+        signal.Notify(hangups, syscall.SIGHUP)
+        defer signal.Stop(hangups)
+    }
+
+    errs := make(chan error, len(listeners))
+    for _, ln := range listeners {
+        this.Logger.Printf("httputil: serving on %s %s", ln.Addr().Network(), ln.Addr())
+        go func (ln net.Listener) () { errs <- this.server.Serve(ln) }(ln)
+    }
+    for {
+        select {
+        case <-hangups:
+            if err := this.certs.Reload(); err != nil {
+                this.Logger.Printf("httputil: reloading certificate: %v", err)
+            }
+        case sig := <-signals:
+            this.Logger.Printf("httputil: shutting down on %v", sig)
+            return this.shutdown()
+        case <-this.stop:
+            this.Logger.Printf("httputil: shutting down")
+            return this.shutdown()
+        case err := <-errs:
+            if err != http.ErrServerClosed {
+                this.server.Close()
+                return err
+            }
+        }
+    }
+}
+
+func (this *Server) shutdown() (err error) {
+    atomic.StoreInt32(&this.draining, 1)
+    time.Sleep(this.DrainDelay)
+    ctx, cancel := context.WithTimeout(context.Background(), this.ShutdownTimeout)
+    defer cancel()
+    err = this.server.Shutdown(ctx)
+    // net/http does not wait for hijacked connections
+    ticker := time.NewTicker(10 * time.Millisecond)
+    defer ticker.Stop()
+    for err == nil && this.InFlight() > 0 {
+        select {
+        case <-ctx.Done():
+            err = ctx.Err()
+        case <-ticker.C:
+        }
+    }
+    if err != nil {
+        this.server.Close()
+        return fmt.Errorf("httputil: shutdown after %v: %v, %d requests cut off", this.ShutdownTimeout, err, this.InFlight())
+    }
+    return nil
+}
+
+//
+
+// how often CertReloader looks at the files
+const certCheckInterval = 10 * time.Second
+
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+    this := &CertReloader{
+        certFile: certFile,
+        keyFile: keyFile,
+    }
+    if err := this.Reload(); err != nil {
+        return nil, err
+    }
+    return this, nil
+}
+
+// CertReloader serves a certificate pair, loaded again when the files
+// changed. Failed loads keep the previous pair.
+type CertReloader struct {
+    certFile    string
+    keyFile     string
+    lock        sync.RWMutex
+    cert        *tls.Certificate
+    modTime     time.Time
+    checked     time.Time
+}
+
+func (this *CertReloader) Reload() (error) {
+    modTime := this.filesModTime()
+    cert, err := tls.LoadX509KeyPair(this.certFile, this.keyFile)
+    if err != nil {
+        return fmt.Errorf("httputil: loading certificate: %v", err)
+    }
+    this.lock.Lock()
+    defer this.lock.Unlock()
+    this.cert, this.modTime, this.checked = &cert, modTime, time.Now()
+    return nil
+}
+
+func (this *CertReloader) filesModTime() (modTime time.Time) {
+    for _, name := range []string{ this.certFile, this.keyFile } {
+        if info, err := os.Stat(name); err == nil && info.ModTime().After(modTime) {
+            modTime = info.ModTime()
+        }
+    }
+    return
+}
+
+// GetCertificate is the tls.Config hook.
+func (this *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+    this.lock.RLock()
+    cert, due := this.cert, time.Since(this.checked) >= certCheckInterval
+    this.lock.RUnlock()
+    if !due {
+        return cert, nil
+    }
+    this.lock.Lock()
+    modTime := this.modTime
+    this.checked = time.Now()
+    this.lock.Unlock()
+    if this.filesModTime().After(modTime) {
+        this.Reload()
+    }
+    this.lock.RLock()
+    defer this.lock.RUnlock()
+    return this.cert, nil
+}
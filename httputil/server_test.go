@@ -0,0 +1,85 @@
+package httputil_test
+
+import (
+    "context"
+    "io"
+    "log"
+    "net"
+    "os"
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "time"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+func TestServer(t *testing.T) () {
+    router := httputil.NewMultiHostRouter()
+    started, release := make(chan bool), make(chan bool)
+    router.Handle(httputil.MATCH_HOST_ANY, "/slow", httputil.METHOD_GET, http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        started <- true
+        <-release
+        io.WriteString(w, "done")
+    }))
+    socket := t.TempDir() + "/http.sock"
+    server := httputil.NewServer(router, &httputil.ServerOptions{
+        Listen: []string{ "unix:" + socket },
+        DrainDelay: 50 * time.Millisecond,
+        ShutdownTimeout: time.Second,
+        Logger: log.New(io.Discard, "", 0),
+    })
+    router.Handle(httputil.MATCH_HOST_ANY, "/readyz", httputil.METHOD_GET, server.Ready())
+    served := make(chan error, 1)
+    go func () () { served <- server.ListenAndServe() }()
+
+    client := &http.Client{ Transport: &http.Transport{
+        DialContext: func (ctx context.Context, network, addr string) (net.Conn, error) {
+            return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+        },
+    }}
+    var res *http.Response
+    var err error
+    for i := 0; i < 100; i++ {
+        if res, err = client.Get("http://app/readyz"); err == nil {
+            break
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+    if err != nil || res.StatusCode != http.StatusOK {
+        t.Fatalf("server must be ready, but got %v %v\n", res, err)
+    }
+    res.Body.Close()
+
+    slow := make(chan string, 1)
+    go func () () {
+        res, err := client.Get("http://app/slow")
+        if err != nil {
+            slow <- err.Error()
+            return
+        }
+        body, _ := io.ReadAll(res.Body)
+        res.Body.Close()
+        slow <- string(body)
+    }()
+    <-started
+    if server.InFlight() != 1 {
+        t.Fatalf("1 request must be in flight, but got %d\n", server.InFlight())
+    }
+    server.Stop()
+    time.Sleep(10 * time.Millisecond)
+    w := httptest.NewRecorder()
+    server.Ready().ServeHTTP(w, httptest.NewRequest(httputil.METHOD_GET, "/readyz", nil))
+    if !server.Draining() || w.Code != http.StatusServiceUnavailable {
+        t.Fatalf("draining server must not be ready, but got %d\n", w.Code)
+    }
+    release <- true
+    if body := <-slow; body != "done" {
+        t.Fatalf("request in flight must finish, but got %q\n", body)
+    }
+    if err := <-served; err != nil {
+        t.Fatalf("graceful shutdown must return nil, but got %v\n", err)
+    }
+    if _, err := os.Stat(socket); !os.IsNotExist(err) {
+        t.Fatalf("socket must be removed, but got %v\n", err)
+    }
+}
@@ -0,0 +1,252 @@
+package httputil
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "io/fs"
+    "net/http"
+    "os"
+    "path"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+/*
+    router.Handle("/static/<file:path>", METHOD_GET, StaticDir("./public", nil))
+
+    //go:embed assets
+    var assets embed.FS
+    sub, _ := fs.Sub(assets, "assets")
+    router.Handle("/assets/<file:path>", METHOD_GET, StaticHandler(sub, &StaticOptions{
+        Precompressed: true,
+        CacheControl: "public, max-age=86400",
+    }))
+
+    The served path is the path param of the rule, or the whole URL path
+    without one. Ranges and conditional requests are answered by
+    http.ServeContent. Dot segments can't leave the root and directories
+    are never listed.
+//*/
+
+type StaticOptions struct {
+    // route param naming the file, by default the last path param
+    Param           string
+    // files served for directories, by default index.html
+    Index           []string
+    // serve file.br or file.gz instead of file to clients accepting them
+    Precompressed   bool
+    // Cache-Control header of served files, none when empty
+    CacheControl    string
+    // charset of text/* types, by default utf-8
+    Charset         string
+}
+
+var staticEncodings = []struct {
+    encoding    string
+    ext         string
+}{
+    { "br", ".br" },
+    { "gzip", ".gz" },
+}
+
+// StaticDir serves the files below dir.
+func StaticDir(dir string, opts *StaticOptions) (http.Handler) {
+    return StaticHandler(os.DirFS(dir), opts)
+}
+
+// StaticHandler serves the files of fsys, e.g. an embed.FS.
+func StaticHandler(fsys fs.FS, opts *StaticOptions) (http.Handler) {
+    this := &staticHandler{
+        fsys: fsys,
+    }
+    if opts != nil {
+        this.StaticOptions = *opts
+    }
+    if len(this.Index) <= 0 {
+        this.Index = []string{ "index.html" }
+    }
+    return this
+}
+
+type staticHandler struct {
+    StaticOptions
+    fsys    fs.FS
+    // name => ETag of files without modification time, embedded files
+    etags   sync.Map
+}
+
+func (this *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) () {
+    if r.Method != METHOD_GET && r.Method != METHOD_HEAD {
+        MethodNotAllowedHandler([]string{ METHOD_GET, METHOD_HEAD }).ServeHTTP(w, r)
+        return
+    }
+    name, ok := this.fileName(r)
+    if !ok {
+        WriteError(w, r, NewError(http.StatusNotFound, "page not found"))
+        return
+    }
+    info, err := fs.Stat(this.fsys, name)
+    if err == nil && info.IsDir() {
+        // relative links of index files need the slash
+        if !strings.HasSuffix(r.URL.Path, "/") {
+            target := r.URL.Path + "/"
+            if r.URL.RawQuery != "" {
+                target += "?" + r.URL.RawQuery
+            }
+            http.Redirect(w, r, target, http.StatusMovedPermanently)
+            return
+        }
+        name, info, err = this.findIndex(name)
+    }
+    if err != nil {
+        serveFileError(w, r, err)
+        return
+    }
+    if !info.Mode().IsRegular() {
+        WriteError(w, r, NewError(http.StatusNotFound, "page not found"))
+        return
+    }
+
+    header := w.Header()
+    header.Set(HEADER_CONTENT_TYPE, ContentTypeByFilename2(name, this.Charset))
+    if this.CacheControl != "" {
+        header.Set(HEADER_CACHE_CONTROL, this.CacheControl)
+    }
+    served, served_info, encoding := name, info, ""
+    if this.Precompressed {
+        header.Add(HEADER_VARY, HEADER_ACCEPT_ENCODING)
+        served, served_info, encoding = this.findEncoded(r, name, info)
+    }
+    if err := this.serveFile(w, r, served, served_info, encoding); err != nil {
+        serveFileError(w, r, err)
+    }
+}
+
+// fileName returns the cleaned name of the requested file in fsys.
+func (this *staticHandler) fileName(r *http.Request) (string, bool) {
+    params := RequestParams(r)
+    var name string
+    var found bool
+    if this.Param != "" {
+        if param, ok := params.Get(this.Param); ok {
+            name, found = param.Value, true
+        }
+    } else {
+        for i := len(params)-1; i >= 0; i-- {
+            if params[i].Filter == "path" {
+                name, found = params[i].Value, true
+                break
+            }
+        }
+    }
+    if !found {
+        name = r.URL.Path
+    }
+    if strings.Contains(name, "\x00") || strings.Contains(name, `\`) {
+        return "", false
+    }
+    name = path.Clean("/" + name)[1:]
+    if name == "" {
+        name = "."
+    }
+    return name, fs.ValidPath(name)
+}
+
+func (this *staticHandler) findIndex(dir string) (string, fs.FileInfo, error) {
+    for _, index := range this.Index {
+        name := path.Join(dir, index)
+        if info, err := fs.Stat(this.fsys, name); err == nil && info.Mode().IsRegular() {
+            return name, info, nil
+        }
+    }
+    return "", nil, fs.ErrNotExist
+}
+
+// findEncoded picks a precompressed sibling of name the client accepts.
+func (this *staticHandler) findEncoded(r *http.Request, name string, info fs.FileInfo) (string, fs.FileInfo, string) {
+    accept := r.Header.Get(HEADER_ACCEPT_ENCODING)
+    for _, e := range staticEncodings {
+        if !acceptsEncoding(accept, e.encoding) {
+            continue
+        }
+        if encoded, err := fs.Stat(this.fsys, name + e.ext); err == nil && encoded.Mode().IsRegular() {
+            return name + e.ext, encoded, e.encoding
+        }
+    }
+    return name, info, ""
+}
+
+func (this *staticHandler) serveFile(w http.ResponseWriter, r *http.Request, name string, info fs.FileInfo, encoding string) (error) {
+    f, err := this.fsys.Open(name)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+    content, ok := f.(io.ReadSeeker)
+    if !ok {
+        buf, err := io.ReadAll(f)
+        if err != nil {
+            return err
+        }
+        content = bytes.NewReader(buf)
+    }
+    etag, err := this.etag(name, info, content)
+    if err != nil {
+        return err
+    }
+    header := w.Header()
+    header.Set(HEADER_ETAG, etag)
+    if encoding != "" {
+        header.Set(HEADER_CONTENT_ENCODING, encoding)
+    }
+    // ServeContent skips Last-Modified for zero times
+    http.ServeContent(w, r, name, info.ModTime(), content)
+    return nil
+}
+
+func (this *staticHandler) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+    if !info.ModTime().IsZero() {
+        return fmt.Sprintf(`"%s-%s"`, strconv.FormatInt(info.ModTime().UnixNano(), 36), strconv.FormatInt(info.Size(), 36)), nil
+    }
+    if etag, ok := this.etags.Load(name); ok {
+        return etag.(string), nil
+    }
+    hash := sha256.New()
+    if _, err := io.Copy(hash, content); err != nil {
+        return "", err
+    }
+    if _, err := content.Seek(0, io.SeekStart); err != nil {
+        return "", err
+    }
+    etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+    this.etags.Store(name, etag)
+    return etag, nil
+}
+
+func serveFileError(w http.ResponseWriter, r *http.Request, err error) () {
+    switch {
+    case errors.Is(err, fs.ErrNotExist):
+        WriteError(w, r, NewError(http.StatusNotFound, "page not found"))
+    case errors.Is(err, fs.ErrPermission):
+        WriteError(w, r, NewError(http.StatusForbidden, "forbidden"))
+    default:
+        WriteError(w, r, &Error{ Status: http.StatusInternalServerError, Err: err })
+    }
+}
+
+// acceptsEncoding tells whether an Accept-Encoding header allows
+// encoding, explicitly or by "*".
+func acceptsEncoding(accept, encoding string) (bool) {
+    return encodingQuality(accept, encoding) > 0
+}
+
+// encodingQuality returns the q value an Accept-Encoding header gives
+// encoding, 0 when not accepted.
+func encodingQuality(accept, encoding string) (float64) {
+    return float64(ParseAccept(accept).Quality(encoding))
+}
@@ -0,0 +1,67 @@
+package httputil_test
+
+import (
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "time"
+    "testing/fstest"
+    "github.com/princeofdatamining/golib/httputil"
+)
+
+func TestStatic(t *testing.T) () {
+    modtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+    fsys := fstest.MapFS{
+        "app.js": { Data: []byte("console.log(1)"), ModTime: modtime },
+        "app.js.gz": { Data: []byte("gzipped"), ModTime: modtime },
+        "docs/index.html": { Data: []byte("<h1>docs</h1>"), ModTime: modtime },
+        "embedded.txt": { Data: []byte("0123456789") },
+    }
+    router := httputil.NewRouter()
+    router.Handle("/static/<file:path>", httputil.METHOD_GET, httputil.StaticHandler(fsys, &httputil.StaticOptions{
+        Precompressed: true,
+        CacheControl: "max-age=60",
+    }))
+
+    serve := func (path string, header map[string]string) (*httptest.ResponseRecorder) {
+        req := httptest.NewRequest(httputil.METHOD_GET, "http://example.com" + path, nil)
+        for key, value := range header {
+            req.Header.Set(key, value)
+        }
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, req)
+        return w
+    }
+
+    w := serve("/static/app.js", nil)
+    if w.Code != http.StatusOK || w.Body.String() != "console.log(1)" || w.Header().Get(httputil.HEADER_CACHE_CONTROL) != "max-age=60" {
+        t.Fatalf("app.js must be served, but got %d %q\n", w.Code, w.Body.String())
+    }
+    if ct := w.Header().Get(httputil.HEADER_CONTENT_TYPE); ct != httputil.ContentTypeByFilename("app.js") {
+        t.Fatalf("unexpected content type %q\n", ct)
+    }
+    etag := w.Header().Get(httputil.HEADER_ETAG)
+    if w := serve("/static/app.js", map[string]string{ httputil.HEADER_IF_NONE_MATCH: etag }); w.Code != http.StatusNotModified {
+        t.Fatalf("If-None-Match must reply 304, but got %d\n", w.Code)
+    }
+    if w := serve("/static/app.js", map[string]string{ httputil.HEADER_ACCEPT_ENCODING: "br;q=0, gzip" }); w.Body.String() != "gzipped" || w.Header().Get(httputil.HEADER_CONTENT_ENCODING) != "gzip" {
+        t.Fatalf("app.js.gz must be served, but got %q\n", w.Body.String())
+    }
+    if w := serve("/static/app.js", map[string]string{ httputil.HEADER_ACCEPT_ENCODING: "gzip;q=0" }); w.Body.String() != "console.log(1)" {
+        t.Fatalf("gzip;q=0 must get app.js, but got %q\n", w.Body.String())
+    }
+    if w := serve("/static/embedded.txt", map[string]string{ httputil.HEADER_RANGE: "bytes=2-4" }); w.Code != http.StatusPartialContent || w.Body.String() != "234" || w.Header().Get(httputil.HEADER_ETAG) == "" {
+        t.Fatalf("range must reply 206, but got %d %q\n", w.Code, w.Body.String())
+    }
+    if w := serve("/static/docs", nil); w.Code != http.StatusMovedPermanently || w.Header().Get(httputil.HEADER_LOCATION) != "/static/docs/" {
+        t.Fatalf("directory must redirect, but got %d\n", w.Code)
+    }
+    if w := serve("/static/docs/", nil); w.Body.String() != "<h1>docs</h1>" {
+        t.Fatalf("index must be served, but got %q\n", w.Body.String())
+    }
+    for _, path := range []string{ "/static/../router.go", "/static/docs/../../x", "/static/%2e%2e/x", "/static/missing" } {
+        if w := serve(path, nil); w.Code != http.StatusNotFound {
+            t.Fatalf("%s must reply 404, but got %d\n", path, w.Code)
+        }
+    }
+}
@@ -0,0 +1,200 @@
+package httputil
+
+import (
+    "strings"
+)
+
+/*
+    Dynamic rules whose parts each fill a whole path segment and use the
+    default, int, float or (last) path filter are matched segment by
+    segment in a trie; other rules keep their combined regexps.
+
+    /users/<id:int>/posts       trie
+    /static/<file:path>         trie
+    /files/<name>.json          regexp, part shares its segment
+    /<code:re:[a-z]{2}>/home    regexp, filter with conf
+
+    Every dynamic rule has a registration order, the match with the lowest
+    order wins whichever matcher found it, like the combined regexps did.
+//*/
+
+// paramMark stands for a dynamic part while splitting a rule in segments.
+const paramMark = "\x00"
+
+type segmentCheck func (segment string) (bool)
+
+// The checks accept exactly what the filter regexps accept on one segment.
+var segmentChecks = map[string]segmentCheck{
+    "re": func (s string) (bool) { return s != "" },
+    "int": func (s string) (bool) {
+        s = strings.TrimPrefix(s, "-")
+        if s == "" {
+            return false
+        }
+        for i := 0; i < len(s); i++ {
+            if s[i] < '0' || s[i] > '9' {
+                return false
+            }
+        }
+        return true
+    },
+    "float": func (s string) (bool) {
+        s = strings.TrimPrefix(s, "-")
+        if s == "" {
+            return false
+        }
+        for i := 0; i < len(s); i++ {
+            if (s[i] < '0' || s[i] > '9') && s[i] != '.' {
+                return false
+            }
+        }
+        return true
+    },
+}
+
+// `.+` of the path filter does not match newlines
+func checkRest(s string) (bool) { return s != "" && strings.IndexByte(s, '\n') < 0 }
+
+type trieNode struct {
+    static      map[string]*trieNode
+    params      []*trieParam
+    // rules ending at this node
+    leaves      []*trieLeaf
+    // rules ending with a path filter consuming the rest
+    rests       []*trieLeaf
+}
+type trieParam struct {
+    filter  string
+    check   segmentCheck
+    node    *trieNode
+}
+type trieLeaf struct {
+    rule    string
+    order   int
+    parts   []*builderPart
+    targets map[string]*Route
+}
+
+func newTrieNode() (*trieNode) {
+    return &trieNode{
+        static: make(map[string]*trieNode),
+    }
+}
+
+// trieSegments splits a parsed rule in segments, ok is false when the
+// rule needs its regexp.
+func trieSegments(parts [][]string) (segments []string, filters []string, ok bool) {
+    var template string
+    for _, part := range parts {
+        prefix, filter, conf := part[0], part[2], part[3]
+        if prefix != "" {
+            if strings.Contains(prefix, paramMark) {
+                return
+            }
+            template += prefix
+            continue
+        }
+        if filter == "" {
+            filter = "re"
+        }
+        if _, known := segmentChecks[filter]; !known && filter != "path" || conf != "" {
+            return
+        }
+        template += paramMark
+        filters = append(filters, filter)
+    }
+    segments = strings.Split(template, "/")
+    n := 0
+    for i, segment := range segments {
+        if !strings.Contains(segment, paramMark) {
+            continue
+        }
+        if segment != paramMark {
+            return
+        }
+        // the path filter may span segments, only as the last part
+        if filters[n] == "path" && i != len(segments)-1 {
+            return
+        }
+        n++
+    }
+    return segments, filters, true
+}
+
+func (this *trieNode) insert(segments, filters []string, leaf *trieLeaf) () {
+    node, n := this, 0
+    for i, segment := range segments {
+        if segment != paramMark {
+            child, ok := node.static[segment]
+            if !ok {
+                child = newTrieNode()
+                node.static[segment] = child
+            }
+            node = child
+            continue
+        }
+        filter := filters[n]
+        n++
+        if filter == "path" && i == len(segments)-1 {
+            node.rests = append(node.rests, leaf)
+            return
+        }
+        var param *trieParam
+        for _, p := range node.params {
+            if p.filter == filter {
+                param = p
+                break
+            }
+        }
+        if param == nil {
+            param = &trieParam{
+                filter: filter,
+                check: segmentChecks[filter],
+                node: newTrieNode(),
+            }
+            node.params = append(node.params, param)
+        }
+        node = param.node
+    }
+    node.leaves = append(node.leaves, leaf)
+}
+
+type trieMatch struct {
+    leaf    *trieLeaf
+    values  []string
+}
+
+// lookup finds the matching rule registered first.
+func (this *trieNode) lookup(path string) (match trieMatch) {
+    segments := strings.Split(path, "/")
+    this.search(segments, 0, make([]string, 0, 8), &match)
+    return
+}
+func (this *trieNode) search(segments []string, i int, values []string, best *trieMatch) () {
+    better := func (leaf *trieLeaf) (bool) { return best.leaf == nil || leaf.order < best.leaf.order }
+    if i == len(segments) {
+        for _, leaf := range this.leaves {
+            if better(leaf) {
+                best.leaf, best.values = leaf, append([]string(nil), values...)
+            }
+        }
+        return
+    }
+    if len(this.rests) > 0 {
+        if rest := strings.Join(segments[i:], "/"); checkRest(rest) {
+            for _, leaf := range this.rests {
+                if better(leaf) {
+                    best.leaf, best.values = leaf, append(append([]string(nil), values...), rest)
+                }
+            }
+        }
+    }
+    if child, ok := this.static[segments[i]]; ok {
+        child.search(segments, i+1, values, best)
+    }
+    for _, param := range this.params {
+        if param.check(segments[i]) {
+            param.node.search(segments, i+1, append(values, segments[i]), best)
+        }
+    }
+}
@@ -5,6 +5,7 @@ import (
     "reflect"
     "strings"
     "strconv"
+    "github.com/princeofdatamining/golib/fieldutil"
     "github.com/princeofdatamining/golib/sqlutil/dialect"
 )
 
@@ -27,8 +28,8 @@ func (this *tableMap) newColumn(f reflect.StructField) () {
     }
     fields := strings.Split(f.Tag.Get("db"), ",")
     var (
-        sprimary, sunique, sindex string
-        bprimary, bunique, bindex bool
+        sprimary, sunique, sindex, sfulltext string
+        bprimary, bunique, bindex, bfulltext bool
         named bool
         err error
     )
@@ -46,6 +47,8 @@ func (this *tableMap) newColumn(f reflect.StructField) () {
                 col.unique = true
             case "index":
                 col.index = true
+            case "fulltext":
+                bfulltext = true
             case "notnull":
                 col.notnull =  true
             default:
@@ -69,6 +72,8 @@ func (this *tableMap) newColumn(f reflect.StructField) () {
             sunique , bunique  = parts[1], true
         case "index":
             sindex  , bindex   = parts[1], true
+        case "fulltext":
+            sfulltext, bfulltext = parts[1], true
         case "type":
             col.newtype = parts[1]
         case "size":
@@ -93,6 +98,9 @@ func (this *tableMap) newColumn(f reflect.StructField) () {
     if this.pseudo {
         return 
     }
+    if err = col.buildRules(f.Tag.Get("validate")); err != nil && this.buildErr == nil {
+        this.buildErr = err
+    }
     if col.autoincr {
         this.autoincrCol = col
     }
@@ -109,6 +117,7 @@ func (this *tableMap) newColumn(f reflect.StructField) () {
     this.addCombinedKey(this.primaries, bprimary, sprimary, col)
     this.addCombinedKey(this.uniques  , bunique , sunique , col)
     this.addCombinedKey(this.indexes  , bindex  , sindex  , col)
+    this.addCombinedKey(this.fulltexts, bfulltext, sfulltext, col)
 }
 func (this *tableMap) addCombinedKey(list map[string][]dialect.ColumnMeta, work bool, key string, col *columnMap) {
     if !work {
@@ -166,6 +175,9 @@ type columnMap struct {
     hasDefault  bool
     defaults    string
     comment     string
+
+    required    bool
+    rules       []*fieldutil.Rule
 }
 func (this *columnMap) GetColumnName() (string) { return this.columnName }
 func (this *columnMap) GetFieldName() (string) { return this.fieldName }
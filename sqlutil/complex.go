@@ -8,6 +8,12 @@ import (
     "github.com/princeofdatamining/golib/sqlutil/dialect"
 )
 
+var (
+    errfMatchNoFullText = errFormatFactory("Match: table %q has no fulltext key on %q")
+    errfMatchNoDialect = errFormatFactory("Match: dialect of table %q does not support fulltext")
+    errfMatchNoText = errFormatFactory("Match: no text to search in table %q")
+)
+
 var (
     sFrom    = "FROM"
     sAs      = "AS"
@@ -42,12 +48,15 @@ type SQLQuery struct {
     orders  string
     limits  string
     suffixs []string
+    match       func (bind int) (*dialect.MatchClause)
+    err         error
     //
     page_grouping   bool
     page_maxNav     int
     page_perRows    int
     page_sql        string
     page_sql_count  string
+    page_count_args []interface{}
     page_allRows    int
     page_count      int
     page_first      int
@@ -133,6 +142,64 @@ func (this *SQLQuery) SetLimit(s string) (*SQLQuery) {
     this.limits = s
     return this
 }
+// Match searches text in the columns of a `db:"fulltext"` key, empty
+// columns select the only/default key. Rows are ordered by relevance
+// unless SetOrderBy is used. The text is bound after the arguments of
+// GetAll/GetOne/InitPage/GetPage, which may thus not bind variables of
+// a HAVING clause.
+func (this *SQLQuery) Match(columns, text string) (*SQLQuery) {
+    ft, ok := this.dialect.(dialect.FullTexter)
+    if !ok {
+        this.err = errfMatchNoDialect(this.table.tableName)
+        return this
+    }
+    if strings.TrimSpace(text) == "" {
+        this.err = errfMatchNoText(this.table.tableName)
+        return this
+    }
+    key, cols, found := this.table.findFullText(columns)
+    if !found {
+        this.err = errfMatchNoFullText(this.table.tableName, columns)
+        return this
+    }
+    this.match = func (bind int) (*dialect.MatchClause) {
+        return ft.MatchSQL(this.table.schemaName, this.table.tableName, this.as, key, text, bind, cols...)
+    }
+    return this
+}
+func (this *tableMap) findFullText(columns string) (key string, cols []dialect.ColumnMeta, found bool) {
+    var names []string
+    for _, name := range strings.Split(columns, ",") {
+        if name = strings.TrimSpace(name); name != "" {
+            names = append(names, name)
+        }
+    }
+    keys := this.fullTextKeys()
+    if len(names) <= 0 {
+        if len(keys) == 1 {
+            return keys[0], this.fulltexts[keys[0]], true
+        }
+        cols, found = this.fulltexts[""]
+        return "", cols, found
+    }
+    for _, key = range keys {
+        if cols = this.fulltexts[key]; len(cols) != len(names) {
+            continue
+        }
+        found = true
+        for _, col := range cols {
+            in := false
+            for _, name := range names {
+                in = in || col.GetColumnName() == name
+            }
+            found = found && in
+        }
+        if found {
+            return
+        }
+    }
+    return "", nil, false
+}
 func (this *SQLQuery) SetSuffixes(suffixes ...string) (*SQLQuery) {
     this.suffixs = suffixes
     return this
@@ -143,11 +210,27 @@ var (
     reGroup = regexp.MustCompile("^\\s*" + sGroupBy)
     reSemicolon = regexp.MustCompile(";\\s*$")
 )
+// MakeSQL returns the query, in page mode with a %s placeholder for the
+// LIMIT clause. The text of Match is not bound, use MakeSQLArgs.
 func (this *SQLQuery) MakeSQL(pageMode bool, suffixes ...string) (s string) {
+    s, _ = this.makeSQL(pageMode, "%s", nil, suffixes)
+    return
+}
+// MakeSQLArgs returns the query and args followed by the text of Match.
+func (this *SQLQuery) MakeSQLArgs(args []interface{}, suffixes ...string) (string, []interface{}) {
+    return this.makeSQL(false, "", args, suffixes)
+}
+// makeSQL appends page, the LIMIT clause, in page mode. It prepares the
+// COUNT(*) query of the rows too.
+func (this *SQLQuery) makeSQL(pageMode bool, page string, args []interface{}, suffixes []string) (s string, allArgs []interface{}) {
     selSQL := this.dialect.SelectSQL(this.table.schemaName, this.table.tableName)
     if this.as != "" {
         selSQL = reTableAs.ReplaceAllString(selSQL, "${0} " + sAs + " " + this.as)
     }
+    var match *dialect.MatchClause
+    if this.match != nil {
+        match = this.match(len(args))
+    }
     var suffix string
     if this.groups != "" {
         suffix += " " + this.groups
@@ -156,8 +239,15 @@ func (this *SQLQuery) MakeSQL(pageMode bool, suffixes ...string) (s string) {
     if this.having != "" {
         suffix += " " + this.having
     }
+    var scoreArgs []interface{}
     if this.orders != "" {
         suffix += " " + this.orders
+    } else if match != nil {
+        suffix += " " + sOrderBy + " " + match.Score
+        if match.Desc {
+            suffix += " DESC"
+        }
+        scoreArgs = match.ScoreArgs
     }
     if this.limits != "" && !pageMode {
         suffix += " " + this.limits
@@ -170,21 +260,36 @@ func (this *SQLQuery) MakeSQL(pageMode bool, suffixes ...string) (s string) {
             this.page_grouping = reGroup.MatchString(sfx)
         }
     }
-    this.page_sql_count = fmt.Sprintf(selSQL, "", "COUNT(*)", this.joins, this.wheres, "")
-    var page_suffix string
-    if pageMode {
-        page_suffix = " %s"
+    joins, wheres := this.joins, this.wheres
+    allArgs = append([]interface{}{}, args...)
+    if match != nil {
+        if joins = strings.TrimSpace(joins + " " + match.Join); wheres == "" || wheres == "1" {
+            wheres = match.Where
+        } else {
+            wheres = "(" + wheres + ") AND " + match.Where
+        }
+        allArgs = append(allArgs, match.WhereArgs...)
+    }
+    this.page_sql_count = fmt.Sprintf(selSQL, "", "COUNT(*)", joins, wheres, "")
+    this.page_count_args = allArgs
+    allArgs = append(allArgs[:len(allArgs):len(allArgs)], scoreArgs...)
+    if pageMode && page != "" {
+        suffix += " " + page
     }
-    return fmt.Sprintf(selSQL, "", this.fields, this.joins, this.wheres, suffix + page_suffix)
+    return fmt.Sprintf(selSQL, "", this.fields, joins, wheres, suffix), allArgs
 }
 func (this *SQLQuery) get (all bool, holder interface{}, exec SQLExecutor,                       args ...interface{}) (rows int64, err error) {
+    if this.err != nil {
+        return 0, this.err
+    }
     if exec == nil {
         exec = this.table
     }
+    query, args := this.makeSQL(false, "", args, this.suffixs)
     if all {
-        rows, err = exec.SelectAll(holder, this.MakeSQL(false, this.suffixs...), args...)
+        rows, err = exec.SelectAll(holder, query, args...)
     } else {
-        err       = exec.SelectOne(holder, this.MakeSQL(false, this.suffixs...), args...)
+        err       = exec.SelectOne(holder, query, args...)
     }
     return 
 }
@@ -204,15 +309,23 @@ func (this *SQLQuery) SetPageMode(maxNavPage, rowsPerPage int) () {
         this.page_perRows = nRowsPerPage
     }
 }
+// InitPage counts the rows to page through. An error of the count query,
+// or of building the query e.g. Match without text, is kept and returned
+// by GetPage, which then runs no query.
 func (this *SQLQuery) InitPage(args ...interface{}) () {
-    this.page_sql = this.MakeSQL(true, this.suffixs...)
-    //
-    if this.page_grouping {
-        s := reSemicolon.ReplaceAllString(this.page_sql, "")
-        this.page_sql_count = fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS IPaging;", fmt.Sprintf(s, ""))
+    var allRows int
+    if this.err == nil {
+        var pageArgs []interface{}
+        this.page_sql, pageArgs = this.makeSQL(true, "", args, this.suffixs)
+        //
+        if this.page_grouping {
+            s := reSemicolon.ReplaceAllString(this.page_sql, "")
+            this.page_sql_count = "SELECT COUNT(*) FROM (" + s + ") AS IPaging;"
+            this.page_count_args = pageArgs
+        }
+        rows, err := this.table.SelectInt(this.page_sql_count, this.page_count_args...)
+        allRows, this.err = int(rows), err
     }
-    rows, _ := this.table.SelectInt(this.page_sql_count, args...)
-    allRows := int(rows)
     //
     this.page_allRows = allRows
     this.page_count = (allRows + this.page_perRows - 1) / this.page_perRows
@@ -234,6 +347,9 @@ func (this *SQLQuery) InitPage2(fields, where string, args ...interface{}) () {
     this.InitPage(args...)
 }
 func (this *SQLQuery) GetPage(slices interface{}, pageNo int, args ...interface{}) (rows int64, err error) {
+    if this.err != nil {
+        return 0, this.err
+    }
     if (this.page_allRows <= 0) {
         return 
     }
@@ -255,7 +371,7 @@ func (this *SQLQuery) GetPage(slices interface{}, pageNo int, args ...interface{
         }
     }
     start := this.page_perRows * (this.page_select-this.page_first)
-    s := fmt.Sprintf(this.page_sql, fmt.Sprintf("%s %d,%d", sLimit, start, this.page_perRows))
+    s, args := this.makeSQL(true, fmt.Sprintf("%s %d,%d", sLimit, start, this.page_perRows), args, this.suffixs)
     return this.table.SelectAll(slices, s, args...)
 }
 var HTML_Paging = `
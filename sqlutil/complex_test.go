@@ -0,0 +1,121 @@
+package sqlutil
+
+import (
+    "reflect"
+    "sort"
+    "strings"
+    "testing"
+    "github.com/princeofdatamining/golib/sqlutil/dialect"
+)
+
+type testArticle struct {
+    Id      int64   `db:"primary"`
+    Title   string  `db:"fulltext"`
+    Body    string  `db:"fulltext"`
+    Draft   bool
+}
+
+func newTestArticles(t *testing.T) (*dbMap, TableMap) {
+    dbmap := newTestDbMap(t)
+    table, _ := dbmap.AddTable(testArticle{}, "articles")
+    if _, err := dbmap.CreateTables(false); err != nil {
+        if strings.Contains(err.Error(), "no such module: fts5") {
+            t.Skip("sqlite3 built without fts5, use -tags sqlite_fts5")
+        }
+        t.Fatal(err)
+    }
+    for i, title := range []string{ "100% cotton", "cotton %s shirts", "wool socks", "silk scarf", "cotton socks" } {
+        if _, err := dbmap.Insert(&testArticle{ Id: int64(i + 1), Title: title, Body: "body of " + title, Draft: i == 4 }); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return dbmap, table
+}
+
+func TestMatch(t *testing.T) () {
+    _, table := newTestArticles(t)
+    for text, want := range map[string][]int64{
+        "cotton": { 1, 2 },
+        "100%": { 1 },
+        "%s": { 2 },
+        `wool OR "socks`: nil,
+        `wool "socks`: { 3 },
+        "body NEAR": nil,
+    } {
+        var list []*testArticle
+        if _, err := NewSQLQuery(table).SetFields("").SetWhere("draft = ?").Match("", text).GetAll(&list, nil, false); err != nil {
+            t.Fatalf("match %q: %v", text, err)
+        }
+        var ids []int64
+        for _, a := range list {
+            ids = append(ids, a.Id)
+        }
+        sort.Slice(ids, func (i, j int) (bool) { return ids[i] < ids[j] })
+        if !reflect.DeepEqual(ids, want) {
+            t.Errorf("match %q: %v, want %v", text, ids, want)
+        }
+    }
+
+    query := NewSQLQuery(table).SetFields("").Match("title, body", "cotton")
+    query.SetPageMode(5, 1)
+    query.InitPage()
+    var list []*testArticle
+    if _, err := query.GetPage(&list, 2); err != nil || len(list) != 1 {
+        t.Fatalf("page 2: %v %v", list, err)
+    }
+    if query.page_allRows != 3 || query.page_count != 3 {
+        t.Fatalf("paging %d rows in %d pages", query.page_allRows, query.page_count)
+    }
+
+    if _, err := NewSQLQuery(table).Match("", " ").GetAll(&list, nil); err == nil {
+        t.Fatalf("empty text searched")
+    }
+    if _, err := NewSQLQuery(table).Match("title", "x").GetAll(&list, nil); err == nil {
+        t.Fatalf("search on columns without key")
+    }
+    query = NewSQLQuery(table).Match("", "")
+    query.InitPage()
+    if _, err := query.GetPage(&list, 1); err == nil {
+        t.Fatalf("page of empty search")
+    }
+}
+
+func TestMatchArgs(t *testing.T) () {
+    for name, want := range map[string]string{
+        "mysql": "WHERE (a = ?) AND MATCH (x.`title`, x.`body`) AGAINST (? IN NATURAL LANGUAGE MODE)  ORDER BY MATCH (x.`title`, x.`body`) AGAINST (? IN NATURAL LANGUAGE MODE) DESC;",
+        "postgres": "WHERE (a = $0) AND to_tsvector('simple', coalesce(x.`title`, '') || ' ' || coalesce(x.`body`, '')) @@ plainto_tsquery('simple', $1)  ORDER BY ts_rank(",
+    } {
+        d, _ := dialect.Open(name, map[string]string{})
+        dbmap := NewDbMap(nil, d)
+        table, _ := dbmap.AddTable(testArticle{}, "articles")
+        query, args := NewSQLQuery(table, "x").SetFields("").SetWhere("a = " + d.BindVar(0)).Match("", "it's 100%").MakeSQLArgs([]interface{}{ 1 })
+        if !strings.Contains(query, want) {
+            t.Errorf("%s: %s", name, query)
+        }
+        if strings.Contains(query, "100%") {
+            t.Errorf("%s: text in %s", name, query)
+        }
+        if n := strings.Count(query, "?") + strings.Count(query, "$"); n != len(args) && name == "mysql" {
+            t.Errorf("%s: %d variables, %d args", name, n, len(args))
+        }
+        if args[0] != 1 || args[1] != "it's 100%" {
+            t.Errorf("%s: args %v", name, args)
+        }
+    }
+}
+
+func TestPageCountError(t *testing.T) () {
+    dbmap, table := newTestProducts(t)
+    if _, err := dbmap.Insert(&testProduct{ Id: 1, Name: "a" }); err != nil {
+        t.Fatal(err)
+    }
+    query := NewSQLQuery(table).SetFields("").SetWhere("no_such_column = ?")
+    query.InitPage(1)
+    var list []*testProduct
+    if _, err := query.GetPage(&list, 1); err == nil || len(list) != 0 {
+        t.Fatalf("page after a failed count: %v %v", list, err)
+    }
+    if query.page_allRows != 0 || query.page_count != 0 {
+        t.Fatalf("failed count pages %d rows in %d pages", query.page_allRows, query.page_count)
+    }
+}
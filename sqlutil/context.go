@@ -0,0 +1,63 @@
+package sqlutil
+
+import (
+    "context"
+    "time"
+)
+
+/*
+    Statements may run on behalf of a request, its context cancels them
+    and reaches the query hook, e.g. to log the request ID of httputil:
+
+    dbmap.SetQueryHook(func (ctx context.Context, q *QueryInfo) () {
+        log.Printf("request=%s query=%q took=%v err=%v", httputil.RequestID(ctx), q.Query, q.Elapsed, q.Err)
+    })
+
+    db := dbmap.WithContext(r.Context())
+    err := db.SelectOne(&user, "SELECT * FROM users WHERE id=?", id)
+
+    Transactions begun on the copy run with its context.
+//*/
+
+type QueryInfo struct {
+    Query       string
+    Args        []interface{}
+    // including retries
+    Elapsed     time.Duration
+    Err         error
+}
+
+// QueryHook is called after each Exec, Query and QueryRow.
+type QueryHook func (ctx context.Context, info *QueryInfo) ()
+
+// SetQueryHook sets the hook, before copies are made by WithContext.
+func (this *dbMap) SetQueryHook(hook QueryHook) () {
+    this.hook = hook
+}
+
+// WithContext returns a copy running statements with ctx. Tables and
+// settings are shared, register them on the original.
+func (this *dbMap) WithContext(ctx context.Context) (DbMap) {
+    dbmap := *this
+    dbmap.ctx = ctx
+    return &dbmap
+}
+
+func (this *dbMap) context() (context.Context) {
+    if this.ctx == nil {
+        return context.Background()
+    }
+    return this.ctx
+}
+
+func (this *dbMap) traceQuery(start time.Time, query string, args []interface{}, err *error) () {
+    if this.hook == nil {
+        return
+    }
+    this.hook(this.context(), &QueryInfo{
+        Query: query,
+        Args: args,
+        Elapsed: time.Since(start),
+        Err: *err,
+    })
+}
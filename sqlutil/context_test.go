@@ -0,0 +1,48 @@
+package sqlutil
+
+import (
+    "context"
+    "errors"
+    "testing"
+)
+
+func TestQueryHook(t *testing.T) () {
+    dbmap := newTestDbMap(t)
+    var queries []string
+    dbmap.SetQueryHook(func (ctx context.Context, q *QueryInfo) () {
+        queries = append(queries, q.Query)
+    })
+    dbmap.Exec("CREATE TABLE t (a INTEGER)")
+    tx, err := dbmap.Begin()
+    if err != nil {
+        t.Fatal(err)
+    }
+    tx.Exec("INSERT INTO t VALUES (?)", 1)
+    var n int
+    tx.QueryRow("SELECT COUNT(*) FROM t").Scan(&n)
+    if rows, err := tx.Query("SELECT a FROM t WHERE a = ?", 1); err == nil {
+        rows.Close()
+    }
+    tx.Commit()
+    want := []string{ "CREATE TABLE t (a INTEGER)", "INSERT INTO t VALUES (?)", "SELECT COUNT(*) FROM t", "SELECT a FROM t WHERE a = ?" }
+    if len(queries) != len(want) {
+        t.Fatalf("traced %q, want %q", queries, want)
+    }
+    for i := range want {
+        if queries[i] != want[i] {
+            t.Fatalf("traced %q, want %q", queries, want)
+        }
+    }
+
+    // transactions run with the context of their DbMap
+    ctx, cancel := context.WithCancel(context.Background())
+    tx, err = dbmap.WithContext(ctx).Begin()
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer tx.Rollback()
+    cancel()
+    if _, err = tx.Exec("INSERT INTO t VALUES (?)", 2); !errors.Is(err, context.Canceled) {
+        t.Fatalf("exec in canceled transaction: %v", err)
+    }
+}
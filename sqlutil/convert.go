@@ -0,0 +1,45 @@
+package sqlutil
+
+import (
+    "fmt"
+    "reflect"
+    "time"
+    "database/sql/driver"
+)
+
+var (
+    typeTime = reflect.TypeOf(time.Time{})
+)
+
+// exportValue unwraps pointers and driver.Valuer (sql.Null*) fields,
+// nil stands for NULL.
+func exportValue(f reflect.Value) (interface{}) {
+    for f.Kind() == reflect.Ptr {
+        if f.IsNil() {
+            return nil
+        }
+        f = f.Elem()
+    }
+    val := f.Interface()
+    if valuer, ok := val.(driver.Valuer); ok {
+        if v, err := valuer.Value(); err == nil {
+            return v
+        }
+    }
+    return val
+}
+
+// formatValue renders an exported value as text.
+func formatValue(val interface{}) (s string, null bool) {
+    switch v := val.(type) {
+    case nil:
+        return "", true
+    case []byte:
+        return string(v), false
+    case time.Time:
+        return v.Format(time.RFC3339Nano), false
+    case string:
+        return v, false
+    }
+    return fmt.Sprint(val), false
+}
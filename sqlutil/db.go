@@ -2,7 +2,9 @@
 package sqlutil
 
 import (
+    "context"
     "fmt"
+    "time"
     "database/sql"
     "github.com/princeofdatamining/golib/sqlutil/dialect"
 )
@@ -41,7 +43,10 @@ type SQLExecutor interface {
 type DbMap interface {
     SQLExecutor
     Begin() (Transaction, error)
+    Validate(object interface{}) (error)
     //
+    GetDialect() (dialect.Dialect)
+    GetTables() ([]TableMap)
     GetTableByName(t string) (TableMap, bool)
     GetTableByMeta(meta interface{}) (TableMap, bool)
     AddTable(meta interface{}, name string) (TableMap, error)
@@ -52,6 +57,20 @@ type DbMap interface {
     DropTables  (ifExists    bool, args ...interface{}) (sql string, err error)
     DropTableByName(t string, ifExists bool) (error)
     DropTableByMeta(meta interface{}, ifExists bool) (error)
+    //
+    SetStmtCacheSize(n int) ()
+    StmtCacheStats() (StmtCacheStats)
+    ClearStmtCache() ()
+    //
+    SetRetryPolicy(policy *RetryPolicy) ()
+    GetRetryPolicy() (*RetryPolicy)
+    Transact(f func (tx Transaction) (error)) (error)
+    Stats() (DbStats)
+    Ping() (error)
+    HealthCheck(timeout time.Duration) (*Health)
+    //
+    SetQueryHook(hook QueryHook) ()
+    WithContext(ctx context.Context) (DbMap)
 }
 func NewDbMap(db *sql.DB, dialect dialect.Dialect) (DbMap) {
     return &dbMap{
@@ -59,6 +78,9 @@ func NewDbMap(db *sql.DB, dialect dialect.Dialect) (DbMap) {
         dialect: dialect,
 
         tableD:  make(map[string]*tableMap),
+        stmts:   newStmtCache(DefaultStmtCacheSize),
+        retry:   DefaultRetryPolicy,
+        counters: &retryCounters{},
     }
 }
 type dbMap struct {
@@ -69,18 +91,53 @@ type dbMap struct {
     tableD  map[string]*tableMap
 
     pseudos []*tableMap
+
+    stmts   *stmtCache
+
+    retry   *RetryPolicy
+    // shared with the copies made by WithContext
+    counters *retryCounters
+
+    ctx     context.Context
+    hook    QueryHook
 }
-func (this *dbMap) Exec(query string, args ...interface{}) (sql.Result, error) {
+func (this *dbMap) Exec(query string, args ...interface{}) (res sql.Result, err error) {
     fmt.Println("Db.Exec:", query)
-    return this.db.Exec(query, args...)
+    defer this.traceQuery(time.Now(), query, args, &err)
+    ctx := this.context()
+    err = this.withRetry(query, func () (err error) {
+        if this.useStmt(args) {
+            res, err = this.stmtExec(ctx, query, args)
+        } else {
+            res, err = this.db.ExecContext(ctx, query, args...)
+        }
+        return
+    })
+    return
 }
-func (this *dbMap) Query(query string, args ...interface{}) (*sql.Rows, error) {
+func (this *dbMap) Query(query string, args ...interface{}) (rows *sql.Rows, err error) {
     fmt.Println("Db.Query:", query)
-    return this.db.Query(query, args...)
+    defer this.traceQuery(time.Now(), query, args, &err)
+    ctx := this.context()
+    err = this.withRetry(query, func () (err error) {
+        if this.useStmt(args) {
+            rows, err = this.stmtQuery(ctx, query, args)
+        } else {
+            rows, err = this.db.QueryContext(ctx, query, args...)
+        }
+        return
+    })
+    return
 }
 func (this *dbMap) QueryRow(query string, args ...interface{}) (*sql.Row) {
     fmt.Println("Db.QueryRow:", query)
-    return this.db.QueryRow(query, args...)
+    // errors show up on Scan
+    var err error
+    defer this.traceQuery(time.Now(), query, args, &err)
+    if this.useStmt(args) {
+        return this.stmtQueryRow(this.context(), query, args)
+    }
+    return this.db.QueryRowContext(this.context(), query, args...)
 }
 
 var _, _, _ SQLExecutor = NewDbMap(nil, nil), &tableMap{}, &txMap{}
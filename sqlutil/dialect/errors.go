@@ -0,0 +1,124 @@
+package dialect
+
+import (
+    "context"
+    "errors"
+    "io"
+    "net"
+    "reflect"
+    "strconv"
+    "strings"
+    "syscall"
+    "database/sql/driver"
+)
+
+type ErrorClass int
+
+const (
+    ErrorOther          ErrorClass = iota
+    // connection unusable before the statement was sent
+    ErrorBadConn
+    // connection lost while the statement may have run
+    ErrorLostConn
+    ErrorDeadlock
+    ErrorSerialization
+    ErrorLockTimeout
+    // network timeout, the statement may have run
+    ErrorTimeout
+)
+
+func (this ErrorClass) String() (string) {
+    switch this {
+    case ErrorBadConn:
+        return "bad connection"
+    case ErrorLostConn:
+        return "lost connection"
+    case ErrorDeadlock:
+        return "deadlock"
+    case ErrorSerialization:
+        return "serialization failure"
+    case ErrorLockTimeout:
+        return "lock timeout"
+    case ErrorTimeout:
+        return "timeout"
+    }
+    return "other"
+}
+
+// Transient errors may succeed when the statement (or the transaction
+// it aborted) runs again.
+func (this ErrorClass) Transient() (bool) { return this != ErrorOther }
+
+// ErrorClassifier is implemented by dialects recognizing their driver
+// errors; drivers are not imported, codes are read from the error text
+// or from SQLState()/Code/Number when present.
+type ErrorClassifier interface {
+    ClassifyError(err error) (ErrorClass)
+}
+
+// ClassifyError recognizes driver independent failures.
+func ClassifyError(err error) (ErrorClass) {
+    var netErr net.Error
+    switch {
+    case err == nil:
+        return ErrorOther
+    // the caller gave up, trying again would not help
+    case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+        return ErrorOther
+    case errors.Is(err, driver.ErrBadConn):
+        return ErrorBadConn
+    case errors.As(err, &netErr) && netErr.Timeout():
+        return ErrorTimeout
+    case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, net.ErrClosed),
+        errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNABORTED), errors.Is(err, syscall.EPIPE):
+        return ErrorLostConn
+    }
+    msg := strings.ToLower(err.Error())
+    for _, s := range []string{"bad connection", "broken pipe", "connection reset", "connection refused", "invalid connection", "unexpected eof"} {
+        if strings.Contains(msg, s) {
+            return ErrorLostConn
+        }
+    }
+    return ErrorOther
+}
+
+// errorCode reads SQLState() or an exported Code/Number field of the
+// driver error, e.g. pq.Error.Code or mysql.MySQLError.Number.
+func errorCode(err error) (string) {
+    if s, ok := err.(interface{ SQLState() string }); ok {
+        return s.SQLState()
+    }
+    v := reflect.Indirect(reflect.ValueOf(err))
+    if v.Kind() != reflect.Struct {
+        return ""
+    }
+    for _, name := range []string{"Code", "Number"} {
+        if f := v.FieldByName(name); f.IsValid() {
+            switch f.Kind() {
+            case reflect.String:
+                return f.String()
+            case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+                return strconv.FormatInt(f.Int(), 10)
+            case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+                return strconv.FormatUint(f.Uint(), 10)
+            }
+        }
+    }
+    return ""
+}
+
+func classifyBy(err error, codes map[string]ErrorClass, texts map[string]ErrorClass) (ErrorClass) {
+    if class := ClassifyError(err); class != ErrorOther {
+        return class
+    }
+    if class, ok := codes[errorCode(err)]; ok {
+        return class
+    }
+    msg := strings.ToLower(err.Error())
+    for text, class := range texts {
+        if strings.Contains(msg, text) {
+            return class
+        }
+    }
+    return ErrorOther
+}
@@ -0,0 +1,65 @@
+package dialect
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "os"
+    "syscall"
+    "testing"
+    "database/sql/driver"
+)
+
+type testMySQLError struct {
+    Number  uint16
+    Message string
+}
+func (this *testMySQLError) Error() (string) { return this.Message }
+
+type testPQError string
+func (this testPQError) Error() (string) { return "pq: " + string(this) }
+func (this testPQError) SQLState() (string) { return string(this) }
+
+type testSqliteError struct {
+    Code    int
+}
+func (this testSqliteError) Error() (string) { return "sqlite error" }
+
+func TestClassifyError(t *testing.T) () {
+    mysql, _ := Open("mysql", map[string]string{})
+    postgres, _ := Open("postgres", map[string]string{})
+    sqlite, _ := Open("sqlite", map[string]string{})
+    for _, in := range []struct{
+        d       Dialect
+        err     error
+        class   ErrorClass
+    }{
+        { mysql, driver.ErrBadConn, ErrorBadConn },
+        { mysql, io.ErrUnexpectedEOF, ErrorLostConn },
+        { mysql, fmt.Errorf("query: %w", driver.ErrBadConn), ErrorBadConn },
+        { mysql, &net.OpError{ Op: "read", Err: errors.New("connection reset by peer") }, ErrorLostConn },
+        { mysql, &net.OpError{ Op: "write", Err: os.NewSyscallError("write", syscall.ECONNRESET) }, ErrorLostConn },
+        { mysql, fmt.Errorf("read: %w", net.ErrClosed), ErrorLostConn },
+        { mysql, &net.OpError{ Op: "read", Err: os.ErrDeadlineExceeded }, ErrorTimeout },
+        { mysql, &net.DNSError{ Err: "no such host", Name: "db" }, ErrorOther },
+        { mysql, context.DeadlineExceeded, ErrorOther },
+        { mysql, &testMySQLError{ 1213, "Deadlock found" }, ErrorDeadlock },
+        { mysql, &testMySQLError{ 1205, "Lock wait timeout exceeded" }, ErrorLockTimeout },
+        { mysql, &testMySQLError{ 1062, "Duplicate entry" }, ErrorOther },
+        { postgres, testPQError("40001"), ErrorSerialization },
+        { postgres, testPQError("40P01"), ErrorDeadlock },
+        { postgres, testPQError("23505"), ErrorOther },
+        { sqlite, testSqliteError{ 5 }, ErrorLockTimeout },
+        { sqlite, errors.New("database table is locked: t"), ErrorLockTimeout },
+        { sqlite, errors.New("UNIQUE constraint failed"), ErrorOther },
+    } {
+        if class := in.d.(ErrorClassifier).ClassifyError(in.err); class != in.class {
+            t.Errorf("%T %v: %v, want %v", in.d, in.err, class, in.class)
+        }
+        if in.class.Transient() != (in.class != ErrorOther) {
+            t.Errorf("%v transient %v", in.class, in.class.Transient())
+        }
+    }
+}
@@ -0,0 +1,52 @@
+package dialect
+
+import (
+    "strings"
+)
+
+// FullTexter is implemented by dialects supporting `db:"fulltext"` keys.
+type FullTexter interface {
+    // clause inside CREATE TABLE, "" when the index is created apart
+    CreateFullTextKey(tableName, key string, cols ...ColumnMeta) (string)
+    // statements run after CREATE TABLE / before DROP TABLE, "" if none
+    CreateFullTextSQL(schemaName, tableName, key string, ifNotExists bool, cols ...ColumnMeta) (string)
+    DropFullTextSQL(schemaName, tableName, key string, ifExists bool) (string)
+    // condition for searching text in the key columns, its bind
+    // variables are numbered from bind
+    MatchSQL(schemaName, tableName, alias, key, text string, bind int, cols ...ColumnMeta) (*MatchClause)
+}
+
+// MatchClause is a fulltext search, the text is only passed in the
+// argument lists.
+type MatchClause struct {
+    // added to the FROM clause
+    Join        string
+    Where       string
+    WhereArgs   []interface{}
+    // orders by relevance, descending when Desc
+    Score       string
+    ScoreArgs   []interface{}
+    Desc        bool
+}
+
+// FullTextName names the index/virtual table of a fulltext key, the
+// default key of bare `fulltext` fields is "".
+func FullTextName(tableName, key string) (string) {
+    if key == "" {
+        return tableName + "_ft"
+    }
+    return key
+}
+
+// QuoteString makes a standard SQL string literal.
+func QuoteString(s string) (string) {
+    return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+func fullTextColumns(this Dialect, prefix string, cols []ColumnMeta) (string) {
+    names := make([]string, len(cols))
+    for i, col := range cols {
+        names[i] = prefix + this.QuoteField(col.GetColumnName())
+    }
+    return strings.Join(names, ", ")
+}
@@ -0,0 +1,152 @@
+package dialect
+
+import (
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+    "database/sql"
+)
+
+// Inspector reads table/column/index metadata back from a live database.
+// Registered dialects implement it, check with a type assertion.
+type Inspector interface {
+    ListTables(q Queryer, schemaName string) ([]string, error)
+    InspectTable(q Queryer, schemaName, tableName string) (*TableInfo, error)
+}
+
+type TableInfo struct {
+    Schema      string
+    Name        string
+    Comment     string
+    Columns     []*ColumnInfo
+    Indexes     []*IndexInfo
+}
+
+type ColumnInfo struct {
+    Name        string
+    Type        string
+    Size        int
+    Precision   int
+    NotNull     bool
+    AutoIncr    bool
+    HasDefault  bool
+    Default     string
+    Comment     string
+}
+
+type IndexInfo struct {
+    Name        string
+    Primary     bool
+    Unique      bool
+    Columns     []string
+}
+
+func (this *TableInfo) Column(name string) (*ColumnInfo) {
+    for _, col := range this.Columns {
+        if col.Name == name {
+            return col
+        }
+    }
+    return nil
+}
+func (this *TableInfo) addIndexColumn(name string, primary, unique bool, column string) () {
+    for _, index := range this.Indexes {
+        if index.Name == name {
+            index.Columns = append(index.Columns, column)
+            return
+        }
+    }
+    this.Indexes = append(this.Indexes, &IndexInfo{
+        Name: name,
+        Primary: primary,
+        Unique: unique,
+        Columns: []string{column},
+    })
+}
+
+//
+
+var reColumnType = regexp.MustCompile(`^\s*([^(]*[^(\s])\s*(?:\(\s*(\d+)\s*(?:,\s*(\d+)\s*)?\))?\s*(.*?)\s*$`)
+
+// ParseColumnType splits "decimal(10,2) unsigned" into "decimal unsigned", 10, 2.
+// Missing size or precision are returned as -1.
+func ParseColumnType(s string) (name string, size, precision int) {
+    size, precision = -1, -1
+    subs := reColumnType.FindStringSubmatch(s)
+    if subs == nil {
+        return strings.ToLower(strings.TrimSpace(s)), size, precision
+    }
+    name = strings.ToLower(subs[1])
+    if subs[4] != "" {
+        name += " " + strings.ToLower(subs[4])
+    }
+    if subs[2] != "" {
+        size, _ = strconv.Atoi(subs[2])
+    }
+    if subs[3] != "" {
+        precision, _ = strconv.Atoi(subs[3])
+    }
+    return
+}
+
+// UnquoteDefault strips the literal quoting databases put around
+// textual column defaults, e.g. 'abc' or 'abc'::character varying.
+func UnquoteDefault(s string) (string) {
+    s = strings.TrimSpace(s)
+    if i := strings.LastIndex(s, "'::"); i > 0 && s[0] == '\'' {
+        s = s[:i+1]
+    }
+    if n := len(s); n >= 2 && s[0] == '\'' && s[n-1] == '\'' {
+        s = strings.Replace(s[1:n-1], "''", "'", -1)
+    }
+    return s
+}
+
+// scanRowMaps reads every row as column name => text, for statements
+// (like sqlite PRAGMAs) whose result columns vary between versions.
+func scanRowMaps(rows *sql.Rows) (list []map[string]sql.NullString, err error) {
+    defer rows.Close()
+    var cols []string
+    if cols, err = rows.Columns(); err != nil {
+        return
+    }
+    for rows.Next() {
+        values := make([]sql.NullString, len(cols))
+        dest := make([]interface{}, len(cols))
+        for i := range values {
+            dest[i] = &values[i]
+        }
+        if err = rows.Scan(dest...); err != nil {
+            return
+        }
+        m := make(map[string]sql.NullString, len(cols))
+        for i, col := range cols {
+            m[strings.ToLower(col)] = values[i]
+        }
+        list = append(list, m)
+    }
+    err = rows.Err()
+    return
+}
+
+func queryStrings(q Queryer, query string, args ...interface{}) (list []string, err error) {
+    rows, err := q.Query(query, args...)
+    if err != nil {
+        return
+    }
+    defer rows.Close()
+    for rows.Next() {
+        var s string
+        if err = rows.Scan(&s); err != nil {
+            return
+        }
+        list = append(list, s)
+    }
+    err = rows.Err()
+    return
+}
+
+func inspectError(dialect, tableName string, err error) (error) {
+    return fmt.Errorf("%s: inspect table %q: %v", dialect, tableName, err)
+}
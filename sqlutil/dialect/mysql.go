@@ -4,6 +4,8 @@ package dialect
 import (
     "fmt"
     "reflect"
+    "strings"
+    "database/sql"
 )
 
 func init() () {
@@ -148,3 +150,156 @@ func (this *mysqlDialect) SelectSQL(schemaName, tableName string) (string) {
 func (this *mysqlDialect) DeleteSQL(schemaName, tableName string) (string) {
     return DeleteSQL(this, schemaName, tableName)
 }
+
+//
+
+var _ Inspector = &mysqlDialect{}
+
+func (this *mysqlDialect) ListTables(q Queryer, schemaName string) ([]string, error) {
+    return queryStrings(q, "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = " + mysqlSchema(schemaName) + " AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME", mysqlSchemaArgs(schemaName)...)
+}
+func mysqlSchema(schemaName string) (string) {
+    if schemaName == "" {
+        return "DATABASE()"
+    }
+    return "?"
+}
+func mysqlSchemaArgs(schemaName string, args ...interface{}) ([]interface{}) {
+    if schemaName == "" {
+        return args
+    }
+    return append([]interface{}{schemaName}, args...)
+}
+func (this *mysqlDialect) InspectTable(q Queryer, schemaName, tableName string) (info *TableInfo, err error) {
+    info = &TableInfo{
+        Schema: schemaName,
+        Name: tableName,
+    }
+    where := " WHERE TABLE_SCHEMA = " + mysqlSchema(schemaName) + " AND TABLE_NAME = ?"
+    args := mysqlSchemaArgs(schemaName, tableName)
+    if err = q.QueryRow("SELECT TABLE_COMMENT FROM information_schema.TABLES" + where, args...).Scan(&info.Comment); err != nil {
+        return nil, inspectError("mysql", tableName, err)
+    }
+    if err = this.inspectColumns(q, info, where, args); err != nil {
+        return nil, inspectError("mysql", tableName, err)
+    }
+    if err = this.inspectIndexes(q, info, where, args); err != nil {
+        return nil, inspectError("mysql", tableName, err)
+    }
+    return
+}
+func (this *mysqlDialect) inspectColumns(q Queryer, info *TableInfo, where string, args []interface{}) (err error) {
+    rows, err := q.Query("SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT, EXTRA, COLUMN_COMMENT FROM information_schema.COLUMNS" + where + " ORDER BY ORDINAL_POSITION", args...)
+    if err != nil {
+        return
+    }
+    defer rows.Close()
+    for rows.Next() {
+        var (
+            name, coltype, nullable, extra, comment string
+            defaults sql.NullString
+        )
+        if err = rows.Scan(&name, &coltype, &nullable, &defaults, &extra, &comment); err != nil {
+            return
+        }
+        col := &ColumnInfo{
+            Name: name,
+            NotNull: nullable == "NO",
+            AutoIncr: strings.Contains(strings.ToLower(extra), "auto_increment"),
+            HasDefault: defaults.Valid,
+            Default: UnquoteDefault(defaults.String),
+            Comment: comment,
+        }
+        col.Type, col.Size, col.Precision = ParseColumnType(coltype)
+        switch {
+        case col.Type == "tinyint" && col.Size == 1:
+            // BOOLEAN is an alias of TINYINT(1)
+            col.Type, col.Size = "boolean", -1
+        case strings.HasSuffix(col.Type, "int"), strings.HasSuffix(col.Type, "int unsigned"):
+            // display width only
+            col.Size = -1
+        }
+        info.Columns = append(info.Columns, col)
+    }
+    return rows.Err()
+}
+func (this *mysqlDialect) inspectIndexes(q Queryer, info *TableInfo, where string, args []interface{}) (err error) {
+    rows, err := q.Query("SELECT INDEX_NAME, NON_UNIQUE, COLUMN_NAME FROM information_schema.STATISTICS" + where + " ORDER BY INDEX_NAME, SEQ_IN_INDEX", args...)
+    if err != nil {
+        return
+    }
+    defer rows.Close()
+    for rows.Next() {
+        var (
+            name, column string
+            nonUnique int
+        )
+        if err = rows.Scan(&name, &nonUnique, &column); err != nil {
+            return
+        }
+        info.addIndexColumn(name, name == "PRIMARY", nonUnique == 0, column)
+    }
+    return rows.Err()
+}
+
+//
+
+var _ FullTexter = &mysqlDialect{}
+
+func (this *mysqlDialect) CreateFullTextKey(tableName, key string, cols ...ColumnMeta) (string) {
+    return fmt.Sprintf("  FULLTEXT KEY %s (%s)", this.QuoteField(FullTextName(tableName, key)), quoteColumns(this, cols))
+}
+func (this *mysqlDialect) CreateFullTextSQL(schemaName, tableName, key string, ifNotExists bool, cols ...ColumnMeta) (string) { return "" }
+func (this *mysqlDialect) DropFullTextSQL(schemaName, tableName, key string, ifExists bool) (string) { return "" }
+func (this *mysqlDialect) MatchSQL(schemaName, tableName, alias, key, text string, bind int, cols ...ColumnMeta) (*MatchClause) {
+    prefix := alias
+    if prefix == "" {
+        prefix = this.QuoteTable(schemaName, tableName)
+    }
+    match := "MATCH (%s) AGAINST (%s IN NATURAL LANGUAGE MODE)"
+    columns := fullTextColumns(this, prefix + ".", cols)
+    return &MatchClause{
+        Where: fmt.Sprintf(match, columns, this.BindVar(bind)),
+        WhereArgs: []interface{}{ text },
+        Score: fmt.Sprintf(match, columns, this.BindVar(bind + 1)),
+        ScoreArgs: []interface{}{ text },
+        Desc: true,
+    }
+}
+
+var _ Upserter = &mysqlDialect{}
+
+func (this *mysqlDialect) InsertConflictSQL(keys, updates []ColumnMeta, mode ConflictMode) (head, tail string, err error) {
+    switch {
+    case mode == ConflictIgnore:
+        return "INSERT IGNORE INTO", "", nil
+    case mode == ConflictUpdate && len(updates) <= 0:
+        return "", "", ErrConflictUpdate
+    case mode == ConflictUpdate:
+        return insertInto, " ON DUPLICATE KEY UPDATE " + conflictSets(this, updates, "VALUES(%s)"), nil
+    }
+    return insertInto, "", nil
+}
+// placeholders of one prepared statement
+func (this *mysqlDialect) MaxBindVars() (int) { return 65535 }
+
+var _ ErrorClassifier = &mysqlDialect{}
+
+var (
+    mysqlErrorCodes = map[string]ErrorClass{
+        "1205": ErrorLockTimeout,
+        "1213": ErrorDeadlock,
+        "1053": ErrorLostConn,
+        "2006": ErrorLostConn,
+        "2013": ErrorLostConn,
+    }
+    mysqlErrorTexts = map[string]ErrorClass{
+        "error 1205": ErrorLockTimeout,
+        "error 1213": ErrorDeadlock,
+        "error 2006": ErrorLostConn,
+        "error 2013": ErrorLostConn,
+        "server has gone away": ErrorLostConn,
+    }
+)
+
+func (this *mysqlDialect) ClassifyError(err error) (ErrorClass) { return classifyBy(err, mysqlErrorCodes, mysqlErrorTexts) }
@@ -5,6 +5,7 @@ import (
     "fmt"
     "reflect"
     "strings"
+    "database/sql"
 )
 
 func init() () {
@@ -14,12 +15,16 @@ func init() () {
         if dialect.suffix, ok = params["suffix"]; !ok {
             //
         }
+        if dialect.tsconfig, ok = params["tsconfig"]; !ok {
+            dialect.tsconfig = "simple"
+        }
         return dialect
     })
 }
 
 type postgresDialect struct {
     suffix string
+    tsconfig string
 }
 
 func (this *postgresDialect) QuoteField(f string) (string) { return QuoteField(strings.ToLower(f)) }
@@ -180,3 +185,200 @@ func (this *postgresDialect) SelectSQL(schemaName, tableName string) (string) {
 func (this *postgresDialect) DeleteSQL(schemaName, tableName string) (string) {
     return DeleteSQL(this, schemaName, tableName)
 }
+
+//
+
+var _ Inspector = &postgresDialect{}
+
+func postgresSchema(schemaName string) (string) {
+    if schemaName == "" {
+        return "public"
+    }
+    return schemaName
+}
+func (this *postgresDialect) ListTables(q Queryer, schemaName string) ([]string, error) {
+    return queryStrings(q, "SELECT table_name FROM information_schema.tables WHERE table_schema = $1 AND table_type = 'BASE TABLE' ORDER BY table_name", postgresSchema(schemaName))
+}
+func (this *postgresDialect) InspectTable(q Queryer, schemaName, tableName string) (info *TableInfo, err error) {
+    info = &TableInfo{
+        Schema: schemaName,
+        Name: tableName,
+    }
+    var comment sql.NullString
+    if err = q.QueryRow("SELECT obj_description((quote_ident($1) || '.' || quote_ident($2))::regclass, 'pg_class')", postgresSchema(schemaName), tableName).Scan(&comment); err != nil {
+        return nil, inspectError("postgres", tableName, err)
+    }
+    info.Comment = comment.String
+    if err = this.inspectColumns(q, info); err != nil {
+        return nil, inspectError("postgres", tableName, err)
+    }
+    if err = this.inspectIndexes(q, info); err != nil {
+        return nil, inspectError("postgres", tableName, err)
+    }
+    return
+}
+func (this *postgresDialect) inspectColumns(q Queryer, info *TableInfo) (err error) {
+    rows, err := q.Query(`SELECT column_name, data_type, character_maximum_length, numeric_precision, numeric_scale, is_nullable, column_default,
+    col_description((quote_ident(table_schema) || '.' || quote_ident(table_name))::regclass, ordinal_position)
+FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2 ORDER BY ordinal_position`, postgresSchema(info.Schema), info.Name)
+    if err != nil {
+        return
+    }
+    defer rows.Close()
+    for rows.Next() {
+        var (
+            name, datatype, nullable string
+            maxlen, numprec, numscale sql.NullInt64
+            defaults, comment sql.NullString
+        )
+        if err = rows.Scan(&name, &datatype, &maxlen, &numprec, &numscale, &nullable, &defaults, &comment); err != nil {
+            return
+        }
+        col := &ColumnInfo{
+            Name: name,
+            Type: datatype,
+            Size: -1,
+            Precision: -1,
+            NotNull: nullable == "NO",
+            Comment: comment.String,
+        }
+        switch datatype {
+        case "character varying":
+            col.Type = "varchar"
+        case "character":
+            col.Type = "char"
+        case "timestamp without time zone":
+            col.Type = "timestamp"
+        }
+        switch col.Type {
+        case "varchar", "char":
+            if maxlen.Valid {
+                col.Size = int(maxlen.Int64)
+            }
+        case "numeric":
+            if numprec.Valid {
+                col.Size = int(numprec.Int64)
+            }
+            if numscale.Valid {
+                col.Precision = int(numscale.Int64)
+            }
+        }
+        if defaults.Valid {
+            if strings.HasPrefix(defaults.String, "nextval(") {
+                // serial / bigserial
+                col.AutoIncr = true
+                switch col.Type {
+                case "integer":
+                    col.Type = "serial"
+                case "bigint":
+                    col.Type = "bigserial"
+                }
+            } else {
+                col.HasDefault, col.Default = true, UnquoteDefault(defaults.String)
+            }
+        }
+        info.Columns = append(info.Columns, col)
+    }
+    return rows.Err()
+}
+func (this *postgresDialect) inspectIndexes(q Queryer, info *TableInfo) (err error) {
+    rows, err := q.Query(`SELECT i.relname, ix.indisprimary, ix.indisunique, a.attname
+FROM pg_index ix
+    JOIN pg_class t ON t.oid = ix.indrelid
+    JOIN pg_class i ON i.oid = ix.indexrelid
+    JOIN pg_namespace n ON n.oid = t.relnamespace
+    JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord) ON true
+    JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+WHERE n.nspname = $1 AND t.relname = $2
+ORDER BY i.relname, k.ord`, postgresSchema(info.Schema), info.Name)
+    if err != nil {
+        return
+    }
+    defer rows.Close()
+    for rows.Next() {
+        var (
+            name, column string
+            primary, unique bool
+        )
+        if err = rows.Scan(&name, &primary, &unique, &column); err != nil {
+            return
+        }
+        info.addIndexColumn(name, primary, unique, column)
+    }
+    return rows.Err()
+}
+
+//
+
+var _ FullTexter = &postgresDialect{}
+
+func (this *postgresDialect) tsvector(prefix string, cols []ColumnMeta) (string) {
+    exprs := make([]string, len(cols))
+    for i, col := range cols {
+        exprs[i] = fmt.Sprintf("coalesce(%s%s, '')", prefix, this.QuoteField(col.GetColumnName()))
+    }
+    return fmt.Sprintf("to_tsvector(%s, %s)", QuoteString(this.tsconfig), strings.Join(exprs, " || ' ' || "))
+}
+func (this *postgresDialect) CreateFullTextKey(tableName, key string, cols ...ColumnMeta) (string) { return "" }
+func (this *postgresDialect) CreateFullTextSQL(schemaName, tableName, key string, ifNotExists bool, cols ...ColumnMeta) (string) {
+    addIfNotExists := ""
+    if ifNotExists {
+        addIfNotExists = " IF NOT EXISTS"
+    }
+    return fmt.Sprintf("CREATE INDEX%s %s ON %s USING GIN (%s);", addIfNotExists, this.QuoteField(FullTextName(tableName, key)), this.QuoteTable(schemaName, tableName), this.tsvector("", cols))
+}
+// the GIN index is dropped along with its table
+func (this *postgresDialect) DropFullTextSQL(schemaName, tableName, key string, ifExists bool) (string) { return "" }
+func (this *postgresDialect) MatchSQL(schemaName, tableName, alias, key, text string, bind int, cols ...ColumnMeta) (*MatchClause) {
+    prefix := alias
+    if prefix == "" {
+        prefix = this.QuoteTable(schemaName, tableName)
+    }
+    vector := this.tsvector(prefix + ".", cols)
+    // the score reuses the numbered variable of the condition
+    query := fmt.Sprintf("plainto_tsquery(%s, %s)", QuoteString(this.tsconfig), this.BindVar(bind))
+    return &MatchClause{
+        Where: fmt.Sprintf("%s @@ %s", vector, query),
+        WhereArgs: []interface{}{ text },
+        Score: fmt.Sprintf("ts_rank(%s, %s)", vector, query),
+        Desc: true,
+    }
+}
+
+var _ Upserter = &postgresDialect{}
+
+func (this *postgresDialect) InsertConflictSQL(keys, updates []ColumnMeta, mode ConflictMode) (head, tail string, err error) {
+    switch {
+    case mode == ConflictIgnore:
+        return insertInto, " ON CONFLICT DO NOTHING", nil
+    case mode == ConflictUpdate && (len(updates) <= 0 || len(keys) <= 0):
+        return "", "", ErrConflictUpdate
+    case mode == ConflictUpdate:
+        return insertInto, fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", quoteColumns(this, keys), conflictSets(this, updates, "EXCLUDED.%s")), nil
+    }
+    return insertInto, "", nil
+}
+// parameters of one extended query protocol message
+func (this *postgresDialect) MaxBindVars() (int) { return 65535 }
+
+var _ ErrorClassifier = &postgresDialect{}
+
+var (
+    postgresErrorCodes = map[string]ErrorClass{
+        "40001": ErrorSerialization,
+        "40P01": ErrorDeadlock,
+        "55P03": ErrorLockTimeout,
+        "57P01": ErrorLostConn,
+        "08000": ErrorLostConn,
+        "08003": ErrorLostConn,
+        "08006": ErrorLostConn,
+    }
+    postgresErrorTexts = map[string]ErrorClass{
+        "could not serialize access": ErrorSerialization,
+        "deadlock detected": ErrorDeadlock,
+        "could not obtain lock": ErrorLockTimeout,
+        "terminating connection": ErrorLostConn,
+    }
+)
+
+func (this *postgresDialect) ClassifyError(err error) (ErrorClass) { return classifyBy(err, postgresErrorCodes, postgresErrorTexts) }
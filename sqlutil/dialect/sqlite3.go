@@ -4,6 +4,9 @@ package dialect
 import (
     "fmt"
     "reflect"
+    "strings"
+    "strconv"
+    "database/sql"
 )
 
 func init() () {
@@ -137,3 +140,171 @@ func (this *sqliteDialect) SelectSQL(schemaName, tableName string) (string) {
 func (this *sqliteDialect) DeleteSQL(schemaName, tableName string) (string) {
     return DeleteSQL(this, schemaName, tableName)
 }
+
+//
+
+var _ Inspector = &sqliteDialect{}
+
+func (this *sqliteDialect) ListTables(q Queryer, schemaName string) ([]string, error) {
+    return queryStrings(q, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+}
+func (this *sqliteDialect) InspectTable(q Queryer, schemaName, tableName string) (info *TableInfo, err error) {
+    info = &TableInfo{
+        Schema: schemaName,
+        Name: tableName,
+    }
+    var ddl string
+    if err = q.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", tableName).Scan(&ddl); err != nil {
+        return nil, inspectError("sqlite", tableName, err)
+    }
+    if err = this.inspectColumns(q, info, strings.Contains(strings.ToUpper(ddl), "AUTOINCREMENT")); err != nil {
+        return nil, inspectError("sqlite", tableName, err)
+    }
+    if err = this.inspectIndexes(q, info); err != nil {
+        return nil, inspectError("sqlite", tableName, err)
+    }
+    return
+}
+func (this *sqliteDialect) inspectColumns(q Queryer, info *TableInfo, autoincr bool) (err error) {
+    rows, err := q.Query(fmt.Sprintf("PRAGMA table_info(%s)", this.QuoteField(info.Name)))
+    if err != nil {
+        return
+    }
+    list, err := scanRowMaps(rows)
+    if err != nil {
+        return
+    }
+    var primaries []string
+    for _, m := range list {
+        col := &ColumnInfo{
+            Name: m["name"].String,
+            NotNull: m["notnull"].String == "1",
+            HasDefault: m["dflt_value"].Valid,
+            Default: UnquoteDefault(m["dflt_value"].String),
+        }
+        col.Type, col.Size, col.Precision = ParseColumnType(m["type"].String)
+        if pk, _ := strconv.Atoi(m["pk"].String); pk > 0 {
+            for len(primaries) < pk {
+                primaries = append(primaries, "")
+            }
+            primaries[pk-1] = col.Name
+            col.AutoIncr = autoincr && col.Type == "integer"
+        }
+        info.Columns = append(info.Columns, col)
+    }
+    // rowid tables do not report their primary key in index_list
+    for _, name := range primaries {
+        info.addIndexColumn("PRIMARY", true, true, name)
+    }
+    return
+}
+func (this *sqliteDialect) inspectIndexes(q Queryer, info *TableInfo) (err error) {
+    rows, err := q.Query(fmt.Sprintf("PRAGMA index_list(%s)", this.QuoteField(info.Name)))
+    if err != nil {
+        return
+    }
+    list, err := scanRowMaps(rows)
+    if err != nil {
+        return
+    }
+    for _, m := range list {
+        if m["origin"].String == "pk" {
+            continue
+        }
+        name, unique := m["name"].String, m["unique"].String == "1"
+        if rows, err = q.Query(fmt.Sprintf("PRAGMA index_info(%s)", this.QuoteField(name))); err != nil {
+            return
+        }
+        var cols []map[string]sql.NullString
+        if cols, err = scanRowMaps(rows); err != nil {
+            return
+        }
+        for _, c := range cols {
+            info.addIndexColumn(name, false, unique, c["name"].String)
+        }
+    }
+    return
+}
+
+//
+
+var _ FullTexter = &sqliteDialect{}
+
+// FTS5 external content table kept in sync by triggers.
+func (this *sqliteDialect) CreateFullTextKey(tableName, key string, cols ...ColumnMeta) (string) { return "" }
+func (this *sqliteDialect) CreateFullTextSQL(schemaName, tableName, key string, ifNotExists bool, cols ...ColumnMeta) (string) {
+    addIfNotExists := ""
+    if ifNotExists {
+        addIfNotExists = " IF NOT EXISTS"
+    }
+    fts := FullTextName(tableName, key)
+    qfts, qtable := this.QuoteField(fts), this.QuoteTable(schemaName, tableName)
+    names := quoteColumns(this, cols)
+    newValues, oldValues := fullTextColumns(this, "new.", cols), fullTextColumns(this, "old.", cols)
+    lines := []string{
+        fmt.Sprintf("CREATE VIRTUAL TABLE%s %s USING fts5(%s, content=%s);", addIfNotExists, qfts, names, QuoteString(tableName)),
+        fmt.Sprintf("CREATE TRIGGER%s %s AFTER INSERT ON %s BEGIN INSERT INTO %s(rowid, %s) VALUES (new.rowid, %s); END;",
+            addIfNotExists, this.QuoteField(fts + "_ai"), qtable, qfts, names, newValues),
+        fmt.Sprintf("CREATE TRIGGER%s %s AFTER DELETE ON %s BEGIN INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.rowid, %s); END;",
+            addIfNotExists, this.QuoteField(fts + "_ad"), qtable, qfts, qfts, names, oldValues),
+        fmt.Sprintf("CREATE TRIGGER%s %s AFTER UPDATE ON %s BEGIN INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.rowid, %s); INSERT INTO %s(rowid, %s) VALUES (new.rowid, %s); END;",
+            addIfNotExists, this.QuoteField(fts + "_au"), qtable, qfts, qfts, names, oldValues, qfts, names, newValues),
+    }
+    return strings.Join(lines, "\n")
+}
+// triggers go with their table, the virtual table does not
+func (this *sqliteDialect) DropFullTextSQL(schemaName, tableName, key string, ifExists bool) (string) {
+    return DropTableSQL(this, schemaName, FullTextName(tableName, key), ifExists)
+}
+func (this *sqliteDialect) MatchSQL(schemaName, tableName, alias, key, text string, bind int, cols ...ColumnMeta) (*MatchClause) {
+    prefix := alias
+    if prefix == "" {
+        prefix = this.QuoteTable(schemaName, tableName)
+    }
+    fts := this.QuoteField(FullTextName(tableName, key))
+    // quote every word as FTS5 string so its operators stay literal
+    words := strings.Fields(text)
+    for i, word := range words {
+        words[i] = `"` + strings.Replace(word, `"`, `""`, -1) + `"`
+    }
+    return &MatchClause{
+        Join: fmt.Sprintf("JOIN %s ON %s.rowid = %s.rowid", fts, fts, prefix),
+        Where: fmt.Sprintf("%s MATCH %s", fts, this.BindVar(bind)),
+        WhereArgs: []interface{}{ strings.Join(words, " ") },
+        Score: fmt.Sprintf("bm25(%s)", fts),
+    }
+}
+
+var _ Upserter = &sqliteDialect{}
+
+// ON CONFLICT DO UPDATE needs SQLite 3.24, unlike INSERT OR REPLACE it
+// keeps the row and the columns not imported.
+func (this *sqliteDialect) InsertConflictSQL(keys, updates []ColumnMeta, mode ConflictMode) (head, tail string, err error) {
+    switch {
+    case mode == ConflictIgnore:
+        return "INSERT OR IGNORE INTO", "", nil
+    case mode == ConflictUpdate && (len(updates) <= 0 || len(keys) <= 0):
+        return "", "", ErrConflictUpdate
+    case mode == ConflictUpdate:
+        return insertInto, fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", quoteColumns(this, keys), conflictSets(this, updates, "excluded.%s")), nil
+    }
+    return insertInto, "", nil
+}
+// SQLITE_MAX_VARIABLE_NUMBER before SQLite 3.32
+func (this *sqliteDialect) MaxBindVars() (int) { return 999 }
+
+var _ ErrorClassifier = &sqliteDialect{}
+
+var (
+    // SQLITE_BUSY, SQLITE_LOCKED
+    sqliteErrorCodes = map[string]ErrorClass{
+        "5": ErrorLockTimeout,
+        "6": ErrorLockTimeout,
+    }
+    sqliteErrorTexts = map[string]ErrorClass{
+        "database is locked": ErrorLockTimeout,
+        "database table is locked": ErrorLockTimeout,
+    }
+)
+
+func (this *sqliteDialect) ClassifyError(err error) (ErrorClass) { return classifyBy(err, sqliteErrorCodes, sqliteErrorTexts) }
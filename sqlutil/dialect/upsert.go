@@ -0,0 +1,96 @@
+package dialect
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+)
+
+type ConflictMode int
+
+const (
+    ConflictError   ConflictMode = iota
+    ConflictIgnore
+    ConflictUpdate
+)
+
+var ErrConflictUpdate = errors.New("dialect: ConflictUpdate needs key columns and columns to update")
+
+// Upserter is implemented by dialects able to resolve key conflicts
+// of INSERT statements.
+type Upserter interface {
+    // head replaces "INSERT INTO", tail follows the VALUES list
+    InsertConflictSQL(keys, updates []ColumnMeta, mode ConflictMode) (head, tail string, err error)
+}
+
+// BindVarLimiter is implemented by dialects limiting the bind variables
+// of one statement.
+type BindVarLimiter interface {
+    MaxBindVars() (int)
+}
+
+const (
+    insertInto = "INSERT INTO"
+    // when the dialect does not tell, the lowest of the known databases
+    nDefaultMaxBindVars = 999
+)
+
+// MaxBindVars returns how many bind variables one statement may hold.
+func MaxBindVars(this Dialect) (int) {
+    if limiter, ok := this.(BindVarLimiter); ok {
+        return limiter.MaxBindVars()
+    }
+    return nDefaultMaxBindVars
+}
+
+// BatchInsertSQL builds a multi-row INSERT for n rows of cols.
+func BatchInsertSQL(this Dialect, schemaName, tableName string, cols, keys []ColumnMeta, n int, mode ConflictMode) (string, error) {
+    head, tail := insertInto, ""
+    if mode != ConflictError {
+        upserter, ok := this.(Upserter)
+        if !ok {
+            return "", fmt.Errorf("dialect does not support conflict mode %d", mode)
+        }
+        var updates []ColumnMeta
+        for _, col := range cols {
+            isKey := false
+            for _, key := range keys {
+                isKey = isKey || key.GetColumnName() == col.GetColumnName()
+            }
+            if !isKey {
+                updates = append(updates, col)
+            }
+        }
+        var err error
+        if head, tail, err = upserter.InsertConflictSQL(keys, updates, mode); err != nil {
+            return "", err
+        }
+    }
+    values := make([]string, n)
+    vars := make([]string, len(cols))
+    for r := range values {
+        for i := range cols {
+            vars[i] = this.BindVar(r * len(cols) + i)
+        }
+        values[r] = "(" + strings.Join(vars, ", ") + ")"
+    }
+    return fmt.Sprintf("%s %s (%s) VALUES %s%s;", head, this.QuoteTable(schemaName, tableName), quoteColumns(this, cols), strings.Join(values, ", "), tail), nil
+}
+
+// quoteColumns lists the quoted names of cols, comma separated.
+func quoteColumns(this Dialect, cols []ColumnMeta) (string) {
+    names := make([]string, len(cols))
+    for i, col := range cols {
+        names[i] = this.QuoteField(col.GetColumnName())
+    }
+    return strings.Join(names, ", ")
+}
+
+func conflictSets(this Dialect, updates []ColumnMeta, format string) (string) {
+    sets := make([]string, len(updates))
+    for i, col := range updates {
+        name := this.QuoteField(col.GetColumnName())
+        sets[i] = fmt.Sprintf("%s = " + format, name, name)
+    }
+    return strings.Join(sets, ", ")
+}
@@ -0,0 +1,60 @@
+package dialect
+
+import (
+    "reflect"
+    "testing"
+)
+
+type testColumn string
+
+func (this testColumn) GetColumnName() (string) { return string(this) }
+func (this testColumn) GetFieldName() (string) { return string(this) }
+func (this testColumn) GetAutoIncr() (bool) { return false }
+func (this testColumn) GetNotNull() (bool) { return false }
+func (this testColumn) GetGoType() (reflect.Type) { return reflect.TypeOf("") }
+func (this testColumn) GetForceType() (string) { return "" }
+func (this testColumn) GetSize() (int, int) { return -1, -1 }
+func (this testColumn) GetDefault() (bool, string) { return false, "" }
+func (this testColumn) GetComment() (string) { return "" }
+
+func TestBatchInsertSQL(t *testing.T) () {
+    cols := []ColumnMeta{ testColumn("id"), testColumn("name") }
+    keys := cols[:1]
+    for name, want := range map[string][2]string{
+        "mysql": {
+            "INSERT IGNORE INTO `t` (`id`, `name`) VALUES (?, ?), (?, ?);",
+            "INSERT INTO `t` (`id`, `name`) VALUES (?, ?), (?, ?) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`);",
+        },
+        "postgres": {
+            "INSERT INTO `t` (`id`, `name`) VALUES ($0, $1), ($2, $3) ON CONFLICT DO NOTHING;",
+            "INSERT INTO `t` (`id`, `name`) VALUES ($0, $1), ($2, $3) ON CONFLICT (`id`) DO UPDATE SET `name` = EXCLUDED.`name`;",
+        },
+        "sqlite": {
+            "INSERT OR IGNORE INTO `t` (`id`, `name`) VALUES (?, ?), (?, ?);",
+            "INSERT INTO `t` (`id`, `name`) VALUES (?, ?), (?, ?) ON CONFLICT (`id`) DO UPDATE SET `name` = excluded.`name`;",
+        },
+    } {
+        d, _ := Open(name, map[string]string{})
+        for i, mode := range []ConflictMode{ ConflictIgnore, ConflictUpdate } {
+            if s, err := BatchInsertSQL(d, "", "t", cols, keys, 2, mode); err != nil || s != want[i] {
+                t.Errorf("%s mode %d: %v\n%s\nwant\n%s", name, mode, err, s, want[i])
+            }
+        }
+        // nothing to update is no silent DO NOTHING
+        if _, err := BatchInsertSQL(d, "", "t", keys, keys, 1, ConflictUpdate); err != ErrConflictUpdate {
+            t.Errorf("%s without updates: %v", name, err)
+        }
+        if _, err := BatchInsertSQL(d, "", "t", cols, nil, 1, ConflictUpdate); err != ErrConflictUpdate && name != "mysql" {
+            t.Errorf("%s without keys: %v", name, err)
+        }
+    }
+}
+
+func TestMaxBindVars(t *testing.T) () {
+    for name, want := range map[string]int{ "mysql": 65535, "postgres": 65535, "sqlite": 999 } {
+        d, _ := Open(name, map[string]string{})
+        if n := MaxBindVars(d); n != want {
+            t.Errorf("%s: %d variables", name, n)
+        }
+    }
+}
@@ -0,0 +1,93 @@
+package sqlutil
+
+import (
+    "io"
+    "reflect"
+    "strings"
+    "encoding/csv"
+    "encoding/json"
+)
+
+func (this *tableMap) exportColumns() (cols []*columnMap) {
+    for _, col := range this.columns {
+        if !col.transient {
+            cols = append(cols, col)
+        }
+    }
+    return
+}
+
+// exportRows streams the rows matching where, f gets each row scanned
+// into a new object of the mapped type.
+func (this *tableMap) exportRows(cols []*columnMap, where string, args []interface{}, f func (obj reflect.Value) (error)) (rows int64, err error) {
+    fields := make([]string, len(cols))
+    for i, col := range cols {
+        fields[i] = this.dbmap.dialect.QuoteField(col.columnName)
+    }
+    if len(args) == 1 {
+        where, args = this.dbmap.maybeExpandNamedQuery(where, args)
+    }
+    res, err := this.Query(this.makeSelectSQL(strings.Join(fields, ", "), where, ""), args...)
+    if err != nil {
+        return
+    }
+    defer res.Close()
+    dest := make([]interface{}, len(cols))
+    for res.Next() {
+        obj := reflect.New(this.gotype).Elem()
+        for i, col := range cols {
+            dest[i] = obj.FieldByName(col.fieldName).Addr().Interface()
+        }
+        if err = res.Scan(dest...); err != nil {
+            return
+        }
+        if err = f(obj); err != nil {
+            return
+        }
+        rows++
+    }
+    err = res.Err()
+    return
+}
+
+// ExportCSV writes a header of column names then one record per row,
+// NULL is written as an empty field.
+func (this *tableMap) ExportCSV(w io.Writer, where string, args ...interface{}) (rows int64, err error) {
+    cols := this.exportColumns()
+    cw := csv.NewWriter(w)
+    record := make([]string, len(cols))
+    for i, col := range cols {
+        record[i] = col.columnName
+    }
+    if err = cw.Write(record); err != nil {
+        return
+    }
+    rows, err = this.exportRows(cols, where, args, func (obj reflect.Value) (error) {
+        for i, col := range cols {
+            record[i], _ = formatValue(exportValue(obj.FieldByName(col.fieldName)))
+        }
+        return cw.Write(record)
+    })
+    cw.Flush()
+    if err == nil {
+        err = cw.Error()
+    }
+    return
+}
+
+// ExportJSONL writes one JSON object per line keyed by column names.
+func (this *tableMap) ExportJSONL(w io.Writer, where string, args ...interface{}) (int64, error) {
+    cols := this.exportColumns()
+    enc := json.NewEncoder(w)
+    return this.exportRows(cols, where, args, func (obj reflect.Value) (error) {
+        m := make(map[string]interface{}, len(cols))
+        for _, col := range cols {
+            val := exportValue(obj.FieldByName(col.fieldName))
+            if b, ok := val.([]byte); ok {
+                val = string(b)
+            }
+            m[col.columnName] = val
+        }
+        return enc.Encode(m)
+    })
+}
@@ -0,0 +1,52 @@
+package sqlutil
+
+import (
+    "bytes"
+    "testing"
+    "time"
+    "database/sql"
+)
+
+type testProduct struct {
+    Id      int64           `db:"primary"`
+    Name    string          `db:"size=32"`
+    Price   float64
+    Stock   sql.NullInt64
+    Updated time.Time
+    Tag     *string
+}
+
+func newTestProducts(t *testing.T) (*dbMap, TableMap) {
+    dbmap := newTestDbMap(t)
+    table, _ := dbmap.AddTable(testProduct{}, "products")
+    if _, err := dbmap.CreateTables(false); err != nil {
+        t.Fatal(err)
+    }
+    return dbmap, table
+}
+
+func TestExport(t *testing.T) () {
+    dbmap, table := newTestProducts(t)
+    tag := "new, \"hot\""
+    updated := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+    dbmap.Insert(
+        &testProduct{ Id: 1, Name: "pen", Price: 1.5, Stock: sql.NullInt64{ Int64: 10, Valid: true }, Updated: updated, Tag: &tag },
+        &testProduct{ Id: 2, Name: "ink", Price: 3, Updated: updated },
+    )
+
+    var buf bytes.Buffer
+    rows, err := table.ExportCSV(&buf, "price > ?", 0)
+    want := "id,name,price,stock,updated,tag\n" +
+        "1,pen,1.5,10,2026-01-02T03:04:05Z,\"new, \"\"hot\"\"\"\n" +
+        "2,ink,3,,2026-01-02T03:04:05Z,\n"
+    if err != nil || rows != 2 || buf.String() != want {
+        t.Fatalf("csv %d %v:\n%s", rows, err, buf.String())
+    }
+
+    buf.Reset()
+    rows, err = table.ExportJSONL(&buf, "id = ?", 2)
+    want = `{"id":2,"name":"ink","price":3,"stock":null,"tag":null,"updated":"2026-01-02T03:04:05Z"}` + "\n"
+    if err != nil || rows != 1 || buf.String() != want {
+        t.Fatalf("jsonl %d %v:\n%s", rows, err, buf.String())
+    }
+}
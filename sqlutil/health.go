@@ -0,0 +1,91 @@
+package sqlutil
+
+import (
+    "time"
+    "context"
+    "net/http"
+    "sync/atomic"
+    "encoding/json"
+    "database/sql"
+)
+
+// DbStats combines the connection pool, statement cache and retry counters.
+type DbStats struct {
+    sql.DBStats
+    Stmts           StmtCacheStats
+    // statements (or transactions) tried again
+    Retries         int64
+    // transient failures returned after the last attempt
+    RetryExhausted  int64
+}
+
+func (this *dbMap) Stats() (stats DbStats) {
+    stats.DBStats = this.db.Stats()
+    stats.Stmts = this.StmtCacheStats()
+    stats.Retries = atomic.LoadInt64(&this.counters.retries)
+    stats.RetryExhausted = atomic.LoadInt64(&this.counters.giveups)
+    return
+}
+
+// Ping checks the connection, retried like an idempotent statement.
+func (this *dbMap) Ping() (error) {
+    return this.withRetry("SELECT", this.db.Ping)
+}
+
+const (
+    HealthUp = "up"
+    HealthDown = "down"
+)
+
+// Health is the result of a health check, ready to be encoded as JSON.
+type Health struct {
+    Status          string          `json:"status"`
+    Error           string          `json:"error,omitempty"`
+    Latency         time.Duration   `json:"latency"`
+    OpenConnections int             `json:"open_connections"`
+    InUse           int             `json:"in_use"`
+    Idle            int             `json:"idle"`
+    WaitCount       int64           `json:"wait_count"`
+    WaitDuration    time.Duration   `json:"wait_duration"`
+}
+func (this *Health) Up() (bool) { return this.Status == HealthUp }
+
+// HealthCheck pings the database once, giving up after timeout.
+func (this *dbMap) HealthCheck(timeout time.Duration) (health *Health) {
+    ctx, cancel := context.Background(), context.CancelFunc(func () () {})
+    if timeout > 0 {
+        ctx, cancel = context.WithTimeout(ctx, timeout)
+    }
+    defer cancel()
+
+    start := time.Now()
+    err := this.db.PingContext(ctx)
+    stats := this.db.Stats()
+    health = &Health{
+        Status: HealthUp,
+        Latency: time.Since(start),
+        OpenConnections: stats.OpenConnections,
+        InUse: stats.InUse,
+        Idle: stats.Idle,
+        WaitCount: stats.WaitCount,
+        WaitDuration: stats.WaitDuration,
+    }
+    if err != nil {
+        health.Status, health.Error = HealthDown, err.Error()
+    }
+    return
+}
+
+// HealthHandler serves the health check as JSON for readiness probes,
+// answering 503 while the database is down.
+func HealthHandler(dbmap DbMap, timeout time.Duration) (http.Handler) {
+    return http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) () {
+        health := dbmap.HealthCheck(timeout)
+        w.Header().Set("Content-Type", "application/json; charset=utf-8")
+        w.Header().Set("Cache-Control", "no-store")
+        if !health.Up() {
+            w.WriteHeader(http.StatusServiceUnavailable)
+        }
+        json.NewEncoder(w).Encode(health)
+    })
+}
@@ -0,0 +1,262 @@
+package sqlutil
+
+import (
+    "io"
+    "reflect"
+    "strings"
+    "encoding/csv"
+    "encoding/json"
+    "github.com/princeofdatamining/golib/fieldutil"
+    "github.com/princeofdatamining/golib/sqlutil/dialect"
+)
+
+var (
+    errfImportUnknownColumn = errFormatFactory("Import: table %q has no column %q")
+    errfImportRecord = errFormatFactory("Import: record %d, column %q: %v")
+)
+
+const (
+    nImportBatchSize = 100
+)
+
+type ImportOptions struct {
+    // executor of the INSERTs, the table itself by default; pass a
+    // Transaction to import all or nothing
+    Exec            SQLExecutor
+    // rows per INSERT statement, fewer when their bind variables exceed
+    // the limit of the dialect
+    BatchSize       int
+    // ConflictError, ConflictIgnore or ConflictUpdate on key conflicts
+    OnConflict      dialect.ConflictMode
+    // skip fields without a mapped column instead of failing
+    IgnoreUnknown   bool
+    // run `validate` rules on every row
+    Validate        bool
+    // CSV field text standing for NULL, "" by default
+    Null            string
+}
+
+type importBatch struct {
+    table   *tableMap
+    opts    *ImportOptions
+    cols    []*columnMap
+    args    []interface{}
+    n       int
+    total   int64
+}
+func newImportBatch(table *tableMap, opts *ImportOptions) (*importBatch) {
+    if opts == nil {
+        opts = &ImportOptions{}
+    }
+    if opts.Exec == nil {
+        opts.Exec = table
+    }
+    if opts.BatchSize <= 0 {
+        opts.BatchSize = nImportBatchSize
+    }
+    return &importBatch{
+        table: table,
+        opts: opts,
+    }
+}
+func sameColumns(a, b []*columnMap) (bool) {
+    if len(a) != len(b) {
+        return false
+    }
+    for i := range a {
+        if a[i] != b[i] {
+            return false
+        }
+    }
+    return true
+}
+// add queues one object, rows with another column set start a new batch.
+func (this *importBatch) add(cols []*columnMap, obj reflect.Value) (err error) {
+    if this.opts.Validate {
+        if err = this.table.validate(obj); err != nil {
+            return
+        }
+    }
+    if !sameColumns(this.cols, cols) {
+        if err = this.flush(); err != nil {
+            return
+        }
+        this.cols = cols
+    }
+    for _, col := range cols {
+        this.args = append(this.args, obj.FieldByName(col.fieldName).Interface())
+    }
+    if this.n++; this.n >= this.batchSize() {
+        return this.flush()
+    }
+    return
+}
+func (this *importBatch) batchSize() (int) {
+    n := this.opts.BatchSize
+    if len(this.cols) <= 0 {
+        return n
+    }
+    if max := dialect.MaxBindVars(this.table.dbmap.dialect) / len(this.cols); max < n {
+        n = max
+    }
+    if n < 1 {
+        n = 1
+    }
+    return n
+}
+func (this *importBatch) flush() (err error) {
+    if this.n <= 0 {
+        return
+    }
+    metas := make([]dialect.ColumnMeta, len(this.cols))
+    for i, col := range this.cols {
+        metas[i] = col
+    }
+    t := this.table
+    query, err := dialect.BatchInsertSQL(t.dbmap.dialect, t.schemaName, t.tableName, metas, t.getKeyColumns(""), this.n, this.opts.OnConflict)
+    if err != nil {
+        return
+    }
+    if _, err = this.opts.Exec.Exec(query, this.args...); err != nil {
+        return
+    }
+    this.total += int64(this.n)
+    this.args, this.n = this.args[:0], 0
+    return
+}
+
+func (this *tableMap) importColumns(names []string, ignoreUnknown bool) (cols []*columnMap, err error) {
+    cols = make([]*columnMap, len(names))
+    for i, name := range names {
+        col, ok := this.colDict[strings.TrimSpace(name)]
+        if ok && !col.transient {
+            cols[i] = col
+        } else if !ignoreUnknown {
+            return nil, errfImportUnknownColumn(this.tableName, name)
+        }
+    }
+    return
+}
+func compactColumns(cols []*columnMap) (list []*columnMap) {
+    for _, col := range cols {
+        if col != nil {
+            list = append(list, col)
+        }
+    }
+    return
+}
+
+// ImportCSV inserts records whose header names the columns, returns
+// the number of records sent to the database.
+func (this *tableMap) ImportCSV(r io.Reader, opts *ImportOptions) (rows int64, err error) {
+    batch := newImportBatch(this, opts)
+    cr := csv.NewReader(r)
+    header, err := cr.Read()
+    if err != nil {
+        if err == io.EOF {
+            err = nil
+        }
+        return
+    }
+    cols, err := this.importColumns(header, batch.opts.IgnoreUnknown)
+    if err != nil {
+        return
+    }
+    used := compactColumns(cols)
+    for n := 1; ; n++ {
+        var record []string
+        if record, err = cr.Read(); err == io.EOF {
+            break
+        } else if err != nil {
+            return batch.total, err
+        }
+        obj := reflect.New(this.gotype).Elem()
+        for i, col := range cols {
+            if col == nil || i >= len(record) {
+                continue
+            }
+            f := obj.FieldByName(col.fieldName)
+            if record[i] == batch.opts.Null && isNullable(col.gotype) {
+                f.Set(reflect.Zero(f.Type()))
+            } else if err = fieldutil.SetString(f, record[i]); err != nil {
+                return batch.total, errfImportRecord(n, col.columnName, err)
+            }
+        }
+        if err = batch.add(used, obj); err != nil {
+            return batch.total, err
+        }
+    }
+    err = batch.flush()
+    return batch.total, err
+}
+
+// ImportJSONL inserts one JSON object per line keyed by column names.
+func (this *tableMap) ImportJSONL(r io.Reader, opts *ImportOptions) (rows int64, err error) {
+    batch := newImportBatch(this, opts)
+    dec := json.NewDecoder(r)
+    for n := 1; ; n++ {
+        var m map[string]json.RawMessage
+        if err = dec.Decode(&m); err == io.EOF {
+            break
+        } else if err != nil {
+            return batch.total, err
+        }
+        names := make([]string, 0, len(m))
+        for name := range m {
+            names = append(names, name)
+        }
+        var cols []*columnMap
+        if cols, err = this.importColumns(names, batch.opts.IgnoreUnknown); err != nil {
+            return batch.total, err
+        }
+        cols = compactColumns(cols)
+        // keep a stable column order so equal key sets share batches
+        ordered := make([]*columnMap, 0, len(cols))
+        for _, col := range this.columns {
+            for _, c := range cols {
+                if c == col {
+                    ordered = append(ordered, col)
+                }
+            }
+        }
+        obj := reflect.New(this.gotype).Elem()
+        for _, col := range ordered {
+            if err = setFieldJSON(obj.FieldByName(col.fieldName), m[col.columnName]); err != nil {
+                return batch.total, errfImportRecord(n, col.columnName, err)
+            }
+        }
+        if err = batch.add(ordered, obj); err != nil {
+            return batch.total, err
+        }
+    }
+    err = batch.flush()
+    return batch.total, err
+}
+
+func isNullable(t reflect.Type) (bool) {
+    if t.Kind() == reflect.Ptr {
+        return true
+    }
+    _, ok := reflect.New(t).Interface().(interface{ Scan(interface{}) error })
+    return ok && t != typeTime
+}
+
+func setFieldJSON(f reflect.Value, raw json.RawMessage) (err error) {
+    if string(raw) == "null" {
+        f.Set(reflect.Zero(f.Type()))
+        return
+    }
+    var s string
+    isString := json.Unmarshal(raw, &s) == nil
+    if _, ok := f.Addr().Interface().(json.Unmarshaler); !ok && isNullable(f.Type()) && f.Kind() != reflect.Ptr {
+        // sql.Null* types scan the plain value
+        if !isString {
+            s = string(raw)
+        }
+        return fieldutil.SetString(f, s)
+    }
+    if err = json.Unmarshal(raw, f.Addr().Interface()); err != nil && isString {
+        err = fieldutil.SetString(f, s)
+    }
+    return
+}
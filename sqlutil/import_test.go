@@ -0,0 +1,105 @@
+package sqlutil
+
+import (
+    "bytes"
+    "fmt"
+    "strings"
+    "testing"
+    "github.com/princeofdatamining/golib/sqlutil/dialect"
+)
+
+func TestImport(t *testing.T) () {
+    dbmap, table := newTestProducts(t)
+    csv := "id,name,price,stock,updated,tag\n" +
+        "1,pen,1.5,10,2026-01-02 03:04:05,\n" +
+        "2,ink,3,,2026-01-02,blue\n"
+    if rows, err := table.ImportCSV(strings.NewReader(csv), nil); err != nil || rows != 2 {
+        t.Fatalf("csv: %d %v", rows, err)
+    }
+    var got []*testProduct
+    dbmap.SelectAll(&got, "SELECT * FROM products ORDER BY id")
+    if len(got) != 2 || got[0].Stock.Int64 != 10 || got[0].Tag != nil || got[1].Stock.Valid || *got[1].Tag != "blue" || got[1].Updated.Day() != 2 {
+        t.Fatalf("imported %+v", got)
+    }
+
+    // the same keys fail, are skipped or update the imported columns
+    jsonl := `{"id": 1, "name": "pencil"}` + "\n" + `{"id": 3, "name": "pad", "price": 2.5, "stock": 4}` + "\n"
+    if _, err := table.ImportJSONL(strings.NewReader(jsonl), nil); err == nil {
+        t.Fatalf("duplicated key imported")
+    }
+    if rows, err := table.ImportJSONL(strings.NewReader(jsonl), &ImportOptions{ OnConflict: dialect.ConflictIgnore }); err != nil || rows != 2 {
+        t.Fatalf("ignore: %d %v", rows, err)
+    }
+    var name string
+    dbmap.QueryRow("SELECT name FROM products WHERE id = 1").Scan(&name)
+    if name != "pen" {
+        t.Fatalf("ignored row updated: %s", name)
+    }
+    if rows, err := table.ImportJSONL(strings.NewReader(jsonl), &ImportOptions{ OnConflict: dialect.ConflictUpdate }); err != nil || rows != 2 {
+        t.Fatalf("update: %d %v", rows, err)
+    }
+    var price float64
+    dbmap.QueryRow("SELECT name, price FROM products WHERE id = 1").Scan(&name, &price)
+    if name != "pencil" || price != 1.5 {
+        t.Fatalf("upsert replaced the row: %s %v", name, price)
+    }
+
+    // unknown columns and bad values name the record
+    for doc, want := range map[string]string{
+        "id,color\n4,red\n": `Import: table "products" has no column "color"`,
+        "id,price\n4,cheap\n": `Import: record 1, column "price"`,
+    } {
+        if _, err := table.ImportCSV(strings.NewReader(doc), nil); err == nil || !strings.Contains(err.Error(), want) {
+            t.Errorf("%q: %v, want %s", doc, err, want)
+        }
+    }
+    if rows, err := table.ImportCSV(strings.NewReader("id,color\n4,red\n"), &ImportOptions{ IgnoreUnknown: true }); err != nil || rows != 1 {
+        t.Errorf("ignore unknown: %d %v", rows, err)
+    }
+}
+
+func TestImportRoundTrip(t *testing.T) () {
+    _, table := newTestProducts(t)
+    var src bytes.Buffer
+    src.WriteString("id,name,price,updated\n")
+    // 1200 rows of 4 columns exceed the 999 variables of sqlite
+    for i := 1; i <= 1200; i++ {
+        fmt.Fprintf(&src, "%d,n%d,%d.5,2026-01-02\n", i, i, i)
+    }
+    if rows, err := table.ImportCSV(&src, &ImportOptions{ BatchSize: 1000 }); err != nil || rows != 1200 {
+        t.Fatalf("import: %d %v", rows, err)
+    }
+    var csv bytes.Buffer
+    if rows, err := table.ExportCSV(&csv, "id <= 2"); err != nil || rows != 2 {
+        t.Fatalf("export: %d %v", rows, err)
+    }
+    _, other := newTestProducts(t)
+    if rows, err := other.ImportCSV(&csv, nil); err != nil || rows != 2 {
+        t.Fatalf("reimport: %d %v", rows, err)
+    }
+    var got []*testProduct
+    other.SelectAll(&got, "SELECT * FROM products ORDER BY id")
+    if len(got) != 2 || got[1].Name != "n2" || got[1].Price != 2.5 || got[1].Stock.Valid || got[1].Tag != nil {
+        t.Fatalf("reimported %+v", got)
+    }
+}
+
+func TestImportBatchSize(t *testing.T) () {
+    dbmap, table := newTestProducts(t)
+    batch := newImportBatch(table.(*tableMap), &ImportOptions{ BatchSize: 1000 })
+    batch.cols = table.(*tableMap).columns[:2]
+    if n := batch.batchSize(); n != 499 {
+        t.Fatalf("sqlite batch of %d rows", n)
+    }
+    batch.cols = nil
+    if n := batch.batchSize(); n != 1000 {
+        t.Fatalf("batch without columns of %d rows", n)
+    }
+
+    // upserts need keys to update on
+    keyless, _ := dbmap.AddTable(struct{ Name string }{}, "keyless")
+    keyless.Create(false)
+    if _, err := keyless.ImportCSV(strings.NewReader("name\nx\n"), &ImportOptions{ OnConflict: dialect.ConflictUpdate }); err != dialect.ErrConflictUpdate {
+        t.Fatalf("keyless upsert: %v", err)
+    }
+}
@@ -51,6 +51,9 @@ func (this *tableMap) insert(vptr reflect.Value, exec SQLExecutor, execVal []ref
     if err = triggerRun("PreInsert", vptr, execVal); err != nil {
         return 
     }
+    if err = this.validate(vptr.Elem()); err != nil {
+        return 
+    }
     if bind, err = this.bindInsert(); err != nil {
         return 
     }
@@ -0,0 +1,198 @@
+package sqlutil
+
+import (
+    "time"
+    "strings"
+    "math/rand"
+    "sync/atomic"
+    "github.com/princeofdatamining/golib/sqlutil/dialect"
+)
+
+/*
+    Statements run through DbMap are retried when the dialect classifies
+    the failure as transient:
+
+    bad connection      always, the statement never reached the server
+    deadlock, lock timeout, serialization failure
+                        outside transactions, the statement was rolled back
+    lost connection, timeout
+                        only for idempotent statements (SELECT, SHOW, ...),
+                        writes may have been applied
+
+    Statements of a transaction are never retried one by one, wrap the
+    whole unit with DbMap.Transact instead. QueryRow reports errors on
+    Scan and is not retried.
+//*/
+
+type RetryPolicy struct {
+    // total tries, 1 disables retrying
+    MaxAttempts int
+    // delay before the second try, doubled on every other one
+    MinBackoff  time.Duration
+    MaxBackoff  time.Duration
+    // overrides the dialect classifier
+    Classify    func (err error) (dialect.ErrorClass)
+    // overrides IsIdempotent
+    Idempotent  func (query string) (bool)
+}
+
+var (
+    DefaultRetryPolicy = &RetryPolicy{
+        MaxAttempts: 3,
+        MinBackoff: 20 * time.Millisecond,
+        MaxBackoff: time.Second,
+    }
+    NoRetryPolicy = &RetryPolicy{
+        MaxAttempts: 1,
+    }
+)
+
+var idempotentVerbs = []string{"SELECT", "SHOW", "EXPLAIN", "DESCRIBE", "DESC", "VALUES"}
+
+// IsIdempotent reports whether running query twice has the effect of
+// running it once, judged by its leading keyword.
+func IsIdempotent(query string) (bool) {
+    fields := strings.Fields(query)
+    if len(fields) <= 0 {
+        return false
+    }
+    verb := strings.ToUpper(strings.TrimLeft(fields[0], "("))
+    for _, v := range idempotentVerbs {
+        if verb == v {
+            return true
+        }
+    }
+    return false
+}
+
+// Backoff returns the delay before try attempt+1 (attempt counts from 1),
+// exponential with full jitter.
+func (this *RetryPolicy) Backoff(attempt int) (time.Duration) {
+    if this.MinBackoff <= 0 {
+        return 0
+    }
+    d := this.MinBackoff
+    for i := 1; i < attempt && (this.MaxBackoff <= 0 || d < this.MaxBackoff); i++ {
+        d *= 2
+    }
+    if this.MaxBackoff > 0 && d > this.MaxBackoff {
+        d = this.MaxBackoff
+    }
+    return d / 2 + time.Duration(rand.Int63n(int64(d / 2) + 1))
+}
+
+// Retryable decides for one failure of a statement outside transactions.
+func (this *RetryPolicy) Retryable(class dialect.ErrorClass, idempotent bool) (bool) {
+    switch class {
+    case dialect.ErrorOther:
+        return false
+    case dialect.ErrorLostConn, dialect.ErrorTimeout:
+        return idempotent
+    }
+    return true
+}
+
+func (this *RetryPolicy) classify(d dialect.Dialect, err error) (dialect.ErrorClass) {
+    if this.Classify != nil {
+        return this.Classify(err)
+    }
+    if c, ok := d.(dialect.ErrorClassifier); ok {
+        return c.ClassifyError(err)
+    }
+    return dialect.ClassifyError(err)
+}
+func (this *RetryPolicy) idempotent(query string) (bool) {
+    if this.Idempotent != nil {
+        return this.Idempotent(query)
+    }
+    return IsIdempotent(query)
+}
+
+type retryCounters struct {
+    retries     int64
+    giveups     int64
+}
+
+// run calls f until it succeeds, fails permanently or the attempts are
+// used up. accept tells which failures may be tried again.
+func (this *RetryPolicy) run(counters *retryCounters, classify func (error) (dialect.ErrorClass), accept func (dialect.ErrorClass) (bool), f func () (error)) (err error) {
+    for attempt := 1; ; attempt++ {
+        if err = f(); err == nil {
+            return
+        }
+        if !accept(classify(err)) {
+            return
+        }
+        if attempt >= this.MaxAttempts {
+            if counters != nil {
+                atomic.AddInt64(&counters.giveups, 1)
+            }
+            return
+        }
+        if counters != nil {
+            atomic.AddInt64(&counters.retries, 1)
+        }
+        time.Sleep(this.Backoff(attempt))
+    }
+}
+
+//
+
+func (this *dbMap) SetRetryPolicy(policy *RetryPolicy) () {
+    if policy == nil {
+        policy = NoRetryPolicy
+    }
+    this.retry = policy
+}
+func (this *dbMap) GetRetryPolicy() (*RetryPolicy) { return this.retry }
+
+func (this *dbMap) withRetry(query string, f func () (error)) (error) {
+    policy := this.retry
+    idempotent := policy.idempotent(query)
+    return policy.run(this.counters, func (err error) (dialect.ErrorClass) {
+        return policy.classify(this.dialect, err)
+    }, func (class dialect.ErrorClass) (bool) {
+        return policy.Retryable(class, idempotent)
+    }, f)
+}
+
+// commitError marks failures of Commit, a connection lost while
+// committing leaves the outcome unknown, so does a timeout.
+type commitError struct {
+    err     error
+}
+func (this *commitError) Error() (string) { return this.err.Error() }
+
+// Transact runs f inside a transaction, committing when it returns nil.
+// The whole transaction is run again when it was aborted by a deadlock,
+// serialization failure or lock timeout, or when the connection broke
+// before commit. f may run several times, keep other side effects out.
+func (this *dbMap) Transact(f func (tx Transaction) (error)) (err error) {
+    policy := this.retry
+    err = policy.run(this.counters, func (err error) (dialect.ErrorClass) {
+        if e, ok := err.(*commitError); ok {
+            if class := policy.classify(this.dialect, e.err); class != dialect.ErrorLostConn && class != dialect.ErrorTimeout {
+                return class
+            }
+            return dialect.ErrorOther
+        }
+        return policy.classify(this.dialect, err)
+    }, dialect.ErrorClass.Transient, func () (err error) {
+        tx, err := this.Begin()
+        if err != nil {
+            return
+        }
+        if err = f(tx); err != nil {
+            tx.Rollback()
+            return
+        }
+        if err = tx.Commit(); err != nil {
+            return &commitError{err}
+        }
+        return
+    })
+    if e, ok := err.(*commitError); ok {
+        err = e.err
+    }
+    return
+}
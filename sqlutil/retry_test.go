@@ -0,0 +1,136 @@
+package sqlutil
+
+import (
+    "errors"
+    "testing"
+    "time"
+    "github.com/princeofdatamining/golib/sqlutil/dialect"
+)
+
+func TestIsIdempotent(t *testing.T) () {
+    for query, want := range map[string]bool{
+        "SELECT 1": true,
+        "  (select * from t) union (select * from u)": true,
+        "show tables": true,
+        "UPDATE t SET a = 1": false,
+        "INSERT INTO t VALUES (1)": false,
+        "": false,
+    } {
+        if got := IsIdempotent(query); got != want {
+            t.Errorf("%q: %v", query, got)
+        }
+    }
+}
+
+func TestRetryPolicy(t *testing.T) () {
+    policy := &RetryPolicy{ MaxAttempts: 5, MinBackoff: 10 * time.Millisecond, MaxBackoff: 40 * time.Millisecond }
+    for attempt, max := range map[int]time.Duration{ 1: 10, 2: 20, 3: 40, 8: 40 } {
+        max *= time.Millisecond
+        if d := policy.Backoff(attempt); d < max / 2 || d > max {
+            t.Errorf("backoff %d: %v not in [%v, %v]", attempt, d, max / 2, max)
+        }
+    }
+    for _, in := range []struct{
+        class       dialect.ErrorClass
+        idempotent  bool
+        want        bool
+    }{
+        { dialect.ErrorOther, true, false },
+        { dialect.ErrorBadConn, false, true },
+        { dialect.ErrorLostConn, false, false },
+        { dialect.ErrorLostConn, true, true },
+        { dialect.ErrorTimeout, false, false },
+        { dialect.ErrorTimeout, true, true },
+        { dialect.ErrorDeadlock, false, true },
+    } {
+        if got := policy.Retryable(in.class, in.idempotent); got != in.want {
+            t.Errorf("%v idempotent %v: %v", in.class, in.idempotent, got)
+        }
+    }
+}
+
+var errTestLost = errors.New("test: lost")
+
+func TestRetry(t *testing.T) () {
+    dbmap := newTestDbMap(t)
+    dbmap.SetRetryPolicy(&RetryPolicy{
+        MaxAttempts: 3,
+        Classify: func (err error) (dialect.ErrorClass) {
+            if err == errTestLost {
+                return dialect.ErrorLostConn
+            }
+            return dialect.ErrorOther
+        },
+    })
+    calls := 0
+    fail := func (n int) (func () (error)) {
+        return func () (error) {
+            if calls++; calls <= n {
+                return errTestLost
+            }
+            return nil
+        }
+    }
+    if err := dbmap.withRetry("SELECT 1", fail(2)); err != nil || calls != 3 {
+        t.Fatalf("select: %v after %d calls", err, calls)
+    }
+    // writes may have been applied before the connection broke
+    calls = 0
+    if err := dbmap.withRetry("UPDATE t SET a = 1", fail(1)); err != errTestLost || calls != 1 {
+        t.Fatalf("update: %v after %d calls", err, calls)
+    }
+    calls = 0
+    if err := dbmap.withRetry("SELECT 1", fail(5)); err != errTestLost || calls != 3 {
+        t.Fatalf("exhausted: %v after %d calls", err, calls)
+    }
+    if stats := dbmap.Stats(); stats.Retries != 4 || stats.RetryExhausted != 1 {
+        t.Fatalf("stats %+v", stats)
+    }
+}
+
+func TestTransact(t *testing.T) () {
+    dbmap := newTestDbMap(t)
+    dbmap.SetRetryPolicy(&RetryPolicy{
+        MaxAttempts: 3,
+        Classify: func (err error) (dialect.ErrorClass) {
+            if err == errTestLost {
+                return dialect.ErrorDeadlock
+            }
+            return dialect.ErrorOther
+        },
+    })
+    if _, err := dbmap.Exec("CREATE TABLE t (a integer)"); err != nil {
+        t.Fatal(err)
+    }
+    runs := 0
+    err := dbmap.Transact(func (tx Transaction) (error) {
+        if _, err := tx.Exec("INSERT INTO t VALUES (?)", runs); err != nil {
+            return err
+        }
+        if runs++; runs < 2 {
+            return errTestLost
+        }
+        return nil
+    })
+    var n int
+    dbmap.QueryRow("SELECT COUNT(*) FROM t").Scan(&n)
+    if err != nil || runs != 2 || n != 1 {
+        t.Fatalf("transact: %v after %d runs, %d rows", err, runs, n)
+    }
+    runs = 0
+    failed := errors.New("test: failed")
+    if err = dbmap.Transact(func (tx Transaction) (error) { runs++; return failed }); err != failed || runs != 1 {
+        t.Fatalf("permanent failure: %v after %d runs", err, runs)
+    }
+}
+
+func TestHealthCheck(t *testing.T) () {
+    dbmap := newTestDbMap(t)
+    if health := dbmap.HealthCheck(time.Second); !health.Up() {
+        t.Fatalf("health %+v", health)
+    }
+    dbmap.db.Close()
+    if health := dbmap.HealthCheck(time.Second); health.Up() || health.Error == "" {
+        t.Fatalf("closed database %+v", health)
+    }
+}
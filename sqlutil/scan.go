@@ -3,10 +3,10 @@ package sqlutil
 
 import (
     "database/sql"
-    "database/sql/driver"
     "reflect"
     "strings"
     "errors"
+    "github.com/princeofdatamining/golib/sqlutil/dialect"
 )
 
 func buildValues(columns []string, values []interface{}, data []interface{}, dummy interface{}) (bool) {
@@ -54,12 +54,14 @@ type StmtBind interface {
     Query(args ...interface{}) (error)
     Next() (error)
     One () (error)
+    SetRetryPolicy(policy *RetryPolicy) ()
 }
 
 func NewStmtBind(stmt *sql.Stmt, data ...interface{}) (StmtBind) {
     return &stmtBind{
         stmt:   stmt,
         data:   data,
+        retry:  DefaultRetryPolicy,
     }
 }
 func NewStmt(db *sql.DB, query string, data ...interface{}) (StmtBind, error) {
@@ -73,6 +75,7 @@ func NewStmt(db *sql.DB, query string, data ...interface{}) (StmtBind, error) {
 type stmtBind struct {
     stmt    *sql.Stmt
     data    []interface{}
+    retry   *RetryPolicy
     //
     err     error
     rows    *sql.Rows
@@ -93,15 +96,32 @@ func (this *stmtBind) clear() () {
         this.rows = nil
     }
 }
+func (this *stmtBind) SetRetryPolicy(policy *RetryPolicy) () {
+    if policy == nil {
+        policy = NoRetryPolicy
+    }
+    this.retry = policy
+}
+// The statement may belong to a transaction and the dialect is unknown,
+// only connection failures are retried: broken connections before the
+// statement was sent, lost connections for queries.
+func (this *stmtBind) withRetry(idempotent bool, f func () (error)) (error) {
+    policy := this.retry
+    return policy.run(nil, func (err error) (dialect.ErrorClass) {
+        if policy.Classify != nil {
+            return policy.Classify(err)
+        }
+        return dialect.ClassifyError(err)
+    }, func (class dialect.ErrorClass) (bool) {
+        return class == dialect.ErrorBadConn || class == dialect.ErrorLostConn && idempotent
+    }, f)
+}
 func (this *stmtBind) Exec(args ...interface{}) (res sql.Result, err error) {
     this.clear()
-    // Like sql.DB.Query() try more times
-    for i := 0; i < 10; i++ {
+    err = this.withRetry(false, func () (err error) {
         res, err = this.stmt.Exec(args...)
-        if err != driver.ErrBadConn {
-            break
-        }
-    }
+        return
+    })
     return 
 }
 func (this *stmtBind) Query(args ...interface{}) (err error) {
@@ -112,13 +132,10 @@ func (this *stmtBind) Query(args ...interface{}) (err error) {
             this.clear()
         }
     }()
-    // Like sql.DB.Query() try more times
-    for i := 0; i < 10; i++ {
+    err = this.withRetry(true, func () (err error) {
         this.rows, err = this.stmt.Query(args...)
-        if err != driver.ErrBadConn {
-            break 
-        }
-    }
+        return
+    })
     if err != nil {
         return 
     }
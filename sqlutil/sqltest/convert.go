@@ -0,0 +1,37 @@
+package sqltest
+
+import (
+    "fmt"
+    "reflect"
+    "database/sql"
+    "encoding/json"
+    "github.com/princeofdatamining/golib/fieldutil"
+)
+
+// setValue converts a decoded fixture value to the field type, text and
+// numbers are converted like imported CSV fields.
+func setValue(f reflect.Value, val interface{}) (err error) {
+    if val == nil {
+        f.Set(reflect.Zero(f.Type()))
+        return
+    }
+    if num, ok := val.(json.Number); ok {
+        val = string(num)
+    }
+    v := reflect.ValueOf(val)
+    if v.Type().AssignableTo(f.Type()) {
+        f.Set(v)
+        return
+    }
+    if scanner, ok := f.Addr().Interface().(sql.Scanner); ok {
+        return scanner.Scan(val)
+    }
+    if f.Kind() == reflect.Ptr {
+        ptr := reflect.New(f.Type().Elem())
+        if err = setValue(ptr.Elem(), val); err == nil {
+            f.Set(ptr)
+        }
+        return
+    }
+    return fieldutil.SetString(f, fmt.Sprint(val))
+}
@@ -0,0 +1,333 @@
+// Package sqltest loads fixtures into tables mapped on a sqlutil.DbMap
+// and dumps table contents for golden-file assertions.
+//
+// A fixture file maps table names to rows, rows map column names to values:
+//
+//  {
+//      "users": [
+//          {"_label": "alice", "name": "Alice"}
+//      ],
+//      "posts": [
+//          {"user_id": "@users.alice", "title": "hello"}
+//      ]
+//  }
+//
+// "@table.label" refers to the autoincr column of a labelled row inserted
+// before, "@table.label.column" to any of its columns. Use "@@" to write
+// a literal leading "@".
+//
+// JSON fixtures are always decoded. YAML (.yaml, .yml) needs gopkg.in/yaml.v3
+// and is linked in with the yaml build tag:
+//
+//  go test -tags yaml ./...
+package sqltest
+
+import (
+    "fmt"
+    "bytes"
+    "errors"
+    "io/ioutil"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "strings"
+    "encoding/json"
+    "github.com/princeofdatamining/golib/sqlutil"
+    "github.com/princeofdatamining/golib/sqlutil/dialect"
+)
+
+const (
+    LabelKey = "_label"
+    RefPrefix = "@"
+)
+
+var (
+    errfUnknownTable = errFormatFactory("sqltest: table %q was not registered")
+    errfUnknownColumn = errFormatFactory("sqltest: table %q has no column %q")
+    errfUnknownDecoder = errFormatFactory("sqltest: no decoder for %q files")
+    errfBadRef = errFormatFactory("sqltest: bad reference %q")
+    errfUnknownRef = errFormatFactory("sqltest: reference %q to a row not inserted yet")
+    errfDupLabel = errFormatFactory("sqltest: duplicated label %q in table %q")
+    errfNoAutoIncr = errFormatFactory("sqltest: reference %q needs a column, table has no autoincr")
+    errCyclicRefs = errors.New("sqltest: cyclic references between fixture tables")
+)
+
+func errFormatFactory(f string) (func (args ...interface{}) (error)) {
+    return func (args ...interface{}) (error) {
+        return fmt.Errorf(f, args...)
+    }
+}
+
+// DecodeFunc decodes one fixture file into table => rows.
+type DecodeFunc func (data []byte) (map[string][]map[string]interface{}, error)
+
+var decoders = map[string]DecodeFunc{
+    ".json": decodeJSON,
+}
+
+// RegisterDecoder adds support for another file extension, e.g. ".yml".
+func RegisterDecoder(ext string, f DecodeFunc) () {
+    decoders[strings.ToLower(ext)] = f
+}
+
+func decodeJSON(data []byte) (tables map[string][]map[string]interface{}, err error) {
+    dec := json.NewDecoder(bytes.NewReader(data))
+    dec.UseNumber()
+    err = dec.Decode(&tables)
+    return
+}
+
+type fixtureRow struct {
+    label   string
+    values  map[string]interface{}
+}
+
+type Fixtures struct {
+    dbmap   sqlutil.DbMap
+    rows    map[string][]*fixtureRow
+    // label => inserted object, per table
+    objects map[string]map[string]interface{}
+}
+
+func New(dbmap sqlutil.DbMap) (*Fixtures) {
+    return &Fixtures{
+        dbmap: dbmap,
+        rows: make(map[string][]*fixtureRow),
+        objects: make(map[string]map[string]interface{}),
+    }
+}
+
+// Load reads fixture files, the decoder is chosen by file extension.
+func (this *Fixtures) Load(files ...string) (err error) {
+    for _, fn := range files {
+        var data []byte
+        if data, err = ioutil.ReadFile(fn); err != nil {
+            return
+        }
+        if err = this.Parse(filepath.Ext(fn), data); err != nil {
+            return fmt.Errorf("%s: %v", fn, err)
+        }
+    }
+    return
+}
+
+// LoadDir reads every file in dir having a registered extension.
+func (this *Fixtures) LoadDir(dir string) (err error) {
+    infos, err := ioutil.ReadDir(dir)
+    if err != nil {
+        return
+    }
+    var files []string
+    for _, info := range infos {
+        if _, ok := decoders[strings.ToLower(filepath.Ext(info.Name()))]; ok && !info.IsDir() {
+            files = append(files, filepath.Join(dir, info.Name()))
+        }
+    }
+    return this.Load(files...)
+}
+
+// Parse adds the rows of one fixture document.
+func (this *Fixtures) Parse(ext string, data []byte) (err error) {
+    decode, ok := decoders[strings.ToLower(ext)]
+    if !ok {
+        return errfUnknownDecoder(ext)
+    }
+    tables, err := decode(data)
+    if err != nil {
+        return
+    }
+    for name, rows := range tables {
+        table, ok := this.dbmap.GetTableByName(name)
+        if !ok {
+            return errfUnknownTable(name)
+        }
+        columns := columnDict(table)
+        for _, values := range rows {
+            row := &fixtureRow{
+                values: make(map[string]interface{}),
+            }
+            for key, val := range values {
+                if key == LabelKey {
+                    row.label = fmt.Sprint(val)
+                    continue
+                }
+                if _, ok := columns[key]; !ok {
+                    return errfUnknownColumn(name, key)
+                }
+                row.values[key] = val
+            }
+            if row.label != "" {
+                for _, other := range this.rows[name] {
+                    if other.label == row.label {
+                        return errfDupLabel(row.label, name)
+                    }
+                }
+            }
+            this.rows[name] = append(this.rows[name], row)
+        }
+    }
+    return
+}
+
+// Object returns the inserted struct pointer of a labelled row.
+func (this *Fixtures) Object(table, label string) (interface{}, bool) {
+    obj, ok := this.objects[table][label]
+    return obj, ok
+}
+
+// Insert writes all fixture rows through exec, tables referenced by
+// others are inserted first.
+func (this *Fixtures) Insert(exec sqlutil.SQLExecutor) (err error) {
+    order, err := this.tableOrder()
+    if err != nil {
+        return
+    }
+    this.objects = make(map[string]map[string]interface{})
+    for _, name := range order {
+        table, _ := this.dbmap.GetTableByName(name)
+        columns := columnDict(table)
+        for _, row := range this.rows[name] {
+            ptr := reflect.New(table.GetGoType())
+            for key, val := range row.values {
+                if val, err = this.resolve(val); err != nil {
+                    return
+                }
+                f := ptr.Elem().FieldByName(columns[key].GetFieldName())
+                if err = setValue(f, val); err != nil {
+                    return fmt.Errorf("sqltest: %s.%s: %v", name, key, err)
+                }
+            }
+            obj := ptr.Interface()
+            if _, err = exec.Insert(obj); err != nil {
+                return
+            }
+            if row.label != "" {
+                if this.objects[name] == nil {
+                    this.objects[name] = make(map[string]interface{})
+                }
+                this.objects[name][row.label] = obj
+            }
+        }
+    }
+    return
+}
+
+// Setup empties every mapped table then inserts the fixtures,
+// for tests that commit their changes.
+func (this *Fixtures) Setup() (err error) {
+    if _, err = this.dbmap.TruncateTables(); err != nil {
+        return
+    }
+    return this.Insert(this.dbmap)
+}
+
+// Begin inserts the fixtures inside a new transaction, roll it back
+// at the end of the test to restore the previous state.
+func (this *Fixtures) Begin() (tx sqlutil.Transaction, err error) {
+    if tx, err = this.dbmap.Begin(); err != nil {
+        return
+    }
+    if err = this.Insert(tx); err != nil {
+        tx.Rollback()
+        return nil, err
+    }
+    return
+}
+
+func parseRef(s string) (table, label, column string, ok bool) {
+    if !strings.HasPrefix(s, RefPrefix) || strings.HasPrefix(s, RefPrefix + RefPrefix) {
+        return
+    }
+    parts := strings.Split(s[len(RefPrefix):], ".")
+    switch len(parts) {
+    case 2:
+        return parts[0], parts[1], "", true
+    case 3:
+        return parts[0], parts[1], parts[2], true
+    }
+    return
+}
+func (this *Fixtures) resolve(val interface{}) (interface{}, error) {
+    s, ok := val.(string)
+    if !ok {
+        return val, nil
+    }
+    if strings.HasPrefix(s, RefPrefix + RefPrefix) {
+        return s[len(RefPrefix):], nil
+    }
+    if !strings.HasPrefix(s, RefPrefix) {
+        return val, nil
+    }
+    name, label, column, ok := parseRef(s)
+    if !ok {
+        return nil, errfBadRef(s)
+    }
+    obj, ok := this.objects[name][label]
+    if !ok {
+        return nil, errfUnknownRef(s)
+    }
+    table, _ := this.dbmap.GetTableByName(name)
+    var fieldName string
+    for _, col := range table.GetColumns() {
+        if column == "" && col.GetAutoIncr() || column != "" && col.GetColumnName() == column {
+            fieldName = col.GetFieldName()
+            break
+        }
+    }
+    switch {
+    case fieldName != "":
+        return reflect.ValueOf(obj).Elem().FieldByName(fieldName).Interface(), nil
+    case column == "":
+        return nil, errfNoAutoIncr(s)
+    }
+    return nil, errfUnknownColumn(name, column)
+}
+
+// tableOrder sorts fixture tables so referenced tables come first.
+func (this *Fixtures) tableOrder() (order []string, err error) {
+    deps := make(map[string]map[string]bool)
+    var names []string
+    for name, rows := range this.rows {
+        names = append(names, name)
+        deps[name] = make(map[string]bool)
+        for _, row := range rows {
+            for _, val := range row.values {
+                s, _ := val.(string)
+                if ref, _, _, ok := parseRef(s); ok && ref != name {
+                    deps[name][ref] = true
+                }
+            }
+        }
+    }
+    sort.Strings(names)
+    done := make(map[string]bool)
+    for len(order) < len(names) {
+        progress := false
+        for _, name := range names {
+            if done[name] {
+                continue
+            }
+            ready := true
+            for ref := range deps[name] {
+                if _, known := deps[ref]; known && !done[ref] {
+                    ready = false
+                }
+            }
+            if ready {
+                order, done[name], progress = append(order, name), true, true
+            }
+        }
+        if !progress {
+            return nil, errCyclicRefs
+        }
+    }
+    return
+}
+
+func columnDict(table sqlutil.TableMap) (dict map[string]dialect.ColumnMeta) {
+    dict = make(map[string]dialect.ColumnMeta)
+    for _, col := range table.GetColumns() {
+        dict[col.GetColumnName()] = col
+    }
+    return
+}
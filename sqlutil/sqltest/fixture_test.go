@@ -0,0 +1,149 @@
+package sqltest_test
+
+import (
+    "bytes"
+    "path/filepath"
+    "testing"
+    "database/sql"
+    _ "github.com/mattn/go-sqlite3"
+    "github.com/princeofdatamining/golib/sqlutil"
+    "github.com/princeofdatamining/golib/sqlutil/dialect"
+    "github.com/princeofdatamining/golib/sqlutil/sqltest"
+)
+
+type testUser struct {
+    Id      int64   `db:"primary"`
+    Name    string  `db:"size=32"`
+    Admin   bool
+}
+type testPost struct {
+    Id      int64   `db:"primary"`
+    UserId  int64   `db:"name=user_id"`
+    Title   string
+}
+
+const testFixtures = `{
+    "posts": [
+        {"id": 10, "user_id": "@users.alice.id", "title": "@@hello"},
+        {"id": 11, "user_id": "@users.bob.id", "title": "bye"}
+    ],
+    "users": [
+        {"_label": "alice", "id": 1, "name": "Alice", "admin": true},
+        {"_label": "bob", "id": 2, "name": "Bob"}
+    ]
+}`
+
+const testDump = `{
+  "posts": [
+    {
+      "id": 10,
+      "title": "@hello",
+      "user_id": 1
+    },
+    {
+      "id": 11,
+      "title": "bye",
+      "user_id": 2
+    }
+  ],
+  "users": [
+    {
+      "admin": 1,
+      "id": 1,
+      "name": "Alice"
+    },
+    {
+      "admin": 0,
+      "id": 2,
+      "name": "Bob"
+    }
+  ]
+}
+`
+
+func openTestDbMap(t *testing.T) (sqlutil.DbMap) {
+    db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sqltest.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func () () { db.Close() })
+    d, _ := dialect.Open("sqlite", map[string]string{})
+    dbmap := sqlutil.NewDbMap(db, d)
+    dbmap.AddTable(testUser{}, "users")
+    dbmap.AddTable(testPost{}, "posts")
+    if _, err = dbmap.CreateTables(false); err != nil {
+        t.Fatal(err)
+    }
+    return dbmap
+}
+
+func TestFixtures(t *testing.T) () {
+    dbmap := openTestDbMap(t)
+    fixtures := sqltest.New(dbmap)
+    if err := fixtures.Parse(".json", []byte(testFixtures)); err != nil {
+        t.Fatal(err)
+    }
+    if err := fixtures.Setup(); err != nil {
+        t.Fatal(err)
+    }
+    if obj, ok := fixtures.Object("users", "alice"); !ok || obj.(*testUser).Name != "Alice" {
+        t.Fatalf("object alice: %v %v", obj, ok)
+    }
+    var buf bytes.Buffer
+    if err := sqltest.DumpJSON(&buf, dbmap, nil); err != nil {
+        t.Fatal(err)
+    }
+    if buf.String() != testDump {
+        t.Fatalf("dump:\n%s\nwant:\n%s", buf.String(), testDump)
+    }
+
+    // fixtures inserted in a transaction vanish with its rollback
+    if _, err := dbmap.TruncateTables(); err != nil {
+        t.Fatal(err)
+    }
+    tx, err := fixtures.Begin()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if list, _ := sqltest.Dump(dbmap, tx, dbmap.GetTables()[0]); len(list) != 2 {
+        t.Fatalf("users in transaction: %v", list)
+    }
+    tx.Rollback()
+    if list, _ := sqltest.Dump(dbmap, nil, dbmap.GetTables()[0]); len(list) != 0 {
+        t.Fatalf("users after rollback: %v", list)
+    }
+}
+
+func TestFixtureErrors(t *testing.T) () {
+    dbmap := openTestDbMap(t)
+    for doc, want := range map[string]string{
+        `{"groups": [{}]}`: `sqltest: table "groups" was not registered`,
+        `{"users": [{"email": "x"}]}`: `sqltest: table "users" has no column "email"`,
+        `{"users": [{"_label": "a"}, {"_label": "a"}]}`: `sqltest: duplicated label "a" in table "users"`,
+    } {
+        if err := sqltest.New(dbmap).Parse(".json", []byte(doc)); err == nil || err.Error() != want {
+            t.Errorf("parse %s: %v, want %s", doc, err, want)
+        }
+    }
+    for doc, want := range map[string]string{
+        `{"posts": [{"id": 1, "user_id": "@users.nobody.id"}]}`: `sqltest: reference "@users.nobody.id" to a row not inserted yet`,
+        `{"users": [{"_label": "a", "id": 1}], "posts": [{"id": 1, "user_id": "@users.a"}]}`: `sqltest: reference "@users.a" needs a column, table has no autoincr`,
+        `{"users": [{"id": "@posts.a.id"}], "posts": [{"id": "@users.a.id"}]}`: `sqltest: cyclic references between fixture tables`,
+    } {
+        fixtures := sqltest.New(dbmap)
+        if err := fixtures.Parse(".json", []byte(doc)); err != nil {
+            t.Fatal(err)
+        }
+        if err := fixtures.Setup(); err == nil || err.Error() != want {
+            t.Errorf("insert %s: %v, want %s", doc, err, want)
+        }
+    }
+}
+
+func TestAssertGolden(t *testing.T) () {
+    golden := filepath.Join(t.TempDir(), "testdata", "dump.json")
+    sqltest.UpdateGolden = true
+    sqltest.AssertGolden(t, golden, []byte(testDump))
+    sqltest.UpdateGolden = false
+    sqltest.AssertGolden(t, golden, []byte(testDump))
+}
@@ -0,0 +1,131 @@
+package sqltest
+
+import (
+    "fmt"
+    "bytes"
+    "io"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+    "encoding/json"
+    "github.com/princeofdatamining/golib/sqlutil"
+)
+
+// UpdateGolden rewrites golden files with the current output, it is
+// switched on by the SQLTEST_UPDATE environment variable.
+var UpdateGolden = os.Getenv("SQLTEST_UPDATE") != ""
+
+// Dump reads all rows of table ordered by every column, each row is
+// column name => value with []byte as string and times in RFC3339.
+func Dump(dbmap sqlutil.DbMap, exec sqlutil.SQLExecutor, table sqlutil.TableMap) (list []map[string]interface{}, err error) {
+    if exec == nil {
+        exec = table
+    }
+    d := dbmap.GetDialect()
+    cols := table.GetColumns()
+    names := make([]string, len(cols))
+    order := make([]string, len(cols))
+    for i, col := range cols {
+        names[i] = d.QuoteField(col.GetColumnName())
+        order[i] = fmt.Sprint(i+1)
+    }
+    query := fmt.Sprintf(d.SelectSQL(table.GetSchemaName(), table.GetTableName()), "", strings.Join(names, ", "), "", "1 = 1", "ORDER BY " + strings.Join(order, ", "))
+    rows, err := exec.Query(query)
+    if err != nil {
+        return
+    }
+    defer rows.Close()
+    values := make([]interface{}, len(cols))
+    dest := make([]interface{}, len(cols))
+    for i := range values {
+        dest[i] = &values[i]
+    }
+    for rows.Next() {
+        if err = rows.Scan(dest...); err != nil {
+            return
+        }
+        m := make(map[string]interface{}, len(cols))
+        for i, col := range cols {
+            switch v := values[i].(type) {
+            case []byte:
+                m[col.GetColumnName()] = string(v)
+            case time.Time:
+                m[col.GetColumnName()] = v.UTC().Format(time.RFC3339Nano)
+            default:
+                m[col.GetColumnName()] = v
+            }
+        }
+        list = append(list, m)
+    }
+    err = rows.Err()
+    return
+}
+
+// DumpJSON writes the given tables (all mapped tables when none) as
+// indented JSON keyed by table name, the same layout fixtures use.
+func DumpJSON(w io.Writer, dbmap sqlutil.DbMap, exec sqlutil.SQLExecutor, tables ...sqlutil.TableMap) (err error) {
+    if len(tables) <= 0 {
+        tables = dbmap.GetTables()
+    }
+    if exec == nil {
+        exec = dbmap
+    }
+    dump := make(map[string][]map[string]interface{})
+    for _, table := range tables {
+        var list []map[string]interface{}
+        if list, err = Dump(dbmap, exec, table); err != nil {
+            return
+        }
+        if list == nil {
+            list = []map[string]interface{}{}
+        }
+        dump[table.GetTableName()] = list
+    }
+    data, err := json.MarshalIndent(dump, "", "  ")
+    if err != nil {
+        return
+    }
+    _, err = w.Write(append(data, '\n'))
+    return
+}
+
+// TestingT is the subset of testing.TB used by AssertGolden.
+type TestingT interface {
+    Helper()
+    Errorf(format string, args ...interface{})
+    Fatalf(format string, args ...interface{})
+}
+
+// AssertGolden compares got with the golden file, or writes got into
+// it when UpdateGolden is set.
+func AssertGolden(t TestingT, golden string, got []byte) () {
+    t.Helper()
+    if UpdateGolden {
+        if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+            t.Fatalf("sqltest: %v", err)
+        }
+        if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+            t.Fatalf("sqltest: %v", err)
+        }
+        return
+    }
+    want, err := ioutil.ReadFile(golden)
+    if err != nil {
+        t.Fatalf("sqltest: %v (set SQLTEST_UPDATE=1 to create it)", err)
+    }
+    if !bytes.Equal(want, got) {
+        t.Errorf("sqltest: %s mismatch\n--- want\n%s\n--- got\n%s", golden, want, got)
+    }
+}
+
+// AssertTables dumps tables and compares them with the golden file.
+func AssertTables(t TestingT, golden string, dbmap sqlutil.DbMap, exec sqlutil.SQLExecutor, tables ...sqlutil.TableMap) () {
+    t.Helper()
+    var buf bytes.Buffer
+    if err := DumpJSON(&buf, dbmap, exec, tables...); err != nil {
+        t.Fatalf("sqltest: %v", err)
+    }
+    AssertGolden(t, golden, buf.Bytes())
+}
@@ -0,0 +1,17 @@
+// +build yaml
+
+package sqltest
+
+import (
+    "gopkg.in/yaml.v3"
+)
+
+func init() () {
+    RegisterDecoder(".yml", decodeYAML)
+    RegisterDecoder(".yaml", decodeYAML)
+}
+
+func decodeYAML(data []byte) (tables map[string][]map[string]interface{}, err error) {
+    err = yaml.Unmarshal(data, &tables)
+    return
+}
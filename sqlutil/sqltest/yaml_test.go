@@ -0,0 +1,36 @@
+// +build yaml
+
+package sqltest_test
+
+import (
+    "bytes"
+    "testing"
+    "github.com/princeofdatamining/golib/sqlutil/sqltest"
+)
+
+const testYAMLFixtures = `
+posts:
+  - {id: 10, user_id: "@users.alice.id", title: "@@hello"}
+  - {id: 11, user_id: "@users.bob.id", title: bye}
+users:
+  - {_label: alice, id: 1, name: Alice, admin: true}
+  - {_label: bob, id: 2, name: Bob}
+`
+
+func TestYAMLFixtures(t *testing.T) () {
+    dbmap := openTestDbMap(t)
+    fixtures := sqltest.New(dbmap)
+    if err := fixtures.Parse(".yaml", []byte(testYAMLFixtures)); err != nil {
+        t.Fatal(err)
+    }
+    if err := fixtures.Setup(); err != nil {
+        t.Fatal(err)
+    }
+    var buf bytes.Buffer
+    if err := sqltest.DumpJSON(&buf, dbmap, nil); err != nil {
+        t.Fatal(err)
+    }
+    if buf.String() != testDump {
+        t.Fatalf("dump:\n%s\nwant:\n%s", buf.String(), testDump)
+    }
+}
@@ -0,0 +1,217 @@
+package sqlutil
+
+import (
+    "context"
+    "sync"
+    "sync/atomic"
+    "container/list"
+    "database/sql"
+)
+
+const (
+    DefaultStmtCacheSize = 64
+)
+
+type StmtCacheStats struct {
+    Hits        int64
+    Misses      int64
+    Evictions   int64
+    Size        int
+    Capacity    int
+}
+func (this StmtCacheStats) HitRatio() (float64) {
+    if total := this.Hits + this.Misses; total > 0 {
+        return float64(this.Hits) / float64(total)
+    }
+    return 0
+}
+
+type cachedStmt struct {
+    query   string
+    stmt    *sql.Stmt
+    refs    int
+    evicted bool
+}
+
+// stmtCache keeps the most recently used prepared statements by query
+// text. Statements in use are only closed after their last release.
+type stmtCache struct {
+    sync.Mutex
+    capacity    int
+    enabled     int32
+    lru         *list.List
+    items       map[string]*list.Element
+    hits        int64
+    misses      int64
+    evictions   int64
+}
+func newStmtCache(capacity int) (*stmtCache) {
+    this := &stmtCache{
+        lru: list.New(),
+        items: make(map[string]*list.Element),
+    }
+    this.resize(capacity)
+    return this
+}
+func (this *stmtCache) isEnabled() (bool) { return atomic.LoadInt32(&this.enabled) != 0 }
+func (this *stmtCache) acquire(db *sql.DB, query string) (c *cachedStmt, err error) {
+    this.Lock()
+    if e, ok := this.items[query]; ok {
+        this.hits++
+        this.lru.MoveToFront(e)
+        c = e.Value.(*cachedStmt)
+        c.refs++
+        this.Unlock()
+        return
+    }
+    this.misses++
+    this.Unlock()
+
+    // prepare outside the lock, a concurrent miss on the same query
+    // keeps the first statement and closes its own
+    stmt, err := db.Prepare(query)
+    if err != nil {
+        return
+    }
+    this.Lock()
+    defer this.Unlock()
+    if e, ok := this.items[query]; ok {
+        stmt.Close()
+        c = e.Value.(*cachedStmt)
+        c.refs++
+        return
+    }
+    c = &cachedStmt{
+        query: query,
+        stmt: stmt,
+        refs: 1,
+    }
+    this.items[query] = this.lru.PushFront(c)
+    for this.lru.Len() > this.capacity {
+        this.evict(this.lru.Back())
+    }
+    return
+}
+func (this *stmtCache) release(c *cachedStmt) () {
+    this.Lock()
+    defer this.Unlock()
+    if c.refs--; c.refs <= 0 && c.evicted {
+        c.stmt.Close()
+    }
+}
+func (this *stmtCache) evict(e *list.Element) () {
+    c := e.Value.(*cachedStmt)
+    this.lru.Remove(e)
+    delete(this.items, c.query)
+    this.evictions++
+    if c.evicted = true; c.refs <= 0 {
+        c.stmt.Close()
+    }
+}
+func (this *stmtCache) resize(capacity int) () {
+    this.Lock()
+    defer this.Unlock()
+    this.capacity = capacity
+    if capacity > 0 {
+        atomic.StoreInt32(&this.enabled, 1)
+    } else {
+        atomic.StoreInt32(&this.enabled, 0)
+    }
+    for this.lru.Len() > this.capacity {
+        this.evict(this.lru.Back())
+    }
+}
+func (this *stmtCache) clear() () {
+    this.Lock()
+    defer this.Unlock()
+    for this.lru.Len() > 0 {
+        this.evict(this.lru.Back())
+    }
+}
+func (this *stmtCache) stats() (StmtCacheStats) {
+    this.Lock()
+    defer this.Unlock()
+    return StmtCacheStats{
+        Hits: this.hits,
+        Misses: this.misses,
+        Evictions: this.evictions,
+        Size: this.lru.Len(),
+        Capacity: this.capacity,
+    }
+}
+
+//
+
+// Only parameterized statements go through the cache, literal queries
+// (DDL, Insert2/Update2/Delete2) are seldom repeated and run directly.
+func (this *dbMap) useStmt(args []interface{}) (bool) {
+    return len(args) > 0 && this.stmts != nil && this.stmts.isEnabled()
+}
+func (this *dbMap) SetStmtCacheSize(n int) () {
+    if n < 0 {
+        n = 0
+    }
+    if this.stmts == nil {
+        this.stmts = newStmtCache(n)
+        return
+    }
+    this.stmts.resize(n)
+}
+func (this *dbMap) StmtCacheStats() (StmtCacheStats) {
+    if this.stmts == nil {
+        return StmtCacheStats{}
+    }
+    return this.stmts.stats()
+}
+func (this *dbMap) ClearStmtCache() () {
+    if this.stmts == nil {
+        return
+    }
+    this.stmts.clear()
+}
+
+func (this *dbMap) stmtExec(ctx context.Context, query string, args []interface{}) (sql.Result, error) {
+    c, err := this.stmts.acquire(this.db, query)
+    if err != nil {
+        return nil, err
+    }
+    defer this.stmts.release(c)
+    return c.stmt.ExecContext(ctx, args...)
+}
+// rows keep the underlying statement alive after release
+func (this *dbMap) stmtQuery(ctx context.Context, query string, args []interface{}) (*sql.Rows, error) {
+    c, err := this.stmts.acquire(this.db, query)
+    if err != nil {
+        return nil, err
+    }
+    defer this.stmts.release(c)
+    return c.stmt.QueryContext(ctx, args...)
+}
+func (this *dbMap) stmtQueryRow(ctx context.Context, query string, args []interface{}) (*sql.Row) {
+    c, err := this.stmts.acquire(this.db, query)
+    if err != nil {
+        // let Scan report the error
+        return this.db.QueryRowContext(ctx, query, args...)
+    }
+    defer this.stmts.release(c)
+    return c.stmt.QueryRowContext(ctx, args...)
+}
+
+// txStmt rebinds the cached statement on the transaction, the bound
+// statement lives until Commit/Rollback.
+func (this *txMap) txStmt(query string) (stmt *sql.Stmt, err error) {
+    if stmt, ok := this.stmts[query]; ok {
+        return stmt, nil
+    }
+    c, err := this.dbmap.stmts.acquire(this.dbmap.db, query)
+    if err != nil {
+        return
+    }
+    defer this.dbmap.stmts.release(c)
+    stmt = this.tx.Stmt(c.stmt)
+    if this.stmts == nil {
+        this.stmts = make(map[string]*sql.Stmt)
+    }
+    this.stmts[query] = stmt
+    return
+}
@@ -0,0 +1,98 @@
+package sqlutil
+
+import (
+    "path/filepath"
+    "testing"
+    "database/sql"
+    _ "github.com/mattn/go-sqlite3"
+    "github.com/princeofdatamining/golib/sqlutil/dialect"
+)
+
+// newTestDbMap opens a DbMap on a fresh sqlite file, in-memory
+// databases would differ between pooled connections.
+func newTestDbMap(t *testing.T) (*dbMap) {
+    db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sqlutil.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func () () { db.Close() })
+    d, err := dialect.Open("sqlite", map[string]string{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    return NewDbMap(db, d).(*dbMap)
+}
+
+func TestStmtCacheEviction(t *testing.T) () {
+    dbmap := newTestDbMap(t)
+    cache := newStmtCache(2)
+    for _, query := range []string{ "SELECT 1", "SELECT 2", "SELECT 1", "SELECT 3" } {
+        c, err := cache.acquire(dbmap.db, query)
+        if err != nil {
+            t.Fatal(err)
+        }
+        cache.release(c)
+    }
+    // "SELECT 2" was the least recently used
+    if _, ok := cache.items["SELECT 2"]; ok {
+        t.Fatalf("SELECT 2 not evicted")
+    }
+    want := StmtCacheStats{ Hits: 1, Misses: 3, Evictions: 1, Size: 2, Capacity: 2 }
+    if stats := cache.stats(); stats != want {
+        t.Fatalf("stats %+v, want %+v", stats, want)
+    }
+
+    cache.resize(0)
+    if cache.isEnabled() || cache.lru.Len() != 0 {
+        t.Fatalf("resize(0) keeps %d statements", cache.lru.Len())
+    }
+}
+
+func TestStmtCacheRefs(t *testing.T) () {
+    dbmap := newTestDbMap(t)
+    cache := newStmtCache(1)
+    held, err := cache.acquire(dbmap.db, "SELECT ?")
+    if err != nil {
+        t.Fatal(err)
+    }
+    again, _ := cache.acquire(dbmap.db, "SELECT ?")
+    if again != held || held.refs != 2 {
+        t.Fatalf("second acquire got another statement, refs %d", held.refs)
+    }
+    cache.release(again)
+
+    // evicted while in use, the statement stays open until released
+    other, _ := cache.acquire(dbmap.db, "SELECT ? + 1")
+    cache.release(other)
+    if !held.evicted {
+        t.Fatalf("statement in use not evicted")
+    }
+    var n int
+    if err = held.stmt.QueryRow(1).Scan(&n); err != nil || n != 1 {
+        t.Fatalf("evicted statement in use: %v %d", err, n)
+    }
+    cache.release(held)
+    if err = held.stmt.QueryRow(1).Scan(&n); err == nil {
+        t.Fatalf("released evicted statement still open")
+    }
+}
+
+func TestStmtCacheDbMap(t *testing.T) () {
+    dbmap := newTestDbMap(t)
+    var n int
+    for i := 0; i < 3; i++ {
+        if err := dbmap.QueryRow("SELECT ? * 2", i).Scan(&n); err != nil || n != i * 2 {
+            t.Fatalf("query %d: %v %d", i, err, n)
+        }
+    }
+    // literal queries bypass the cache
+    dbmap.QueryRow("SELECT 1").Scan(&n)
+    if stats := dbmap.StmtCacheStats(); stats.Hits != 2 || stats.Misses != 1 || stats.Size != 1 {
+        t.Fatalf("stats %+v", stats)
+    }
+    dbmap.SetStmtCacheSize(0)
+    dbmap.QueryRow("SELECT ? * 2", 1).Scan(&n)
+    if stats := dbmap.StmtCacheStats(); stats.Misses != 1 || stats.Size != 0 {
+        t.Fatalf("disabled cache used: %+v", stats)
+    }
+}
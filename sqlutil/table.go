@@ -2,10 +2,12 @@
 package sqlutil
 
 import (
+    "io"
     "reflect"
     "errors"
     "fmt"
     "strings"
+    "sort"
     "database/sql"
     "github.com/princeofdatamining/golib/sqlutil/dialect"
 )
@@ -60,8 +62,12 @@ func (this *dbMap) AddTable3(meta interface{}, schema, name, comment string) (Ta
         primaries:   make(map[string][]dialect.ColumnMeta),
         uniques  :   make(map[string][]dialect.ColumnMeta),
         indexes  :   make(map[string][]dialect.ColumnMeta),
+        fulltexts:   make(map[string][]dialect.ColumnMeta),
     }
     tmap.buildColumns(t)
+    if tmap.buildErr != nil {
+        return nil, tmap.buildErr
+    }
 
     this.tables = append(this.tables, tmap)
     this.tableD[name] = tmap
@@ -113,6 +119,13 @@ func (this *dbMap) DropTableByMeta(meta interface{}, ifExists bool) (error) {
         return table.Drop(ifExists)
     }
 }
+func (this *dbMap) GetDialect() (dialect.Dialect) { return this.dialect }
+func (this *dbMap) GetTables() (tables []TableMap) {
+    for _, table := range this.tables {
+        tables = append(tables, table)
+    }
+    return
+}
 func (this *dbMap) GetTableByName(t string) (table TableMap, find bool) {
     table, find = this.tableD[t]
     return 
@@ -141,12 +154,22 @@ func (this *dbMap) getTableByPType(pt reflect.Type, hint string) (table TableMap
 type TableMap interface {
     SQLExecutor
     //
+    GetSchemaName() (string)
+    GetTableName() (string)
+    GetGoType() (reflect.Type)
+    GetColumns() ([]dialect.ColumnMeta)
+    //
     CreateSQL(ifNotExists bool) (string)
     Create(ifNotExists bool) (error)
     DropSQL(ifExists bool) (string)
     Drop(ifExists bool) (error)
     TruncateSQL() (string)
     Truncate() (error)
+    //
+    ExportCSV  (w io.Writer, where string, args ...interface{}) (int64, error)
+    ExportJSONL(w io.Writer, where string, args ...interface{}) (int64, error)
+    ImportCSV  (r io.Reader, opts *ImportOptions) (int64, error)
+    ImportJSONL(r io.Reader, opts *ImportOptions) (int64, error)
 
     checkPType(pt reflect.Type, hint string) (err error)
     insert(vptr reflect.Value, exec SQLExecutor, execVal []reflect.Value) (err error)
@@ -184,11 +207,25 @@ type tableMap struct {
     primaries   map[string][]dialect.ColumnMeta
     uniques     map[string][]dialect.ColumnMeta
     indexes     map[string][]dialect.ColumnMeta
+    fulltexts   map[string][]dialect.ColumnMeta
 
     delBind     *bindObj
     insBind     *bindObj
     updBind     *bindObj
     getBinds    map[string]*bindObj
+
+    buildErr    error
+}
+func (this *tableMap) GetSchemaName() (string) { return this.schemaName }
+func (this *tableMap) GetTableName() (string) { return this.tableName }
+func (this *tableMap) GetGoType() (reflect.Type) { return this.gotype }
+func (this *tableMap) GetColumns() (cols []dialect.ColumnMeta) {
+    for _, col := range this.columns {
+        if !col.transient {
+            cols = append(cols, col)
+        }
+    }
+    return
 }
 func (this *tableMap) quoteTable() (string) {
     return this.dbmap.dialect.QuoteTable(this.schemaName, this.tableName)
@@ -227,12 +264,34 @@ func (this *tableMap) CreateSQL(ifNotExists bool) (string) {
     if len(primaries) > 0 { lines = append(lines, primaries...) }
     if len(uniques  ) > 0 { lines = append(lines, uniques...  ) }
     if len(indexes  ) > 0 { lines = append(lines, indexes...  ) }
+    var after []string
+    if ft, ok := this.dbmap.dialect.(dialect.FullTexter); ok {
+        for _, key := range this.fullTextKeys() {
+            list := this.fulltexts[key]
+            if text := ft.CreateFullTextKey(this.tableName, key, list...); text != "" { lines = append(lines, text) }
+            if text := ft.CreateFullTextSQL(this.schemaName, this.tableName, key, ifNotExists, list...); text != "" { after = append(after, text) }
+        }
+    }
     columns := strings.Join(lines, ",\n")
-    return fmt.Sprintf(f, columns)
+    return strings.Join(append([]string{fmt.Sprintf(f, columns)}, after...), "\n")
+}
+func (this *tableMap) fullTextKeys() (keys []string) {
+    for key := range this.fulltexts {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return
 }
 func (this *tableMap) Create(ifNotExists bool) (error) { return this.exec( this.CreateSQL(ifNotExists) ) }
 func (this *tableMap) DropSQL(ifExists bool) (string) {
-    return this.dbmap.dialect.DropTableSQL(this.schemaName, this.tableName, ifExists)
+    var lines []string
+    if ft, ok := this.dbmap.dialect.(dialect.FullTexter); ok {
+        for _, key := range this.fullTextKeys() {
+            if text := ft.DropFullTextSQL(this.schemaName, this.tableName, key, ifExists); text != "" { lines = append(lines, text) }
+        }
+    }
+    lines = append(lines, this.dbmap.dialect.DropTableSQL(this.schemaName, this.tableName, ifExists))
+    return strings.Join(lines, "\n")
 }
 func (this *tableMap) Drop(ifExists bool) (error) { return this.exec( this.DropSQL(ifExists) ) }
 func (this *tableMap) TruncateSQL() (string) {
@@ -3,6 +3,7 @@ package sqlutil
 
 import (
     "fmt"
+    "time"
     "database/sql"
 )
 
@@ -14,7 +15,7 @@ type Transaction interface {
 }
 
 func (this *dbMap) Begin() (Transaction, error) {
-    tx, err := this.db.Begin()
+    tx, err := this.db.BeginTx(this.context(), nil)
     if err != nil {
         return nil, err
     }
@@ -28,32 +29,60 @@ type txMap struct {
     dbmap   *dbMap
     tx      *sql.Tx
     closed  bool
+    stmts   map[string]*sql.Stmt
 }
 func (this *txMap) Commit() (error) {
     if !this.closed {
-        this.closed = true
+        this.closed, this.stmts = true, nil
         return this.tx.Commit()
     }
     return sql.ErrTxDone
 }
 func (this *txMap) Rollback() (error) {
     if !this.closed {
-        this.closed = true
+        this.closed, this.stmts = true, nil
         return this.tx.Rollback()
     }
     return sql.ErrTxDone
 }
 
-func (this *txMap) Exec(query string, args ...interface{}) (sql.Result, error) {
+func (this *txMap) Exec(query string, args ...interface{}) (res sql.Result, err error) {
     fmt.Println("Tx.Exec:", query)
-    return this.tx.Exec(query, args...)
+    defer this.dbmap.traceQuery(time.Now(), query, args, &err)
+    ctx := this.dbmap.context()
+    if this.dbmap.useStmt(args) {
+        stmt, err := this.txStmt(query)
+        if err != nil {
+            return nil, err
+        }
+        return stmt.ExecContext(ctx, args...)
+    }
+    return this.tx.ExecContext(ctx, query, args...)
 }
 func (this *txMap) exec(query string, args ...interface{}) (err error) { _, err = this.Exec(query, args...); return }
-func (this *txMap) Query(query string, args ...interface{}) (*sql.Rows, error) {
+func (this *txMap) Query(query string, args ...interface{}) (rows *sql.Rows, err error) {
     fmt.Println("Tx.Query:", query)
-    return this.tx.Query(query, args...)
+    defer this.dbmap.traceQuery(time.Now(), query, args, &err)
+    ctx := this.dbmap.context()
+    if this.dbmap.useStmt(args) {
+        stmt, err := this.txStmt(query)
+        if err != nil {
+            return nil, err
+        }
+        return stmt.QueryContext(ctx, args...)
+    }
+    return this.tx.QueryContext(ctx, query, args...)
 }
 func (this *txMap) QueryRow(query string, args ...interface{}) (*sql.Row) {
     fmt.Println("Tx.QueryRow:", query)
-    return this.tx.QueryRow(query, args...)
+    // errors show up on Scan
+    var err error
+    defer this.dbmap.traceQuery(time.Now(), query, args, &err)
+    ctx := this.dbmap.context()
+    if this.dbmap.useStmt(args) {
+        if stmt, err := this.txStmt(query); err == nil {
+            return stmt.QueryRowContext(ctx, args...)
+        }
+    }
+    return this.tx.QueryRowContext(ctx, query, args...)
 }
@@ -83,6 +83,9 @@ func (this *tableMap) update(vptr reflect.Value, exec SQLExecutor, execVal []ref
     if err = triggerRun("PreUpdate", vptr, execVal); err != nil {
         return 
     }
+    if err = this.validate(vptr.Elem()); err != nil {
+        return 
+    }
     if bind, err = this.bindUpdate(); err != nil {
         return 
     }
@@ -0,0 +1,180 @@
+package sqlutil
+
+import (
+    "fmt"
+    "reflect"
+    "strconv"
+    "strings"
+    "unicode/utf8"
+    "database/sql/driver"
+    "github.com/princeofdatamining/golib/fieldutil"
+)
+
+/*
+    Fields are validated before Insert/Update with the `validate` tag:
+
+    Name    string  `db:"name,size=32,notnull" validate:"required,min=2"`
+    State   string  `validate:"enum=new|done"`
+    Code    string  `validate:"len=6,regexp=^[0-9a-f]+$"`
+
+    The rules are the ones of fieldutil, regexp consumes the rest of the
+    tag so its pattern may hold commas. Columns add implicit checks:
+    notnull pointers / sql.Null* must be set, strings longer than size
+    are rejected.
+//*/
+
+// ValidatorFunc checks one (dereferenced) field value, see fieldutil.
+type ValidatorFunc = fieldutil.ValidatorFunc
+
+var (
+    errfRuleExceedsSize = errFormatFactory("validate: field %q rule %q=%d exceeds column size %d")
+)
+
+// RegisterValidator adds a named rule usable in `validate` tags. Rules
+// are resolved when tables are added, register them before AddTable.
+func RegisterValidator(name string, f ValidatorFunc) () { fieldutil.RegisterValidator(name, f) }
+
+type FieldError struct {
+    Field   string
+    Column  string
+    Rule    string
+    Param   string
+    Value   interface{}
+    Message string
+}
+func (this *FieldError) Error() (string) {
+    return fmt.Sprintf("%s: %s", this.Field, this.Message)
+}
+
+// ValidationErrors collects every failed field of one object.
+type ValidationErrors []*FieldError
+func (this ValidationErrors) Error() (string) {
+    msgs := make([]string, len(this))
+    for i, e := range this {
+        msgs[i] = e.Error()
+    }
+    return "validate: " + strings.Join(msgs, "; ")
+}
+// Field returns the errors of one struct field.
+func (this ValidationErrors) Field(name string) (list ValidationErrors) {
+    for _, e := range this {
+        if e.Field == name {
+            list = append(list, e)
+        }
+    }
+    return
+}
+
+// buildRules compiles the `validate` tag of a column and checks it
+// against the column metadata.
+func (this *columnMap) buildRules(tag string) (err error) {
+    if this.rules, this.required, err = fieldutil.ParseRules(this.fieldName, tag); err != nil {
+        return
+    }
+    if this.maxsize <= 0 || !isStringType(this.gotype) {
+        return nil
+    }
+    for _, rule := range this.rules {
+        if rule.Name != "max" && rule.Name != "len" {
+            continue
+        }
+        if n, err := strconv.Atoi(rule.Param); err == nil && n > this.maxsize {
+            return errfRuleExceedsSize(this.fieldName, rule.Name, n, this.maxsize)
+        }
+    }
+    return nil
+}
+
+func isStringType(t reflect.Type) (bool) {
+    for t.Kind() == reflect.Ptr {
+        t = t.Elem()
+    }
+    return t.Kind() == reflect.String || t.Name() == "NullString"
+}
+
+// underlying dereferences pointers and driver.Valuer (sql.Null*) values,
+// valid is false for nil / NULL.
+func underlying(v reflect.Value) (u reflect.Value, valid bool) {
+    for v.Kind() == reflect.Ptr {
+        if v.IsNil() {
+            return v, false
+        }
+        v = v.Elem()
+    }
+    if v.Kind() == reflect.Struct && v.CanInterface() {
+        if valuer, ok := v.Interface().(driver.Valuer); ok {
+            val, err := valuer.Value()
+            if err != nil || val == nil {
+                return v, false
+            }
+            return reflect.ValueOf(val), true
+        }
+    }
+    return v, true
+}
+
+func (this *columnMap) validate(obj reflect.Value) (errs ValidationErrors) {
+    f := obj.FieldByName(this.fieldName)
+    fail := func (rule, param, msg string) () {
+        errs = append(errs, &FieldError{
+            Field: this.fieldName,
+            Column: this.columnName,
+            Rule: rule,
+            Param: param,
+            Value: f.Interface(),
+            Message: msg,
+        })
+    }
+    v, valid := underlying(f)
+    if !valid {
+        if this.required || this.notnull && !this.autoincr && !this.hasDefault {
+            fail("required", "", "is required")
+        }
+        return
+    }
+    if this.required && isZero(v) {
+        fail("required", "", "is required")
+        return
+    }
+    if this.maxsize > 0 && v.Kind() == reflect.String {
+        if n := utf8.RuneCountInString(v.String()); n > this.maxsize {
+            fail("size", strconv.Itoa(this.maxsize), fmt.Sprintf("length %d exceeds column size %d", n, this.maxsize))
+        }
+    }
+    for _, rule := range this.rules {
+        if err := rule.Check(v, rule.Param); err != nil {
+            fail(rule.Name, rule.Param, err.Error())
+        }
+    }
+    return
+}
+
+func isZero(v reflect.Value) (bool) {
+    switch v.Kind() {
+    case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+        return v.Len() == 0
+    }
+    return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+func (this *tableMap) validate(obj reflect.Value) (error) {
+    var errs ValidationErrors
+    for _, col := range this.columns {
+        if col.transient || col.autoincr && col.rules == nil {
+            continue
+        }
+        errs = append(errs, col.validate(obj)...)
+    }
+    if len(errs) > 0 {
+        return errs
+    }
+    return nil
+}
+func (this *dbMap) Validate(object interface{}) (error) {
+    vptr := reflect.ValueOf(object)
+    table, err := this.getTableByPType(vptr.Type(), "Validate")
+    if err != nil {
+        return err
+    }
+    return table.(*tableMap).validate(vptr.Elem())
+}
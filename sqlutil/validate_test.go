@@ -0,0 +1,107 @@
+package sqlutil
+
+import (
+    "errors"
+    "fmt"
+    "reflect"
+    "testing"
+    "database/sql"
+)
+
+type testValidated struct {
+    Id      int64           `db:"primary"`
+    Name    string          `db:"size=8" validate:"required,min=2"`
+    State   string          `validate:"enum=new|done"`
+    Code    string          `validate:"len=4,regexp=^[0-9a-f,]+$"`
+    Email   string          `validate:"email"`
+    Age     int             `validate:"min=0,max=150"`
+    Note    sql.NullString  `db:"notnull"`
+    Even    int             `validate:"even"`
+}
+
+func init() () {
+    RegisterValidator("even", func (v reflect.Value, param string) (error) {
+        if v.Int() % 2 != 0 {
+            return fmt.Errorf("must be even")
+        }
+        return nil
+    })
+}
+
+func TestValidate(t *testing.T) () {
+    dbmap := newTestDbMap(t)
+    if _, err := dbmap.AddTable(testValidated{}, "validated"); err != nil {
+        t.Fatal(err)
+    }
+    valid := testValidated{ Name: "bob", State: "new", Code: "0a,f", Email: "bob@example.com", Age: 30, Note: sql.NullString{ String: "x", Valid: true } }
+    if err := dbmap.Validate(&valid); err != nil {
+        t.Fatalf("valid object: %v", err)
+    }
+
+    invalid := testValidated{ Name: "a", State: "old", Code: "0A", Email: "bob", Age: 200, Even: 3 }
+    err := dbmap.Validate(&invalid)
+    var errs ValidationErrors
+    if !errors.As(err, &errs) {
+        t.Fatalf("got %v", err)
+    }
+    for field, rules := range map[string][]string{
+        "Name": { "min" },
+        "State": { "enum" },
+        "Code": { "len", "regexp" },
+        "Email": { "email" },
+        "Age": { "max" },
+        "Note": { "required" },
+        "Even": { "even" },
+    } {
+        list := errs.Field(field)
+        if len(list) != len(rules) {
+            t.Errorf("%s: got %v, want rules %v", field, list, rules)
+            continue
+        }
+        for i, e := range list {
+            if e.Rule != rules[i] {
+                t.Errorf("%s: rule %s, want %s", field, e.Rule, rules[i])
+            }
+        }
+    }
+
+    invalid = valid
+    invalid.Name = "too long name"
+    if list := dbmap.Validate(&invalid).(ValidationErrors); len(list) != 1 || list[0].Rule != "size" || list[0].Param != "8" {
+        t.Fatalf("size: %v", list)
+    }
+    invalid.Name = ""
+    if list := dbmap.Validate(&invalid).(ValidationErrors); len(list) != 1 || list[0].Message != "is required" {
+        t.Fatalf("required: %v", list)
+    }
+}
+
+func TestValidateTags(t *testing.T) () {
+    for meta, want := range map[interface{}]string{
+        struct{ A string `validate:"unknown"` }{}: `validate: field "A" uses unknown rule "unknown"`,
+        struct{ A string `validate:"min=x"` }{}: `validate: field "A" rule "min": bad parameter "x"`,
+        struct{ A string `validate:"regexp=("` }{}: `validate: field "A" rule "regexp": bad parameter "("`,
+        struct{ A string `db:"size=4" validate:"max=5"` }{}: `validate: field "A" rule "max"=5 exceeds column size 4`,
+    } {
+        dbmap := newTestDbMap(t)
+        if _, err := dbmap.AddTable(meta, "t"); err == nil || err.Error() != want {
+            t.Errorf("%T: %v, want %s", meta, err, want)
+        }
+    }
+}
+
+func TestValidateInsert(t *testing.T) () {
+    dbmap := newTestDbMap(t)
+    dbmap.AddTable(testValidated{}, "validated")
+    if _, err := dbmap.CreateTables(false); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := dbmap.Insert(&testValidated{ Id: 1, Name: "a" }); err == nil {
+        t.Fatalf("invalid object inserted")
+    }
+    var n int
+    dbmap.QueryRow("SELECT COUNT(*) FROM validated").Scan(&n)
+    if n != 0 {
+        t.Fatalf("%d rows inserted", n)
+    }
+}